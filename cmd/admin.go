@@ -0,0 +1,83 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/google/uuid"
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/repository"
+    "go-crud-api/internal/validate"
+)
+
+// createAdmin saves a new user with the admin role. The name, email and
+// password must satisfy the same rules as POST /users.
+func createAdmin(ctx context.Context, repo repository.UserRepositoryInterface, passwords *password.Manager, req model.CreateUserRequest) (model.User, error) {
+    if err := validate.Struct(req); err != nil {
+        return model.User{}, err
+    }
+
+    user := req.ToUser()
+    user.ID = uuid.New().String()
+    user.Role = model.RoleAdmin
+    user.CreatedAt = time.Now().UTC()
+    user.Version = 1
+    hash, err := passwords.Hash(user.Password)
+    if err != nil {
+        return model.User{}, fmt.Errorf("failed to hash password: %v", err)
+    }
+    user.Password = hash
+
+    if err := repo.Save(ctx, user); err != nil {
+        if errors.Is(err, repository.ErrConflict) {
+            return model.User{}, fmt.Errorf("a user with email %s already exists", user.Email)
+        }
+        return model.User{}, fmt.Errorf("failed to save user: %w", err)
+    }
+    return user, nil
+}
+
+// readPassword returns ADMIN_PASSWORD, or else the first line of stdin, so
+// the password never appears in the process arguments
+func readPassword(getenv func(string) string, stdin io.Reader) (string, error) {
+    if value := getenv("ADMIN_PASSWORD"); value != "" {
+        return value, nil
+    }
+    line, err := bufio.NewReader(stdin).ReadString('\n')
+    if err != nil && !errors.Is(err, io.EOF) {
+        return "", fmt.Errorf("failed to read password: %v", err)
+    }
+    return strings.TrimRight(line, "\r\n"), nil
+}
+
+// runCreateAdmin implements go-crud-api create-admin --email <email>
+// [--name <name>], reading the password from ADMIN_PASSWORD or stdin
+func runCreateAdmin(repo repository.UserRepositoryInterface, passwords *password.Manager, args []string) {
+    flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+    email := flags.String("email", "", "email the admin logs in with (required)")
+    name := flags.String("name", "Administrator", "display name")
+    flags.Parse(args)
+    if *email == "" {
+        log.Fatal("create-admin needs --email")
+    }
+
+    plain, err := readPassword(os.Getenv, os.Stdin)
+    if err != nil {
+        log.Fatal(err)
+    }
+    req := model.CreateUserRequest{Name: *name, Email: *email, Password: plain}
+    user, err := createAdmin(context.Background(), repo, passwords, req)
+    if err != nil {
+        log.Fatalf("Failed to create admin: %v", err)
+    }
+    log.Printf("Created admin %s (%s)", user.Email, user.ID)
+}
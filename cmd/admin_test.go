@@ -0,0 +1,51 @@
+package main
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/repository"
+)
+
+func TestCreateAdmin(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewMemoryUserRepository()
+    passwords := password.NewDefaultManager()
+    req := model.CreateUserRequest{Name: "Operator", Email: "Ops@Example.com", Password: "correct horse"}
+
+    user, err := createAdmin(ctx, repo, passwords, req)
+    if err != nil {
+        t.Fatalf("createAdmin() error = %v", err)
+    }
+    stored, err := repo.FindByEmail(ctx, "ops@example.com")
+    if err != nil {
+        t.Fatalf("FindByEmail() error = %v", err)
+    }
+    if stored.ID != user.ID || stored.Role != model.RoleAdmin {
+        t.Errorf("Stored user %+v, want admin %s", stored, user.ID)
+    }
+    if _, err := passwords.Verify(stored.Password, "correct horse"); err != nil {
+        t.Error("Expected the stored password to be a hash of the given one")
+    }
+
+    if _, err := createAdmin(ctx, repo, passwords, req); err == nil {
+        t.Error("Expected an error creating the same admin twice")
+    }
+    req.Email, req.Password = "short@example.com", "short"
+    if _, err := createAdmin(ctx, repo, passwords, req); err == nil {
+        t.Error("Expected a password shorter than 8 characters to be rejected")
+    }
+}
+
+func TestReadPassword(t *testing.T) {
+    env := map[string]string{"ADMIN_PASSWORD": "from env"}
+    if got, _ := readPassword(func(k string) string { return env[k] }, strings.NewReader("from stdin\n")); got != "from env" {
+        t.Errorf("readPassword() = %q, want the environment variable", got)
+    }
+    if got, _ := readPassword(func(string) string { return "" }, strings.NewReader("from stdin\r\nmore\n")); got != "from stdin" {
+        t.Errorf("readPassword() = %q, want the first line of stdin", got)
+    }
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+    "log"
+    "os"
+
+    "go-crud-api/internal/config"
+)
+
+// runConfig implements go-crud-api config print, which writes the
+// effective configuration with secrets redacted. An invalid configuration
+// is printed as well, then reported.
+func runConfig(cfg config.Config, args []string) {
+    if len(args) != 1 || args[0] != "print" {
+        log.Fatalf("Unknown config command %q (want print)", args)
+    }
+    if err := cfg.Print(os.Stdout); err != nil {
+        log.Fatalf("Failed to print configuration: %v", err)
+    }
+    if err := cfg.Validate(); err != nil {
+        log.Fatalf("Invalid configuration:\n%v", err)
+    }
+}
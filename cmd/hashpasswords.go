@@ -0,0 +1,58 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/repository"
+)
+
+// hashPlaintextPasswords hashes every stored password that is not already
+// an encoded hash, including those of soft-deleted users, which would
+// otherwise come back in plaintext when restored. The hash is written with
+// SetPassword so users keep their version. It is safe to run more than once.
+func hashPlaintextPasswords(ctx context.Context, repo repository.UserRepositoryInterface, passwords *password.Manager) (int, error) {
+    opts := repository.ListOptions{
+        Limit:          repository.MaxPageSize,
+        Sort:           []repository.SortField{{Field: "id"}},
+        IncludeDeleted: true,
+    }
+
+    migrated := 0
+    for {
+        page, err := repo.List(ctx, opts)
+        if err != nil {
+            return migrated, fmt.Errorf("failed to fetch users: %w", err)
+        }
+
+        for _, user := range page.Users {
+            if user.Password == "" || passwords.IsHashed(user.Password) {
+                continue
+            }
+
+            hash, err := passwords.Hash(user.Password)
+            if err != nil {
+                return migrated, fmt.Errorf("failed to hash password for user %s: %v", user.ID, err)
+            }
+            if err := repo.SetPassword(ctx, user.ID, hash); err != nil {
+                return migrated, fmt.Errorf("failed to update user %s: %w", user.ID, err)
+            }
+            migrated++
+        }
+
+        if page.NextCursor == "" {
+            return migrated, nil
+        }
+        opts.Cursor = page.NextCursor
+    }
+}
+
+func runHashPasswords(repo repository.UserRepositoryInterface, passwords *password.Manager) {
+    migrated, err := hashPlaintextPasswords(context.Background(), repo, passwords)
+    if err != nil {
+        log.Fatalf("Password migration failed after %d users: %v", migrated, err)
+    }
+    log.Printf("Hashed %d plaintext passwords", migrated)
+}
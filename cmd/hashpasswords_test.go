@@ -0,0 +1,51 @@
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/repository"
+)
+
+func TestHashPlaintextPasswords(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewMemoryUserRepository()
+    passwords := password.NewDefaultManager()
+
+    hash, _ := passwords.Hash("already hashed")
+    for _, user := range []model.User{
+        {ID: "user-1", Name: "Alice", Email: "alice@example.com", Password: "plain one", Role: model.RoleUser},
+        {ID: "user-2", Name: "Bob", Email: "bob@example.com", Password: "plain two", Role: model.RoleUser},
+        {ID: "user-3", Name: "Carol", Email: "carol@example.com", Password: hash, Role: model.RoleUser},
+    } {
+        user.CreatedAt = time.Now().UTC()
+        if err := repo.Save(ctx, user); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := repo.Delete(ctx, "user-2", 0); err != nil {
+        t.Fatal(err)
+    }
+
+    migrated, err := hashPlaintextPasswords(ctx, repo, passwords)
+    if err != nil || migrated != 2 {
+        t.Fatalf("hashPlaintextPasswords() = %d, %v, want 2", migrated, err)
+    }
+    for id, plain := range map[string]string{"user-1": "plain one", "user-2": "plain two"} {
+        user, _ := repo.FindByIdIncludingDeleted(ctx, id)
+        if _, err := passwords.Verify(user.Password, plain); err != nil {
+            t.Errorf("User %s password %q is not a hash of %q", id, user.Password, plain)
+        }
+    }
+    // The deleted user is hashed without a version bump
+    if deleted, _ := repo.FindByIdIncludingDeleted(ctx, "user-2"); deleted.Version != 2 {
+        t.Errorf("Deleted user at version %d after hashing, want 2", deleted.Version)
+    }
+
+    if migrated, err := hashPlaintextPasswords(ctx, repo, passwords); err != nil || migrated != 0 {
+        t.Errorf("Second hashPlaintextPasswords() = %d, %v, want 0", migrated, err)
+    }
+}
@@ -1,40 +1,225 @@
 package main
 
 import (
+    "context"
+    "errors"
+    "flag"
     "log"
     "net/http"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
 
     "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/config"
     "go-crud-api/internal/database"
     "go-crud-api/internal/handler"
+    "go-crud-api/internal/health"
+    "go-crud-api/internal/metrics"
     "go-crud-api/internal/middleware"
-    "go-crud-api/internal/repository"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/problem"
+    "go-crud-api/internal/server"
+    "go-crud-api/internal/tracing"
 )
 
 func main() {
-    // Initialize database connection
-    db, err := database.NewMySQLConnection()
+    // Defaults < config file < environment < flags; what is left after
+    // the flags is the command
+    cfg, args, err := config.Load(os.Args[1:])
+    if errors.Is(err, flag.ErrHelp) {
+        return
+    }
+    if err != nil {
+        log.Fatalf("Failed to load configuration: %v", err)
+    }
+    if len(args) > 0 && args[0] == "config" {
+        runConfig(cfg, args[1:])
+        return
+    }
+    if err := cfg.Validate(); err != nil {
+        log.Fatalf("Invalid configuration:\n%v", err)
+    }
+
+    // Initialize storage; database.driver selects the backend
+    store, err := openStorage(cfg.Database)
+    if err != nil {
+        log.Fatalf("Failed to open storage: %v", err)
+    }
+    defer closeStorage(store)
+
+    // migrate manages the schema itself; everything else first brings the
+    // schema up to date unless migrate.on_start is false
+    if len(args) > 0 && args[0] == "migrate" {
+        if store.db == nil {
+            log.Fatalf("The %s driver has no schema to migrate", database.DriverMemory)
+        }
+        runMigrate(store.db, cfg.Migrate, args[1:])
+        return
+    }
+    if store.db != nil {
+        if err := migrateOnStart(store.db, cfg.Migrate); err != nil {
+            log.Fatalf("Failed to migrate database: %v", err)
+        }
+    }
+
+    // password.algorithm and its parameters apply to new hashes; existing
+    // hashes are upgraded as users log in
+    passwords, err := password.NewConfiguredManager(cfg.Password)
+    if err != nil {
+        log.Fatalf("Failed to set up password hashing: %v", err)
+    }
+
+    userRepo := store.users
+    if cfg.Seed.SampleUsers {
+        runSeedSampleUsers(userRepo)
+    }
+
+    // One-off maintenance commands: go-crud-api [flags] <command>
+    if len(args) > 0 {
+        switch args[0] {
+        case "create-admin":
+            runCreateAdmin(userRepo, passwords, args[1:])
+        case "hash-passwords":
+            runHashPasswords(userRepo, passwords)
+        case "purge-deleted":
+            runPurgeDeleted(userRepo, cfg.Purge)
+        default:
+            log.Fatalf("Unknown command %q", args[0])
+        }
+        return
+    }
+
+    // SIGINT or SIGTERM starts a graceful shutdown: the server drains,
+    // then background work stops, then the deferred closeStorage closes
+    // the database pool or saves the memory snapshot
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    signingKey, err := auth.LoadSigningKey(cfg.Auth)
     if err != nil {
-        log.Fatalf("Failed to connect to database: %v", err)
+        log.Fatalf("Failed to load token signing key: %v", err)
+    }
+    tokens := auth.NewTokenService(signingKey, auth.DefaultAccessTokenTTL)
+
+    // Repository timings and connection pool statistics are served on
+    // /metrics, along with the request metrics
+    appMetrics := metrics.New()
+    if store.db != nil {
+        if err := appMetrics.RegisterDB(store.db.DB, store.db.Driver); err != nil {
+            log.Fatalf("Failed to register database metrics: %v", err)
+        }
+    }
+
+    // Spans cover each request, UserHandler method, repository call and
+    // SQL statement; flushTraces exports the last ones before exiting
+    shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+    if err != nil {
+        log.Fatalf("Failed to set up tracing: %v", err)
+    }
+    defer flushTraces(shutdownTracing)
+
+    userRepo = tracing.NewUserRepository(metrics.NewUserRepository(userRepo, appMetrics))
+    refreshTokens := tracing.NewRefreshTokenRepository(
+        metrics.NewRefreshTokenRepository(store.refreshTokens, appMetrics))
+
+    var background sync.WaitGroup
+    defer background.Wait()
+    if cfg.Purge.Interval > 0 {
+        background.Add(1)
+        go func() {
+            defer background.Done()
+            runPurgeLoop(ctx, userRepo, refreshTokens, cfg.Purge)
+        }()
     }
-    defer db.Close()
 
     r := mux.NewRouter()
+    r.NotFoundHandler = problem.StatusHandler(http.StatusNotFound)
+    r.MethodNotAllowedHandler = problem.StatusHandler(http.StatusMethodNotAllowed)
+
+    // Readiness fails once shutdown is requested, while requests are
+    // still served for server.shutdown_delay
+    healthHandler := health.NewHandler(cfg.Health)
+    if store.db != nil {
+        healthHandler.Add("database", store.db.PingContext)
+    }
+    background.Add(1)
+    go func() {
+        defer background.Done()
+        <-ctx.Done()
+        healthHandler.Drain()
+    }()
 
-    userRepo := repository.NewUserRepository(db)
-    userHandler := handler.NewUserHandler(userRepo)
+    userHandler := handler.NewUserHandler(userRepo, passwords)
+    authHandler := handler.NewAuthHandler(userRepo, refreshTokens, tokens, passwords)
 
-    r.HandleFunc("/users", userHandler.GetAllUsers).Methods("GET")
+    // Public routes: health probes, metrics, login, token refresh and
+    // sign-up
+    healthHandler.RegisterRoutes(r)
+    r.Handle("/metrics", appMetrics.Handler()).Methods("GET")
+    authHandler.RegisterRoutes(r)
     r.HandleFunc("/users", userHandler.CreateUser).Methods("POST")
-    r.HandleFunc("/users/{id}", userHandler.GetUser).Methods("GET")
-    r.HandleFunc("/users/{id}", userHandler.UpdateUser).Methods("PUT")
-    r.HandleFunc("/users/{id}", userHandler.DeleteUser).Methods("DELETE")
 
-    // Apply CORS middleware
-    handler := middleware.CORS(r)
+    // Everything else requires a valid access token, and the role
+    // carried by the token must grant the route's permission
+    protected := r.NewRoute().Subrouter()
+    protected.Use(middleware.Authenticate(tokens))
+
+    requireAny := middleware.RequirePermission
+    requireOwnerOr := middleware.RequireOwnerOrPermission
+
+    protected.Handle("/users", requireAny(auth.PermListUsers)(
+        http.HandlerFunc(userHandler.GetAllUsers))).Methods("GET")
+    // /users/search and /users/me must be registered before /users/{id}
+    // so "search" and "me" are not taken as ids
+    protected.Handle("/users/search", requireAny(auth.PermListUsers)(
+        http.HandlerFunc(userHandler.SearchUsers))).Methods("GET")
+    protected.Handle("/users/me", requireAny(auth.PermReadOwnUser)(
+        http.HandlerFunc(userHandler.GetMe))).Methods("GET")
+    protected.Handle("/users/me", requireAny(auth.PermUpdateOwnUser)(
+        http.HandlerFunc(userHandler.UpdateMe))).Methods("PUT")
+    protected.Handle("/users/me", requireAny(auth.PermUpdateOwnUser)(
+        http.HandlerFunc(userHandler.PatchMe))).Methods("PATCH")
+    protected.Handle("/users/{id}", requireOwnerOr(auth.PermReadAnyUser, auth.PermReadOwnUser)(
+        http.HandlerFunc(userHandler.GetUser))).Methods("GET")
+    protected.Handle("/users/{id}", requireOwnerOr(auth.PermUpdateAnyUser, auth.PermUpdateOwnUser)(
+        http.HandlerFunc(userHandler.UpdateUser))).Methods("PUT")
+    protected.Handle("/users/{id}", requireOwnerOr(auth.PermUpdateAnyUser, auth.PermUpdateOwnUser)(
+        http.HandlerFunc(userHandler.PatchUser))).Methods("PATCH")
+    protected.Handle("/users/{id}", requireAny(auth.PermDeleteAnyUser)(
+        http.HandlerFunc(userHandler.DeleteUser))).Methods("DELETE")
+    protected.Handle("/users/{id}/restore", requireAny(auth.PermRestoreUsers)(
+        http.HandlerFunc(userHandler.RestoreUser))).Methods("POST")
+
+    // Apply CORS middleware; the request ID wraps everything so even
+    // unmatched routes get one in their error responses, and the server
+    // span and the metrics wrap that to time the complete response
+    handler := appMetrics.Instrument(r, tracing.Middleware(r, middleware.RequestID(middleware.CORS(r))))
     
-    log.Println("Starting server on :8080")
-    if err := http.ListenAndServe(":8080", handler); err != nil {
-        log.Fatal(err)
+    log.Printf("Starting server on %s", cfg.Server.Addr)
+    err = server.Run(ctx, cfg.Server, handler)
+    // Stop background work as well when the server failed by itself, and
+    // let a second signal kill the process if closing storage hangs
+    stop()
+    if err != nil {
+        // log.Fatal skips the deferred cleanup, so run it first
+        background.Wait()
+        flushTraces(shutdownTracing)
+        closeStorage(store)
+        log.Fatalf("Server stopped: %v", err)
+    }
+    log.Println("Server stopped")
+}
+
+// flushTraces exports pending spans, giving up after a few seconds so an
+// unreachable collector cannot hold up the exit
+func flushTraces(shutdown func(context.Context) error) {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if err := shutdown(ctx); err != nil {
+        log.Printf("Failed to flush traces: %v", err)
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strconv"
+
+    "go-crud-api/internal/config"
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/migrate"
+)
+
+func newMigrator(db *database.DB, cfg config.MigrateConfig) (*migrate.Migrator, error) {
+    var dialect migrate.Dialect = migrate.MySQL{}
+    migrations := migrate.MySQLMigrations()
+    switch db.Driver {
+    case database.DriverSQLite:
+        dialect, migrations = migrate.SQLite{}, migrate.SQLiteMigrations()
+    case database.DriverPostgres:
+        dialect, migrations = migrate.Postgres{}, migrate.PostgresMigrations()
+    }
+
+    migrator, err := migrate.New(db.DB, dialect, migrations)
+    if err != nil {
+        return nil, err
+    }
+    migrator.LockTimeout = cfg.LockTimeout
+    return migrator, nil
+}
+
+// migrateOnStart applies pending migrations unless cfg.OnStart is false,
+// e.g. when migrations are run as a separate deployment step
+func migrateOnStart(db *database.DB, cfg config.MigrateConfig) error {
+    if !cfg.OnStart {
+        return nil
+    }
+
+    migrator, err := newMigrator(db, cfg)
+    if err != nil {
+        return err
+    }
+    applied, err := migrator.Up(context.Background())
+    for _, m := range applied {
+        log.Printf("Applied migration %d_%s", m.Version, m.Name)
+    }
+    return err
+}
+
+// runMigrate implements go-crud-api migrate [up | down [n] | status]
+func runMigrate(db *database.DB, cfg config.MigrateConfig, args []string) {
+    migrator, err := newMigrator(db, cfg)
+    if err != nil {
+        log.Fatalf("Failed to load migrations: %v", err)
+    }
+    ctx := context.Background()
+
+    command := "up"
+    if len(args) > 0 {
+        command = args[0]
+    }
+    switch command {
+    case "up":
+        applied, err := migrator.Up(ctx)
+        for _, m := range applied {
+            log.Printf("Applied migration %d_%s", m.Version, m.Name)
+        }
+        if err != nil {
+            log.Fatal(err)
+        }
+        log.Printf("Schema is up to date (%d migrations applied)", len(applied))
+    case "down":
+        steps := 1
+        if len(args) > 1 {
+            steps, err = strconv.Atoi(args[1])
+            if err != nil || steps < 1 {
+                log.Fatalf("Invalid number of migrations to revert %q", args[1])
+            }
+        }
+        reverted, err := migrator.Down(ctx, steps)
+        for _, m := range reverted {
+            log.Printf("Reverted migration %d_%s", m.Version, m.Name)
+        }
+        if err != nil {
+            log.Fatal(err)
+        }
+    case "status":
+        statuses, err := migrator.Status(ctx)
+        if err != nil {
+            log.Fatal(err)
+        }
+        for _, s := range statuses {
+            state := "pending"
+            if s.AppliedAt != nil {
+                state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+            }
+            if s.Unknown {
+                state += " (unknown to this binary)"
+            }
+            fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+        }
+    default:
+        log.Fatalf("Unknown migrate command %q (want up, down or status)", command)
+    }
+}
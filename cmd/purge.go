@@ -0,0 +1,65 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "time"
+
+    "go-crud-api/internal/config"
+    "go-crud-api/internal/repository"
+)
+
+// purgeDeletedUsers permanently removes users deleted more than retention ago
+func purgeDeletedUsers(ctx context.Context, repo repository.UserRepositoryInterface, retention time.Duration) (int64, error) {
+    purged, err := repo.Purge(ctx, time.Now().Add(-retention))
+    if err != nil {
+        return purged, fmt.Errorf("failed to purge deleted users: %w", err)
+    }
+    return purged, nil
+}
+
+// deleteExpiredRefreshTokens removes refresh tokens past their expiry,
+// which can no longer be used or reveal reuse
+func deleteExpiredRefreshTokens(ctx context.Context, refreshTokens repository.RefreshTokenRepositoryInterface) (int64, error) {
+    deleted, err := refreshTokens.DeleteExpired(ctx, time.Now())
+    if err != nil {
+        return deleted, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+    }
+    return deleted, nil
+}
+
+// runPurgeLoop purges expired users and refresh tokens every interval
+// until ctx is done. Failures are logged and retried on the next tick.
+func runPurgeLoop(ctx context.Context, repo repository.UserRepositoryInterface, refreshTokens repository.RefreshTokenRepositoryInterface, cfg config.PurgeConfig) {
+    ticker := time.NewTicker(cfg.Interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            purged, err := purgeDeletedUsers(ctx, repo, cfg.Retention)
+            if err != nil {
+                log.Printf("Scheduled purge: %v", err)
+            } else if purged > 0 {
+                log.Printf("Purged %d deleted users", purged)
+            }
+            deleted, err := deleteExpiredRefreshTokens(ctx, refreshTokens)
+            if err != nil {
+                log.Printf("Scheduled purge: %v", err)
+            } else if deleted > 0 {
+                log.Printf("Deleted %d expired refresh tokens", deleted)
+            }
+        }
+    }
+}
+
+func runPurgeDeleted(repo repository.UserRepositoryInterface, cfg config.PurgeConfig) {
+    purged, err := purgeDeletedUsers(context.Background(), repo, cfg.Retention)
+    if err != nil {
+        log.Fatal(err)
+    }
+    log.Printf("Purged %d users deleted more than %s ago", purged, cfg.Retention)
+}
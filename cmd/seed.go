@@ -0,0 +1,63 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "time"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+// sampleUsers are the development users seeded when seed.sample_users is
+// enabled, as in the original init.sql. Passwords are argon2id hashes of
+// admin123 and test123, so despite its name the first one is an ordinary
+// user; admins are created with create-admin.
+var sampleUsers = []model.User{
+    {
+        ID:       "550e8400-e29b-41d4-a716-446655440001",
+        Name:     "Admin User",
+        Email:    "admin@example.com",
+        Password: "$argon2id$v=19$m=65536,t=1,p=4$2armKufuPyPmNh23++VvIQ$3QrRPEifgpztIP1Vt2SY+dHfkrjBMiYld/P1CyRShD8",
+        Role:     model.RoleUser,
+    },
+    {
+        ID:       "550e8400-e29b-41d4-a716-446655440002",
+        Name:     "Test User",
+        Email:    "test@example.com",
+        Password: "$argon2id$v=19$m=65536,t=1,p=4$4ekW+jWYakFhVqrXRnsDwg$p58s7Wrcclfh+VkHywqS42VioqqQob6X+02uzl+d37g",
+        Role:     model.RoleUser,
+    },
+}
+
+// seedSampleUsers saves the sample users that do not exist yet and
+// returns how many it saved. It is safe to run on every start.
+func seedSampleUsers(ctx context.Context, repo repository.UserRepositoryInterface) (int, error) {
+    now := time.Now().UTC()
+    seeded := 0
+    for _, user := range sampleUsers {
+        user.CreatedAt = now
+        user.Version = 1
+        err := repo.Save(ctx, user)
+        if errors.Is(err, repository.ErrConflict) {
+            continue
+        }
+        if err != nil {
+            return seeded, fmt.Errorf("failed to seed user %s: %w", user.Email, err)
+        }
+        seeded++
+    }
+    return seeded, nil
+}
+
+func runSeedSampleUsers(repo repository.UserRepositoryInterface) {
+    seeded, err := seedSampleUsers(context.Background(), repo)
+    if err != nil {
+        log.Fatalf("Failed to seed sample users: %v", err)
+    }
+    if seeded > 0 {
+        log.Printf("Seeded %d sample users with well-known passwords; do not enable seed.sample_users outside development", seeded)
+    }
+}
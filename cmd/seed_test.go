@@ -0,0 +1,30 @@
+package main
+
+import (
+    "context"
+    "testing"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+func TestSeedSampleUsers(t *testing.T) {
+    ctx := context.Background()
+    repo := repository.NewMemoryUserRepository()
+
+    seeded, err := seedSampleUsers(ctx, repo)
+    if err != nil || seeded != len(sampleUsers) {
+        t.Fatalf("seedSampleUsers() = %d, %v, want %d", seeded, err, len(sampleUsers))
+    }
+    users, _ := repo.GetAll(ctx)
+    for _, user := range users {
+        if user.Role != model.RoleUser {
+            t.Errorf("Sample user %s has role %q, want only ordinary users", user.Email, user.Role)
+        }
+    }
+
+    // Seeding again leaves the existing users alone
+    if seeded, err := seedSampleUsers(ctx, repo); err != nil || seeded != 0 {
+        t.Errorf("Second seedSampleUsers() = %d, %v, want 0", seeded, err)
+    }
+}
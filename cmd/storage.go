@@ -0,0 +1,94 @@
+package main
+
+import (
+    "errors"
+    "io/fs"
+    "log"
+
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/repository"
+)
+
+// storage holds the repositories of the configured backend
+type storage struct {
+    // db is nil for the memory backend
+    db            *database.DB
+    users         repository.UserRepositoryInterface
+    refreshTokens repository.RefreshTokenRepositoryInterface
+
+    // memory and snapshotPath are set when the memory backend persists
+    // its users
+    memory       *repository.MemoryUserRepository
+    snapshotPath string
+}
+
+func openStorage(cfg database.Config) (*storage, error) {
+    if cfg.Driver == database.DriverMemory {
+        return openMemoryStorage(cfg.SnapshotPath)
+    }
+
+    db, err := database.Open(cfg)
+    if err != nil {
+        return nil, err
+    }
+    return &storage{
+        db:    db,
+        users: newUserRepository(db),
+        // The refresh token queries are portable across the SQL backends
+        refreshTokens: repository.NewRefreshTokenRepository(db),
+    }, nil
+}
+
+// openMemoryStorage starts from the snapshot at path, or empty when there
+// is none yet. An empty path keeps nothing across restarts.
+func openMemoryStorage(path string) (*storage, error) {
+    users := repository.NewMemoryUserRepository()
+    if path != "" {
+        err := users.LoadSnapshot(path)
+        if err != nil && !errors.Is(err, fs.ErrNotExist) {
+            return nil, err
+        }
+        if err == nil {
+            log.Printf("Loaded users from snapshot %s", path)
+        }
+    }
+    return &storage{
+        users:         users,
+        refreshTokens: repository.NewMemoryRefreshTokenRepository(),
+        memory:        users,
+        snapshotPath:  path,
+    }, nil
+}
+
+// newUserRepository returns the user repository for the database's driver
+func newUserRepository(db *database.DB) repository.UserRepositoryInterface {
+    switch db.Driver {
+    case database.DriverSQLite:
+        return repository.NewSQLiteUserRepository(db)
+    case database.DriverPostgres:
+        return repository.NewPostgresUserRepository(db)
+    default:
+        return repository.NewUserRepository(db)
+    }
+}
+
+// Close saves the memory backend's snapshot or closes the database pool
+func (s *storage) Close() error {
+    if s.db != nil {
+        return s.db.Close()
+    }
+    if s.snapshotPath == "" {
+        return nil
+    }
+    if err := s.memory.SaveSnapshot(s.snapshotPath); err != nil {
+        return err
+    }
+    log.Printf("Saved users to snapshot %s", s.snapshotPath)
+    return nil
+}
+
+func closeStorage(s *storage) {
+    if err := s.Close(); err != nil {
+        log.Printf("Failed to close storage: %v", err)
+    }
+}
@@ -1,33 +1,87 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useCallback } from 'react';
 import './App.css';
 import UserList from './components/UserList';
 import UserForm from './components/UserForm';
-import { userService } from './services/api';
+import LoginForm from './components/LoginForm';
+import { authService, isStale, onSessionExpired, userService } from './services/api';
 
 function App() {
+  const [loggedIn, setLoggedIn] = useState(authService.isLoggedIn());
+  const [currentUser, setCurrentUser] = useState(null);
   const [users, setUsers] = useState([]);
   const [selectedUser, setSelectedUser] = useState(null);
+  const [selectedETag, setSelectedETag] = useState(null);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState(null);
 
-  // Fetch all users
-  const fetchUsers = async () => {
+  const isAdmin = currentUser && currentUser.role === 'admin';
+
+  // Forget the session when the refresh token is no longer accepted
+  useEffect(() => {
+    onSessionExpired(() => {
+      setLoggedIn(false);
+      setCurrentUser(null);
+      setUsers([]);
+      setSelectedUser(null);
+      setSelectedETag(null);
+    });
+  }, []);
+
+  // Fetch the users the current user may see: everyone for admins,
+  // only themselves otherwise
+  const fetchUsers = useCallback(async () => {
     try {
       setLoading(true);
       setError(null);
-      const data = await userService.getAllUsers();
-      setUsers(data || []);
+      const me = await userService.getCurrentUser();
+      setCurrentUser(me);
+      if (me.role === 'admin') {
+        const data = await userService.getAllUsers();
+        setUsers(data || []);
+      } else {
+        setUsers([me]);
+      }
     } catch (err) {
       setError('Failed to fetch users');
       console.error('Error fetching users:', err);
     } finally {
       setLoading(false);
     }
-  };
+  }, []);
 
   useEffect(() => {
-    fetchUsers();
-  }, []);
+    if (loggedIn) {
+      fetchUsers();
+    }
+  }, [loggedIn, fetchUsers]);
+
+  // Log in; LoginForm shows the error if this throws
+  const handleLogin = async (email, password) => {
+    await authService.login(email, password);
+    setLoggedIn(true);
+  };
+
+  // Log out
+  const handleLogout = async () => {
+    try {
+      await authService.logout();
+    } catch (err) {
+      console.error('Error logging out:', err);
+    }
+    setLoggedIn(false);
+    setCurrentUser(null);
+    setUsers([]);
+    setSelectedUser(null);
+    setSelectedETag(null);
+    setError(null);
+  };
+
+  // Load a user into the form along with the ETag its update must send
+  const loadUser = async (id) => {
+    const { user, etag } = await userService.getUser(id);
+    setSelectedUser(user);
+    setSelectedETag(etag);
+  };
 
   // Create or update user
   const handleSaveUser = async (userData) => {
@@ -35,14 +89,24 @@ function App() {
       setError(null);
       if (selectedUser) {
         // Update existing user
-        await userService.updateUser(selectedUser.id, userData);
+        await userService.updateUser(selectedUser.id, userData, selectedETag);
       } else {
         // Create new user
         await userService.createUser(userData);
       }
       setSelectedUser(null);
+      setSelectedETag(null);
       fetchUsers();
     } catch (err) {
+      if (isStale(err)) {
+        // Show the current values rather than overwrite someone else's change
+        setError('This user was changed by someone else. Review the current values and save again.');
+        loadUser(selectedUser.id).catch((loadErr) => {
+          console.error('Error reloading user:', loadErr);
+        });
+        fetchUsers();
+        return;
+      }
       setError('Failed to save user');
       console.error('Error saving user:', err);
     }
@@ -53,57 +117,100 @@ function App() {
     if (window.confirm('Are you sure you want to delete this user?')) {
       try {
         setError(null);
-        await userService.deleteUser(id);
+        // The list carries no ETags, so read the user for one
+        const { etag } = await userService.getUser(id);
+        await userService.deleteUser(id, etag);
+        if (selectedUser && selectedUser.id === id) {
+          setSelectedUser(null);
+          setSelectedETag(null);
+        }
         fetchUsers();
       } catch (err) {
+        if (isStale(err)) {
+          setError('This user was changed by someone else. Review the list and try again.');
+          fetchUsers();
+          return;
+        }
         setError('Failed to delete user');
         console.error('Error deleting user:', err);
       }
     }
   };
 
-  // Edit user
-  const handleEditUser = (user) => {
-    setSelectedUser(user);
+  // Edit user, starting from its current values
+  const handleEditUser = async (user) => {
+    try {
+      setError(null);
+      await loadUser(user.id);
+    } catch (err) {
+      setError('Failed to load user');
+      console.error('Error loading user:', err);
+    }
   };
 
   // Cancel edit
   const handleCancelEdit = () => {
     setSelectedUser(null);
+    setSelectedETag(null);
   };
 
+  if (!loggedIn) {
+    return (
+      <div className="App">
+        <header className="App-header">
+          <h1>User Management System</h1>
+        </header>
+
+        <main className="App-main">
+          <div className="login-section">
+            <h2>Log In</h2>
+            <LoginForm onLogin={handleLogin} />
+          </div>
+        </main>
+      </div>
+    );
+  }
+
   return (
     <div className="App">
       <header className="App-header">
         <h1>User Management System</h1>
+        <div className="session">
+          {currentUser && <span>Signed in as {currentUser.email}</span>}
+          <button className="logout-btn" onClick={handleLogout}>
+            Log Out
+          </button>
+        </div>
       </header>
-      
+
       <main className="App-main">
         {error && (
           <div className="error-message">
             {error}
           </div>
         )}
-        
+
         <div className="container">
-          <div className="form-section">
-            <h2>{selectedUser ? 'Edit User' : 'Add New User'}</h2>
-            <UserForm
-              user={selectedUser}
-              onSave={handleSaveUser}
-              onCancel={handleCancelEdit}
-            />
-          </div>
-          
+          {(isAdmin || selectedUser) && (
+            <div className="form-section">
+              <h2>{selectedUser ? 'Edit User' : 'Add New User'}</h2>
+              <UserForm
+                user={selectedUser}
+                onSave={handleSaveUser}
+                onCancel={handleCancelEdit}
+              />
+            </div>
+          )}
+
           <div className="list-section">
-            <h2>Users List</h2>
+            <h2>{isAdmin ? 'Users List' : 'My Account'}</h2>
             {loading ? (
               <div className="loading">Loading...</div>
             ) : (
               <UserList
                 users={users}
                 onEdit={handleEditUser}
-                onDelete={handleDeleteUser}
+                onDelete={isAdmin ? handleDeleteUser : null}
               />
             )}
           </div>
@@ -113,4 +220,4 @@ function App() {
   );
 }
 
-export default App;
\ No newline at end of file
+export default App;
@@ -0,0 +1,78 @@
+import React, { useState } from 'react';
+import './UserForm.css';
+
+function LoginForm({ onLogin }) {
+  const [formData, setFormData] = useState({
+    email: '',
+    password: ''
+  });
+
+  const [error, setError] = useState(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleChange = (e) => {
+    const { name, value } = e.target;
+    setFormData(prev => ({
+      ...prev,
+      [name]: value
+    }));
+    setError(null);
+  };
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    if (!formData.email.trim() || !formData.password) {
+      setError('Email and password are required');
+      return;
+    }
+
+    try {
+      setSubmitting(true);
+      await onLogin(formData.email, formData.password);
+    } catch (err) {
+      setError(err.response && err.response.status === 401
+        ? 'Invalid email or password'
+        : 'Failed to log in');
+      setSubmitting(false);
+    }
+  };
+
+  return (
+    <form className="user-form" onSubmit={handleSubmit}>
+      <div className="form-group">
+        <label htmlFor="login-email">Email:</label>
+        <input
+          type="email"
+          id="login-email"
+          name="email"
+          autoComplete="username"
+          value={formData.email}
+          onChange={handleChange}
+          className={error ? 'error' : ''}
+        />
+      </div>
+
+      <div className="form-group">
+        <label htmlFor="login-password">Password:</label>
+        <input
+          type="password"
+          id="login-password"
+          name="password"
+          autoComplete="current-password"
+          value={formData.password}
+          onChange={handleChange}
+          className={error ? 'error' : ''}
+        />
+        {error && <span className="error-message">{error}</span>}
+      </div>
+
+      <div className="form-actions">
+        <button type="submit" className="submit-btn" disabled={submitting}>
+          Log In
+        </button>
+      </div>
+    </form>
+  );
+}
+
+export default LoginForm;
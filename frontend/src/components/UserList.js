@@ -28,12 +28,14 @@ function UserList({ users, onEdit, onDelete }) {
                 >
                   Edit
                 </button>
-                <button 
-                  className="delete-btn" 
-                  onClick={() => onDelete(user.id)}
-                >
-                  Delete
-                </button>
+                {onDelete && (
+                  <button 
+                    className="delete-btn" 
+                    onClick={() => onDelete(user.id)}
+                  >
+                    Delete
+                  </button>
+                )}
               </td>
             </tr>
           ))}
@@ -9,17 +9,128 @@ const api = axios.create({
   },
 });
 
+// The tokens from /auth/login, kept across page reloads
+const TOKENS_KEY = 'go-crud-api.tokens';
+
+const tokenStore = {
+  get: () => JSON.parse(localStorage.getItem(TOKENS_KEY) || 'null'),
+  set: (tokens) => localStorage.setItem(TOKENS_KEY, JSON.stringify(tokens)),
+  clear: () => localStorage.removeItem(TOKENS_KEY),
+};
+
+const isAuthRequest = (config) => config.url.startsWith('/auth/');
+
+// Called when the refresh token is rejected and the user has to log in again
+let sessionExpiredHandler = () => {};
+
+export const onSessionExpired = (handler) => {
+  sessionExpiredHandler = handler;
+};
+
+// Send the access token with every request except the /auth ones
+api.interceptors.request.use((config) => {
+  const tokens = tokenStore.get();
+  if (tokens && !isAuthRequest(config)) {
+    config.headers.Authorization = `Bearer ${tokens.access_token}`;
+  }
+  return config;
+});
+
+// Refresh tokens are single use, and presenting one twice ends the
+// session, so concurrent requests that get a 401 share one refresh
+let refreshing = null;
+
+const refreshTokens = async () => {
+  const tokens = tokenStore.get();
+  if (!tokens) {
+    throw new Error('Not logged in');
+  }
+  const response = await api.post('/auth/refresh', { refresh_token: tokens.refresh_token });
+  tokenStore.set(response.data);
+};
+
+// When the access token has expired, refresh it and retry the request once
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const { config, response } = error;
+    if (!response || response.status !== 401 || isAuthRequest(config) || config.retried) {
+      throw error;
+    }
+
+    if (!refreshing) {
+      refreshing = refreshTokens().finally(() => {
+        refreshing = null;
+      });
+    }
+    try {
+      await refreshing;
+    } catch (refreshError) {
+      if (!refreshError.response || refreshError.response.status === 401) {
+        tokenStore.clear();
+        sessionExpiredHandler();
+      }
+      throw error;
+    }
+
+    config.retried = true;
+    return api(config);
+  }
+);
+
+export const authService = {
+  // Log in and keep the tokens for later requests
+  login: async (email, password) => {
+    const response = await api.post('/auth/login', { email, password });
+    tokenStore.set(response.data);
+  },
+
+  // Revoke the session and forget the tokens
+  logout: async () => {
+    const tokens = tokenStore.get();
+    tokenStore.clear();
+    if (tokens) {
+      await api.post('/auth/logout', { refresh_token: tokens.refresh_token });
+    }
+  },
+
+  isLoggedIn: () => tokenStore.get() !== null,
+};
+
+// The largest page GET /users serves
+const PAGE_SIZE = 100;
+
+// The URL of the next page from a Link header, or null on the last page
+const nextPage = (link) => {
+  const match = /<([^>]+)>;\s*rel="next"/.exec(link || '');
+  return match ? match[1] : null;
+};
+
 export const userService = {
-  // Get all users
+  // Get all users (admins only), following the Link header through
+  // every page of the list
   getAllUsers: async () => {
-    const response = await api.get('/users');
+    const users = [];
+    let url = `/users?limit=${PAGE_SIZE}`;
+    while (url) {
+      const response = await api.get(url);
+      users.push(...response.data);
+      url = nextPage(response.headers.link);
+    }
+    return users;
+  },
+
+  // Get the logged in user
+  getCurrentUser: async () => {
+    const response = await api.get('/users/me');
     return response.data;
   },
 
-  // Get a single user
+  // Get a single user with its ETag, which updateUser and deleteUser
+  // need to send back as If-Match
   getUser: async (id) => {
     const response = await api.get(`/users/${id}`);
-    return response.data;
+    return { user: response.data, etag: response.headers.etag };
   },
 
   // Create a new user
@@ -28,16 +139,24 @@ export const userService = {
     return response.data;
   },
 
-  // Update a user
-  updateUser: async (id, userData) => {
-    const response = await api.put(`/users/${id}`, userData);
+  // Update a user; fails with 412 if it changed since etag was read
+  updateUser: async (id, userData, etag) => {
+    const response = await api.put(`/users/${id}`, userData, {
+      headers: { 'If-Match': etag },
+    });
     return response.data;
   },
 
-  // Delete a user
-  deleteUser: async (id) => {
-    await api.delete(`/users/${id}`);
+  // Delete a user; fails with 412 if it changed since etag was read
+  deleteUser: async (id, etag) => {
+    await api.delete(`/users/${id}`, {
+      headers: { 'If-Match': etag },
+    });
   },
 };
 
-export default api;
\ No newline at end of file
+// Whether a write was refused because the user changed since it was read
+export const isStale = (err) =>
+  Boolean(err.response && err.response.status === 412);
+
+export default api;
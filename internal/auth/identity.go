@@ -0,0 +1,28 @@
+package auth
+
+import (
+    "context"
+
+    "go-crud-api/internal/model"
+)
+
+// Identity describes the authenticated caller of a request
+type Identity struct {
+    UserID string
+    Email  string
+    Role   model.Role
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the caller identity
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+    return context.WithValue(ctx, contextKey{}, id)
+}
+
+// IdentityFromContext returns the caller identity stored by the
+// authentication middleware, if any
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+    id, ok := ctx.Value(contextKey{}).(Identity)
+    return id, ok
+}
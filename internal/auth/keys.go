@@ -0,0 +1,156 @@
+package auth
+
+import (
+    "crypto/ed25519"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+
+    "github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a key used to sign or verify access tokens. The ID is
+// published in the token "kid" header so keys can be rotated.
+type SigningKey struct {
+    ID      string
+    Method  jwt.SigningMethod
+    Private interface{}
+    Public  interface{}
+}
+
+// NewHMACKey returns an HS256 key. The secret should be at least 32 bytes.
+func NewHMACKey(id string, secret []byte) SigningKey {
+    return SigningKey{
+        ID:      id,
+        Method:  jwt.SigningMethodHS256,
+        Private: secret,
+        Public:  secret,
+    }
+}
+
+// NewRSAKey returns an RS256 key
+func NewRSAKey(id string, key *rsa.PrivateKey) SigningKey {
+    return SigningKey{
+        ID:      id,
+        Method:  jwt.SigningMethodRS256,
+        Private: key,
+        Public:  &key.PublicKey,
+    }
+}
+
+// NewEd25519Key returns an EdDSA key
+func NewEd25519Key(id string, key ed25519.PrivateKey) SigningKey {
+    return SigningKey{
+        ID:      id,
+        Method:  jwt.SigningMethodEdDSA,
+        Private: key,
+        Public:  key.Public(),
+    }
+}
+
+// Signing algorithms supported by LoadSigningKey
+const (
+    AlgorithmHS256 = "HS256"
+    AlgorithmRS256 = "RS256"
+    AlgorithmEdDSA = "EdDSA"
+)
+
+// KeyConfig selects the token signing key. The yaml, toml and env tags
+// name each setting in configuration files and the environment.
+type KeyConfig struct {
+    // Algorithm is HS256, RS256 or EdDSA
+    Algorithm string `yaml:"algorithm" toml:"algorithm" env:"JWT_ALGORITHM"`
+    // KeyID is published in the token "kid" header
+    KeyID string `yaml:"key_id" toml:"key_id" env:"JWT_KEY_ID"`
+    // Secret is the HMAC secret for HS256
+    Secret string `yaml:"secret" toml:"secret" env:"JWT_SECRET" secret:"true"`
+    // PrivateKeyFile is a PEM encoded private key for RS256 and EdDSA
+    PrivateKeyFile string `yaml:"private_key_file" toml:"private_key_file" env:"JWT_PRIVATE_KEY_FILE"`
+}
+
+// DefaultKeyConfig returns HS256 without a secret, so LoadSigningKey
+// generates one
+func DefaultKeyConfig() KeyConfig {
+    return KeyConfig{Algorithm: AlgorithmHS256, KeyID: "default"}
+}
+
+// Validate reports an unknown algorithm or a missing private key file
+func (c KeyConfig) Validate() error {
+    switch c.Algorithm {
+    case AlgorithmHS256:
+        return nil
+    case AlgorithmRS256, AlgorithmEdDSA:
+        if c.PrivateKeyFile == "" {
+            return fmt.Errorf("private_key_file is required for %s", c.Algorithm)
+        }
+        return nil
+    default:
+        return fmt.Errorf("unsupported algorithm %q (want %s, %s or %s)",
+            c.Algorithm, AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA)
+    }
+}
+
+// LoadSigningKey builds the token signing key cfg describes. When HS256
+// is selected without a secret a random one is generated, which
+// invalidates all tokens whenever the process restarts.
+func LoadSigningKey(cfg KeyConfig) (SigningKey, error) {
+    if err := cfg.Validate(); err != nil {
+        return SigningKey{}, err
+    }
+
+    switch cfg.Algorithm {
+    case AlgorithmHS256:
+        secret := []byte(cfg.Secret)
+        if len(secret) == 0 {
+            log.Println("JWT secret is not set, generating an ephemeral signing secret")
+            secret = make([]byte, 32)
+            if _, err := rand.Read(secret); err != nil {
+                return SigningKey{}, fmt.Errorf("failed to generate JWT secret: %v", err)
+            }
+        }
+        return NewHMACKey(cfg.KeyID, secret), nil
+    default:
+        data, err := os.ReadFile(cfg.PrivateKeyFile)
+        if err != nil {
+            return SigningKey{}, fmt.Errorf("failed to read JWT private key: %v", err)
+        }
+        return ParsePrivateKeyPEM(cfg.KeyID, cfg.Algorithm, data)
+    }
+}
+
+// ParsePrivateKeyPEM parses a PKCS#8 (or PKCS#1 for RSA) private key
+func ParsePrivateKeyPEM(kid, alg string, data []byte) (SigningKey, error) {
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return SigningKey{}, errors.New("no PEM block found in private key")
+    }
+
+    key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes)
+        if rsaErr != nil {
+            return SigningKey{}, fmt.Errorf("failed to parse private key: %v", err)
+        }
+        key = rsaKey
+    }
+
+    switch k := key.(type) {
+    case *rsa.PrivateKey:
+        if alg != "RS256" {
+            return SigningKey{}, fmt.Errorf("RSA key cannot be used with %s", alg)
+        }
+        return NewRSAKey(kid, k), nil
+    case ed25519.PrivateKey:
+        if alg != "EdDSA" {
+            return SigningKey{}, fmt.Errorf("Ed25519 key cannot be used with %s", alg)
+        }
+        return NewEd25519Key(kid, k), nil
+    default:
+        return SigningKey{}, fmt.Errorf("unsupported private key type %T", key)
+    }
+}
@@ -0,0 +1,50 @@
+package auth
+
+import "go-crud-api/internal/model"
+
+// Permission is an action a role may be granted
+type Permission string
+
+const (
+    PermListUsers        Permission = "users:list"
+    PermReadAnyUser      Permission = "users:read:any"
+    PermReadOwnUser      Permission = "users:read:own"
+    PermUpdateAnyUser    Permission = "users:update:any"
+    PermUpdateOwnUser    Permission = "users:update:own"
+    PermDeleteAnyUser    Permission = "users:delete:any"
+    PermManageRoles      Permission = "users:roles:manage"
+    // PermReadDeletedUsers allows listing and reading soft-deleted users
+    PermReadDeletedUsers Permission = "users:deleted:read"
+    PermRestoreUsers     Permission = "users:restore"
+)
+
+var rolePermissions = map[model.Role]map[Permission]bool{
+    model.RoleAdmin: {
+        PermListUsers:        true,
+        PermReadAnyUser:      true,
+        PermReadOwnUser:      true,
+        PermUpdateAnyUser:    true,
+        PermUpdateOwnUser:    true,
+        PermDeleteAnyUser:    true,
+        PermManageRoles:      true,
+        PermReadDeletedUsers: true,
+        PermRestoreUsers:     true,
+    },
+    model.RoleUser: {
+        PermReadOwnUser:   true,
+        PermUpdateOwnUser: true,
+    },
+    model.RoleReadOnly: {
+        PermReadOwnUser: true,
+    },
+}
+
+// HasPermission reports whether role grants p. Unknown roles grant nothing.
+func HasPermission(role model.Role, p Permission) bool {
+    return rolePermissions[role][p]
+}
+
+// Can reports whether the identity's role grants p
+func (id Identity) Can(p Permission) bool {
+    return HasPermission(id.Role, p)
+}
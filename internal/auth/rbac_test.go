@@ -0,0 +1,44 @@
+package auth
+
+import (
+    "testing"
+
+    "go-crud-api/internal/model"
+)
+
+func TestHasPermission(t *testing.T) {
+    tests := []struct {
+        role model.Role
+        perm Permission
+        want bool
+    }{
+        {model.RoleAdmin, PermListUsers, true},
+        {model.RoleAdmin, PermDeleteAnyUser, true},
+        {model.RoleAdmin, PermManageRoles, true},
+        {model.RoleAdmin, PermReadDeletedUsers, true},
+        {model.RoleAdmin, PermRestoreUsers, true},
+        {model.RoleUser, PermListUsers, false},
+        {model.RoleUser, PermReadAnyUser, false},
+        {model.RoleUser, PermReadOwnUser, true},
+        {model.RoleUser, PermUpdateOwnUser, true},
+        {model.RoleUser, PermDeleteAnyUser, false},
+        {model.RoleUser, PermManageRoles, false},
+        {model.RoleUser, PermReadDeletedUsers, false},
+        {model.RoleUser, PermRestoreUsers, false},
+        {model.RoleReadOnly, PermReadOwnUser, true},
+        {model.RoleReadOnly, PermUpdateOwnUser, false},
+        {"", PermReadOwnUser, false},
+        {"root", PermListUsers, false},
+    }
+
+    for _, tt := range tests {
+        t.Run(string(tt.role)+" "+string(tt.perm), func(t *testing.T) {
+            if got := HasPermission(tt.role, tt.perm); got != tt.want {
+                t.Errorf("HasPermission(%q, %q) = %v, want %v", tt.role, tt.perm, got, tt.want)
+            }
+            if got := (Identity{Role: tt.role}).Can(tt.perm); got != tt.want {
+                t.Errorf("Identity.Can(%q) = %v, want %v", tt.perm, got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
+    "time"
+)
+
+// DefaultRefreshTokenTTL is how long a refresh token can be used to
+// obtain new access tokens
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// NewRefreshToken returns a random opaque refresh token and the hash
+// under which it should be stored
+func NewRefreshToken() (token string, hash string, err error) {
+    b := make([]byte, 32)
+    if _, err := rand.Read(b); err != nil {
+        return "", "", fmt.Errorf("failed to generate refresh token: %v", err)
+    }
+    token = base64.RawURLEncoding.EncodeToString(b)
+    return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hex encoded SHA-256 of a refresh token.
+// Refresh tokens carry 256 bits of entropy so a fast hash is sufficient.
+func HashRefreshToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+    "github.com/google/uuid"
+    "go-crud-api/internal/model"
+)
+
+// Issuer is the "iss" claim of every access token
+const Issuer = "go-crud-api"
+
+// DefaultAccessTokenTTL is how long issued access tokens stay valid
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// ErrInvalidToken is returned for tokens that are malformed, expired,
+// signed with an unknown key or otherwise not acceptable
+var ErrInvalidToken = errors.New("invalid access token")
+
+// Claims are the JWT claims carried by an access token
+type Claims struct {
+    Email string     `json:"email"`
+    Role  model.Role `json:"role"`
+    jwt.RegisteredClaims
+}
+
+// TokenService issues and validates signed access tokens
+type TokenService struct {
+    signing SigningKey
+    keys    map[string]SigningKey
+    ttl     time.Duration
+    now     func() time.Time
+}
+
+// NewTokenService signs tokens with signing and accepts tokens signed by
+// it or by any of the additional verification keys, e.g. retired keys
+// that are still within the token lifetime.
+func NewTokenService(signing SigningKey, ttl time.Duration, verifyOnly ...SigningKey) *TokenService {
+    keys := map[string]SigningKey{signing.ID: signing}
+    for _, k := range verifyOnly {
+        keys[k.ID] = k
+    }
+    return &TokenService{
+        signing: signing,
+        keys:    keys,
+        ttl:     ttl,
+        now:     time.Now,
+    }
+}
+
+// Issue returns a signed access token for user and its expiry time
+func (s *TokenService) Issue(user model.User) (string, time.Time, error) {
+    now := s.now()
+    expiresAt := now.Add(s.ttl)
+
+    claims := Claims{
+        Email: user.Email,
+        Role:  user.Role,
+        RegisteredClaims: jwt.RegisteredClaims{
+            ID:        uuid.New().String(),
+            Issuer:    Issuer,
+            Subject:   user.ID,
+            IssuedAt:  jwt.NewNumericDate(now),
+            NotBefore: jwt.NewNumericDate(now),
+            ExpiresAt: jwt.NewNumericDate(expiresAt),
+        },
+    }
+
+    token := jwt.NewWithClaims(s.signing.Method, claims)
+    token.Header["kid"] = s.signing.ID
+
+    signed, err := token.SignedString(s.signing.Private)
+    if err != nil {
+        return "", time.Time{}, fmt.Errorf("failed to sign token: %v", err)
+    }
+    return signed, expiresAt, nil
+}
+
+// Parse validates the token signature and claims and returns the caller
+// identity it carries
+func (s *TokenService) Parse(tokenString string) (Identity, error) {
+    var claims Claims
+    _, err := jwt.ParseWithClaims(tokenString, &claims, s.keyFunc,
+        jwt.WithIssuer(Issuer),
+        jwt.WithExpirationRequired(),
+        jwt.WithTimeFunc(s.now),
+    )
+    if err != nil {
+        return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+    }
+    if claims.Subject == "" {
+        return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+    }
+
+    return Identity{UserID: claims.Subject, Email: claims.Email, Role: claims.Role}, nil
+}
+
+// TTL returns the lifetime of issued access tokens
+func (s *TokenService) TTL() time.Duration {
+    return s.ttl
+}
+
+func (s *TokenService) keyFunc(token *jwt.Token) (interface{}, error) {
+    kid, _ := token.Header["kid"].(string)
+    key, ok := s.keys[kid]
+    if !ok {
+        return nil, fmt.Errorf("unknown key id %q", kid)
+    }
+    // Reject tokens whose header algorithm does not match the key, which
+    // prevents e.g. an RSA public key being used as an HMAC secret.
+    if token.Method.Alg() != key.Method.Alg() {
+        return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+    }
+    return key.Public, nil
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+    "crypto/ed25519"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/golang-jwt/jwt/v5"
+    "go-crud-api/internal/model"
+)
+
+func testKeys(t *testing.T) map[string]SigningKey {
+    t.Helper()
+
+    rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatalf("Failed to generate RSA key: %v", err)
+    }
+    _, edKey, err := ed25519.GenerateKey(rand.Reader)
+    if err != nil {
+        t.Fatalf("Failed to generate Ed25519 key: %v", err)
+    }
+
+    return map[string]SigningKey{
+        "HS256": NewHMACKey("hmac-1", []byte("0123456789abcdef0123456789abcdef")),
+        "RS256": NewRSAKey("rsa-1", rsaKey),
+        "EdDSA": NewEd25519Key("ed-1", edKey),
+    }
+}
+
+func TestTokenService_IssueAndParse(t *testing.T) {
+    user := model.User{ID: "user-123", Email: "john@example.com", Role: model.RoleAdmin}
+
+    for alg, key := range testKeys(t) {
+        t.Run(alg, func(t *testing.T) {
+            s := NewTokenService(key, time.Minute)
+
+            token, expiresAt, err := s.Issue(user)
+            if err != nil {
+                t.Fatalf("Issue returned error: %v", err)
+            }
+            if time.Until(expiresAt) > time.Minute {
+                t.Errorf("Unexpected expiry %v", expiresAt)
+            }
+
+            parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+            if err != nil {
+                t.Fatalf("Failed to parse token header: %v", err)
+            }
+            if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != alg {
+                t.Errorf("Unexpected header %v", parsed.Header)
+            }
+
+            identity, err := s.Parse(token)
+            if err != nil {
+                t.Fatalf("Parse returned error: %v", err)
+            }
+            if identity.UserID != user.ID || identity.Email != user.Email || identity.Role != user.Role {
+                t.Errorf("Parse() = %+v, want user %+v", identity, user)
+            }
+        })
+    }
+}
+
+func TestTokenService_ParseRejects(t *testing.T) {
+    keys := testKeys(t)
+    user := model.User{ID: "user-123", Email: "john@example.com"}
+    s := NewTokenService(keys["HS256"], time.Minute)
+
+    expired := NewTokenService(keys["HS256"], time.Minute)
+    expired.now = func() time.Time { return time.Now().Add(-time.Hour) }
+    expiredToken, _, _ := expired.Issue(user)
+
+    otherKey := NewTokenService(NewHMACKey("hmac-1", []byte("another-secret-another-secret-xx")), time.Minute)
+    forgedToken, _, _ := otherKey.Issue(user)
+
+    unknownKid := NewTokenService(NewHMACKey("hmac-2", []byte("0123456789abcdef0123456789abcdef")), time.Minute)
+    unknownKidToken, _, _ := unknownKid.Issue(user)
+
+    // An HS256 token signed with the RSA public key must not verify
+    // against the RSA key registered under the same kid.
+    rsaService := NewTokenService(keys["RS256"], time.Minute)
+    pub, _ := x509.MarshalPKIXPublicKey(keys["RS256"].Public)
+    confused := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+        RegisteredClaims: jwt.RegisteredClaims{
+            Issuer:    Issuer,
+            Subject:   user.ID,
+            ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+        },
+    })
+    confused.Header["kid"] = keys["RS256"].ID
+    confusedToken, _ := confused.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+
+    tests := []struct {
+        name    string
+        service *TokenService
+        token   string
+    }{
+        {name: "garbage", service: s, token: "not-a-token"},
+        {name: "expired", service: s, token: expiredToken},
+        {name: "wrong signature", service: s, token: forgedToken},
+        {name: "unknown key id", service: s, token: unknownKidToken},
+        {name: "algorithm confusion", service: rsaService, token: confusedToken},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := tt.service.Parse(tt.token); !errors.Is(err, ErrInvalidToken) {
+                t.Errorf("Parse() error = %v, want ErrInvalidToken", err)
+            }
+        })
+    }
+}
+
+func TestTokenService_VerifyOnlyKeys(t *testing.T) {
+    keys := testKeys(t)
+    user := model.User{ID: "user-123"}
+
+    old := NewTokenService(keys["RS256"], time.Minute)
+    token, _, _ := old.Issue(user)
+
+    rotated := NewTokenService(keys["EdDSA"], time.Minute, keys["RS256"])
+    if _, err := rotated.Parse(token); err != nil {
+        t.Errorf("Token signed with retired key should still verify: %v", err)
+    }
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+    keys := testKeys(t)
+
+    rsaDER, _ := x509.MarshalPKCS8PrivateKey(keys["RS256"].Private)
+    rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaDER})
+    edDER, _ := x509.MarshalPKCS8PrivateKey(keys["EdDSA"].Private)
+    edPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER})
+
+    tests := []struct {
+        name    string
+        alg     string
+        data    []byte
+        wantErr bool
+    }{
+        {name: "RSA key", alg: "RS256", data: rsaPEM},
+        {name: "Ed25519 key", alg: "EdDSA", data: edPEM},
+        {name: "algorithm mismatch", alg: "EdDSA", data: rsaPEM, wantErr: true},
+        {name: "not PEM", alg: "RS256", data: []byte("garbage"), wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            key, err := ParsePrivateKeyPEM("kid", tt.alg, tt.data)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParsePrivateKeyPEM() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && key.Method.Alg() != tt.alg {
+                t.Errorf("Got method %s, want %s", key.Method.Alg(), tt.alg)
+            }
+        })
+    }
+}
@@ -0,0 +1,332 @@
+// Package config loads the application configuration. Every setting has
+// a default, which a YAML or TOML file can override, which an environment
+// variable can override, which a command-line flag can override.
+//
+// Settings are the tagged fields of the section structs: the yaml and toml
+// tags give the key within the section's table in a file, the env tag the
+// environment variable, and the flag is the dotted key with hyphens, e.g.
+// server.read_header_timeout is --server.read-header-timeout. Fields
+// tagged secret:"true" are redacted when the configuration is printed.
+package config
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+    "gopkg.in/yaml.v3"
+
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/health"
+    "go-crud-api/internal/migrate"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/server"
+    "go-crud-api/internal/tracing"
+)
+
+// Defaults for the settings this package owns
+const (
+    // DefaultPurgeRetention is how long soft-deleted users can be restored
+    DefaultPurgeRetention = 30 * 24 * time.Hour
+    // DefaultPurgeInterval is how often the server purges expired users
+    DefaultPurgeInterval = time.Hour
+)
+
+// Config is the complete application configuration
+type Config struct {
+    Server   server.Config   `yaml:"server" toml:"server"`
+    Database database.Config `yaml:"database" toml:"database"`
+    Migrate  MigrateConfig   `yaml:"migrate" toml:"migrate"`
+    Purge    PurgeConfig     `yaml:"purge" toml:"purge"`
+    Seed     SeedConfig      `yaml:"seed" toml:"seed"`
+    Auth     auth.KeyConfig  `yaml:"auth" toml:"auth"`
+    Password password.Config `yaml:"password" toml:"password"`
+    Health   health.Config   `yaml:"health" toml:"health"`
+    Tracing  tracing.Config  `yaml:"tracing" toml:"tracing"`
+}
+
+// MigrateConfig controls schema migrations
+type MigrateConfig struct {
+    // OnStart applies pending migrations when the server starts; disable
+    // it to run migrations as a separate deployment step
+    OnStart     bool          `yaml:"on_start" toml:"on_start" env:"DB_MIGRATE_ON_START"`
+    LockTimeout time.Duration `yaml:"lock_timeout" toml:"lock_timeout" env:"DB_MIGRATE_LOCK_TIMEOUT"`
+}
+
+// PurgeConfig controls the removal of soft-deleted users and, on the same
+// schedule, of expired refresh tokens
+type PurgeConfig struct {
+    Retention time.Duration `yaml:"retention" toml:"retention" env:"USER_PURGE_RETENTION"`
+    // Interval between scheduled purges; 0 disables them
+    Interval time.Duration `yaml:"interval" toml:"interval" env:"USER_PURGE_INTERVAL"`
+}
+
+// SeedConfig controls development data
+type SeedConfig struct {
+    // SampleUsers saves the sample users, which have well-known
+    // passwords, at startup; for local development only
+    SampleUsers bool `yaml:"sample_users" toml:"sample_users" env:"SEED_SAMPLE_USERS"`
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() Config {
+    return Config{
+        Server:   server.DefaultConfig(),
+        Database: database.DefaultConfig(),
+        Migrate:  MigrateConfig{OnStart: true, LockTimeout: migrate.DefaultLockTimeout},
+        Purge:    PurgeConfig{Retention: DefaultPurgeRetention, Interval: DefaultPurgeInterval},
+        Auth:     auth.DefaultKeyConfig(),
+        Password: password.DefaultConfig(),
+        Health:   health.DefaultConfig(),
+        Tracing:  tracing.DefaultConfig(),
+    }
+}
+
+// Load builds the configuration from the command line args (without the
+// program name) and the environment, and returns the arguments left after
+// the flags, e.g. a subcommand. The file is named by --config or
+// CONFIG_FILE; its extension selects YAML (.yaml, .yml) or TOML (.toml).
+// Flag errors and -help print the usage to stderr. Load does not validate
+// the result.
+func Load(args []string) (Config, []string, error) {
+    return load(args, os.Getenv, os.Stderr)
+}
+
+// load is Load with the environment and the flag error output injected
+func load(args []string, getenv func(string) string, output io.Writer) (Config, []string, error) {
+    cfg := Default()
+
+    flags := flag.NewFlagSet("go-crud-api", flag.ContinueOnError)
+    flags.SetOutput(output)
+    file := flags.String("config", getenv("CONFIG_FILE"), "YAML or TOML configuration `file` (env CONFIG_FILE)")
+    // Flags are applied last, so they are collected rather than set
+    var set []flagValue
+    for _, s := range settings(&cfg) {
+        s := s
+        flags.Var(&flagValue{setting: s, set: &set}, flagName(s.key), s.usage())
+    }
+    if err := flags.Parse(args); err != nil {
+        return cfg, nil, err
+    }
+
+    if *file != "" {
+        if err := loadFile(&cfg, *file); err != nil {
+            return cfg, nil, err
+        }
+    }
+    for _, s := range settings(&cfg) {
+        if value := getenv(s.env); value != "" {
+            if err := s.parse(value); err != nil {
+                return cfg, nil, fmt.Errorf("invalid %s: %v", s.env, err)
+            }
+        }
+    }
+    byKey := make(map[string]setting)
+    for _, s := range settings(&cfg) {
+        byKey[s.key] = s
+    }
+    for _, f := range set {
+        // Already checked by flagValue.Set
+        byKey[f.setting.key].parse(f.value)
+    }
+    return cfg, flags.Args(), nil
+}
+
+func loadFile(cfg *Config, path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return fmt.Errorf("failed to read config file: %v", err)
+    }
+
+    switch ext := strings.ToLower(filepath.Ext(path)); ext {
+    case ".yaml", ".yml":
+        decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+        decoder.KnownFields(true)
+        if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+            return fmt.Errorf("invalid config file %s: %v", path, err)
+        }
+    case ".toml":
+        meta, err := toml.Decode(string(data), cfg)
+        if err != nil {
+            return fmt.Errorf("invalid config file %s: %v", path, err)
+        }
+        if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+            return fmt.Errorf("invalid config file %s: unknown setting %s", path, undecoded[0])
+        }
+    default:
+        return fmt.Errorf("config file %s must be .yaml, .yml or .toml, not %q", path, ext)
+    }
+    return nil
+}
+
+// Validate reports every invalid setting, each prefixed with its section
+func (c Config) Validate() error {
+    var errs []error
+    sections := []struct {
+        name string
+        err  error
+    }{
+        {"server", c.Server.Validate()},
+        {"database", c.Database.Validate()},
+        {"auth", c.Auth.Validate()},
+        {"password", c.Password.Validate()},
+        {"tracing", c.Tracing.Validate()},
+    }
+    for _, section := range sections {
+        for _, err := range unjoin(section.err) {
+            errs = append(errs, fmt.Errorf("%s: %w", section.name, err))
+        }
+    }
+    for _, s := range settings(&c) {
+        if d, ok := s.value.Interface().(time.Duration); ok && d < 0 {
+            errs = append(errs, fmt.Errorf("%s must not be negative", s.key))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// unjoin splits an errors.Join error into its parts
+func unjoin(err error) []error {
+    if err == nil {
+        return nil
+    }
+    if joined, ok := err.(interface{ Unwrap() []error }); ok {
+        return joined.Unwrap()
+    }
+    return []error{err}
+}
+
+// setting is one configurable field of a Config
+type setting struct {
+    // key is the dotted path in a file, e.g. "server.addr"
+    key    string
+    env    string
+    secret bool
+    value  reflect.Value
+}
+
+// settings lists the fields of cfg in declaration order
+func settings(cfg *Config) []setting {
+    var all []setting
+    var walk func(v reflect.Value, prefix string)
+    walk = func(v reflect.Value, prefix string) {
+        for i := 0; i < v.NumField(); i++ {
+            field := v.Type().Field(i)
+            key := prefix + field.Tag.Get("yaml")
+            if field.Type.Kind() == reflect.Struct {
+                walk(v.Field(i), key+".")
+                continue
+            }
+            all = append(all, setting{
+                key:    key,
+                env:    field.Tag.Get("env"),
+                secret: field.Tag.Get("secret") == "true",
+                value:  v.Field(i),
+            })
+        }
+    }
+    walk(reflect.ValueOf(cfg).Elem(), "")
+    return all
+}
+
+// parse sets the setting from its string form
+func (s setting) parse(value string) error {
+    switch s.value.Interface().(type) {
+    case time.Duration:
+        d, err := time.ParseDuration(value)
+        if err != nil {
+            return err
+        }
+        s.value.SetInt(int64(d))
+        return nil
+    }
+
+    switch s.value.Kind() {
+    case reflect.String:
+        s.value.SetString(value)
+    case reflect.Bool:
+        b, err := strconv.ParseBool(value)
+        if err != nil {
+            return err
+        }
+        s.value.SetBool(b)
+    case reflect.Int:
+        n, err := strconv.Atoi(value)
+        if err != nil {
+            return err
+        }
+        s.value.SetInt(int64(n))
+    default:
+        return fmt.Errorf("unsupported setting type %s", s.value.Type())
+    }
+    return nil
+}
+
+// String formats the setting like parse accepts it, redacting secrets
+func (s setting) String() string {
+    if d, ok := s.value.Interface().(time.Duration); ok {
+        return d.String()
+    }
+    value := fmt.Sprint(s.value.Interface())
+    if s.secret && value != "" {
+        return "[REDACTED]"
+    }
+    return value
+}
+
+// usage describes the setting's flag; the back-quoted type becomes the
+// placeholder in the flag list
+func (s setting) usage() string {
+    if s.value.Kind() == reflect.Bool {
+        return "overrides " + s.env
+    }
+    typ := s.value.Kind().String()
+    if _, ok := s.value.Interface().(time.Duration); ok {
+        typ = "duration"
+    }
+    return fmt.Sprintf("`%s` setting, overrides %s", typ, s.env)
+}
+
+func flagName(key string) string {
+    return strings.ReplaceAll(key, "_", "-")
+}
+
+// flagValue records a flag for load to apply after the file and the
+// environment
+type flagValue struct {
+    setting setting
+    value   string
+    set     *[]flagValue
+}
+
+func (f *flagValue) String() string {
+    if f == nil || f.set == nil {
+        return ""
+    }
+    return f.setting.String()
+}
+
+// Set checks value on a scratch copy of the setting and records it
+func (f *flagValue) Set(value string) error {
+    scratch := f.setting
+    scratch.value = reflect.New(f.setting.value.Type()).Elem()
+    if err := scratch.parse(value); err != nil {
+        return err
+    }
+    f.value = value
+    *f.set = append(*f.set, *f)
+    return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+    return f.setting.value.Kind() == reflect.Bool
+}
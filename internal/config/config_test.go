@@ -0,0 +1,229 @@
+package config
+
+import (
+    "bytes"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "gopkg.in/yaml.v3"
+)
+
+// env returns a getenv backed by vars
+func env(vars map[string]string) func(string) string {
+    return func(key string) string {
+        return vars[key]
+    }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+    cfg, args, err := load(nil, env(nil), io.Discard)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(args) != 0 {
+        t.Errorf("args = %q, want none", args)
+    }
+    if cfg != Default() {
+        t.Errorf("load() = %+v, want the defaults", cfg)
+    }
+}
+
+func TestLoadPrecedence(t *testing.T) {
+    file := writeFile(t, "config.yaml", `
+server:
+  addr: ":7000"
+  read_timeout: 7s
+  write_timeout: 7s
+database:
+  host: file-host
+  max_open_conns: 7
+`)
+    vars := map[string]string{
+        "CONFIG_FILE":        file,
+        "HTTP_READ_TIMEOUT":  "8s",
+        "HTTP_WRITE_TIMEOUT": "8s",
+        "DB_MAX_OPEN_CONNS":  "8",
+    }
+    args := []string{"--server.write-timeout=9s", "--database.max-open-conns", "9", "--migrate.on-start=false", "migrate", "status"}
+
+    cfg, rest, err := load(args, env(vars), io.Discard)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := strings.Join(rest, " "); got != "migrate status" {
+        t.Errorf("remaining args = %q, want the command", got)
+    }
+    checks := []struct {
+        name      string
+        got, want interface{}
+    }{
+        {"file over default", cfg.Server.Addr, ":7000"},
+        {"file over default", cfg.Database.Host, "file-host"},
+        {"env over file", cfg.Server.ReadTimeout, 8 * time.Second},
+        {"flag over env", cfg.Server.WriteTimeout, 9 * time.Second},
+        {"flag over env", cfg.Database.MaxOpenConns, 9},
+        {"bool flag", cfg.Migrate.OnStart, false},
+        {"untouched default", cfg.Server.IdleTimeout, Default().Server.IdleTimeout},
+    }
+    for _, c := range checks {
+        if c.got != c.want {
+            t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+        }
+    }
+}
+
+func TestLoadConfigFlagOverridesEnv(t *testing.T) {
+    yamlFile := writeFile(t, "config.yaml", "database:\n  host: yaml-host\n")
+    tomlFile := writeFile(t, "config.toml", "[database]\nhost = \"toml-host\"\n")
+
+    cfg, _, err := load([]string{"--config", tomlFile}, env(map[string]string{"CONFIG_FILE": yamlFile}), io.Discard)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cfg.Database.Host != "toml-host" {
+        t.Errorf("host = %q, want the --config file's", cfg.Database.Host)
+    }
+}
+
+func TestLoadFileFormats(t *testing.T) {
+    files := map[string]string{
+        "config.yaml": "server:\n  shutdown_timeout: 3s\ndatabase:\n  driver: sqlite\n  port: 5433\nmigrate:\n  on_start: false\n",
+        "config.yml":  "server:\n  shutdown_timeout: 3s\ndatabase:\n  driver: sqlite\n  port: 5433\nmigrate:\n  on_start: false\n",
+        "config.toml": "[server]\nshutdown_timeout = \"3s\"\n[database]\ndriver = \"sqlite\"\nport = 5433\n[migrate]\non_start = false\n",
+    }
+    for name, content := range files {
+        t.Run(name, func(t *testing.T) {
+            path := writeFile(t, name, content)
+            cfg, _, err := load([]string{"--config", path}, env(nil), io.Discard)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if cfg.Server.ShutdownTimeout != 3*time.Second || cfg.Database.Driver != "sqlite" ||
+                cfg.Database.Port != 5433 || cfg.Migrate.OnStart {
+                t.Errorf("load() = %+v, want the file's settings", cfg)
+            }
+            if cfg.Database.Host != "localhost" {
+                t.Errorf("host = %q, want the default kept", cfg.Database.Host)
+            }
+        })
+    }
+}
+
+func TestLoadRejectsBadInput(t *testing.T) {
+    tests := []struct {
+        name string
+        file string // name and content separated by a newline
+        vars map[string]string
+        args []string
+    }{
+        {name: "unknown yaml key", file: "c.yaml\nserver:\n  adress: \":1\"\n"},
+        {name: "unknown toml key", file: "c.toml\n[server]\nadress = \":1\"\n"},
+        {name: "unknown toml section", file: "c.toml\n[servr]\naddr = \":1\"\n"},
+        {name: "bad yaml duration", file: "c.yaml\nserver:\n  read_timeout: soon\n"},
+        {name: "unsupported extension", file: "c.json\n{}\n"},
+        {name: "missing file", args: []string{"--config", "/nonexistent/config.yaml"}},
+        {name: "bad env duration", vars: map[string]string{"HTTP_READ_TIMEOUT": "soon"}},
+        {name: "bad env int", vars: map[string]string{"DB_PORT": "mysql"}},
+        {name: "bad env bool", vars: map[string]string{"DB_MIGRATE_ON_START": "maybe"}},
+        {name: "bad flag value", args: []string{"--database.max-open-conns=many"}},
+        {name: "unknown flag", args: []string{"--server.adress=:1"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            args := tt.args
+            if tt.file != "" {
+                name, content, _ := strings.Cut(tt.file, "\n")
+                args = append(args, "--config", writeFile(t, name, content))
+            }
+            if _, _, err := load(args, env(tt.vars), io.Discard); err == nil {
+                t.Error("load() succeeded, want an error")
+            }
+        })
+    }
+}
+
+func TestValidate(t *testing.T) {
+    if err := Default().Validate(); err != nil {
+        t.Errorf("Default().Validate() = %v, want nil", err)
+    }
+
+    cfg := Default()
+    cfg.Server.Addr = "8080"
+    cfg.Database.Host = ""
+    cfg.Database.MaxOpenConns = 0
+    cfg.Auth.Algorithm = "none"
+    cfg.Purge.Interval = -time.Second
+    err := cfg.Validate()
+    if err == nil {
+        t.Fatal("Validate() = nil, want errors")
+    }
+    for _, want := range []string{"server: ", "database: host", "database: max_open_conns", "auth: ", "purge.interval must not be negative"} {
+        if !strings.Contains(err.Error(), want) {
+            t.Errorf("Validate() = %q, want it to mention %q", err, want)
+        }
+    }
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+    cfg := Default()
+    cfg.Database.Password = "db-password"
+    cfg.Auth.Secret = "jwt-secret"
+
+    var out bytes.Buffer
+    if err := cfg.Print(&out); err != nil {
+        t.Fatal(err)
+    }
+    printed := out.String()
+    for _, secret := range []string{"db-password", "jwt-secret"} {
+        if strings.Contains(printed, secret) {
+            t.Errorf("Print() leaked %q:\n%s", secret, printed)
+        }
+    }
+    if !strings.Contains(printed, "# DB_PASSWORD") {
+        t.Errorf("Print() has no environment variable comments:\n%s", printed)
+    }
+
+    // The output is a valid config file with the secrets replaced
+    path := writeFile(t, "printed.yaml", printed)
+    loaded, _, err := load([]string{"--config", path}, env(nil), io.Discard)
+    if err != nil {
+        t.Fatalf("loading printed config: %v", err)
+    }
+    want := cfg
+    want.Database.Password = "[REDACTED]"
+    want.Auth.Secret = "[REDACTED]"
+    if loaded != want {
+        t.Errorf("printed config loads as %+v, want %+v", loaded, want)
+    }
+}
+
+func TestPrintKeepsEmptySecretsEmpty(t *testing.T) {
+    var out bytes.Buffer
+    if err := Default().Print(&out); err != nil {
+        t.Fatal(err)
+    }
+    var printed struct {
+        Auth struct {
+            Secret string `yaml:"secret"`
+        } `yaml:"auth"`
+    }
+    if err := yaml.Unmarshal(out.Bytes(), &printed); err != nil {
+        t.Fatal(err)
+    }
+    if printed.Auth.Secret != "" {
+        t.Errorf("unset secret printed as %q, want empty", printed.Auth.Secret)
+    }
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+    "io"
+    "reflect"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Print writes c as a YAML configuration file with secrets redacted. Each
+// setting is commented with its environment variable.
+func (c Config) Print(w io.Writer) error {
+    root := &yaml.Node{Kind: yaml.MappingNode}
+    sections := make(map[string]*yaml.Node)
+    for _, s := range settings(&c) {
+        section, key, _ := strings.Cut(s.key, ".")
+        node, exists := sections[section]
+        if !exists {
+            node = &yaml.Node{Kind: yaml.MappingNode}
+            sections[section] = node
+            root.Content = append(root.Content, scalar("!!str", section), node)
+        }
+        value := scalar(yamlTag(s), s.String())
+        value.LineComment = s.env
+        node.Content = append(node.Content, scalar("!!str", key), value)
+    }
+
+    encoder := yaml.NewEncoder(w)
+    encoder.SetIndent(2)
+    if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+        return err
+    }
+    return encoder.Close()
+}
+
+func scalar(tag, value string) *yaml.Node {
+    return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// yamlTag keeps durations and redacted values strings when printed;
+// durations are int64, not int
+func yamlTag(s setting) string {
+    switch s.value.Kind() {
+    case reflect.Bool:
+        return "!!bool"
+    case reflect.Int:
+        return "!!int"
+    default:
+        return "!!str"
+    }
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Config selects and tunes the storage backend. The yaml, toml and env
+// tags name each setting in configuration files and the environment.
+type Config struct {
+    // Driver is one of the Driver* constants
+    Driver string `yaml:"driver" toml:"driver" env:"DB_DRIVER"`
+
+    // Connection settings for mysql and postgres. Port 0 selects the
+    // driver's default port.
+    Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+    Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+    User     string `yaml:"user" toml:"user" env:"DB_USER"`
+    Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+    Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+    // SSLMode is the postgres sslmode
+    SSLMode string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+
+    // SQLitePath is the sqlite database file, or ":memory:"
+    SQLitePath string `yaml:"sqlite_path" toml:"sqlite_path" env:"SQLITE_PATH"`
+    // SnapshotPath is where the memory backend persists its users; empty
+    // keeps nothing across restarts
+    SnapshotPath string `yaml:"memory_snapshot_path" toml:"memory_snapshot_path" env:"MEMORY_SNAPSHOT_PATH"`
+
+    QueryTimeout      time.Duration `yaml:"query_timeout" toml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+    MaxOpenConns      int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+    MaxIdleConns      int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+    ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+    ConnectAttempts   int           `yaml:"connect_attempts" toml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+    ConnectRetryDelay time.Duration `yaml:"connect_retry_delay" toml:"connect_retry_delay" env:"DB_CONNECT_RETRY_DELAY"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden.
+// There is no default password.
+func DefaultConfig() Config {
+    return Config{
+        Driver:            DriverMySQL,
+        Host:              "localhost",
+        User:              "apiuser",
+        Name:              "userdb",
+        SSLMode:           "disable",
+        SQLitePath:        DefaultSQLitePath,
+        QueryTimeout:      DefaultQueryTimeout,
+        MaxOpenConns:      DefaultMaxOpenConns,
+        MaxIdleConns:      DefaultMaxIdleConns,
+        ConnMaxLifetime:   DefaultConnMaxLifetime,
+        ConnectAttempts:   DefaultConnectAttempts,
+        ConnectRetryDelay: DefaultConnectRetryDelay,
+    }
+}
+
+// Validate reports every setting that is invalid for the selected driver
+func (c Config) Validate() error {
+    var errs []error
+    switch c.Driver {
+    case DriverMySQL, DriverPostgres:
+        if c.Host == "" {
+            errs = append(errs, errors.New("host is required"))
+        }
+        if c.User == "" {
+            errs = append(errs, errors.New("user is required"))
+        }
+        if c.Name == "" {
+            errs = append(errs, errors.New("name is required"))
+        }
+        if c.Port < 0 || c.Port > 65535 {
+            errs = append(errs, fmt.Errorf("port %d is out of range", c.Port))
+        }
+        if c.MaxOpenConns < 1 {
+            errs = append(errs, errors.New("max_open_conns must be at least 1"))
+        }
+        if c.MaxIdleConns < 0 || c.MaxIdleConns > c.MaxOpenConns {
+            errs = append(errs, errors.New("max_idle_conns must be between 0 and max_open_conns"))
+        }
+        if c.ConnectAttempts < 1 {
+            errs = append(errs, errors.New("connect_attempts must be at least 1"))
+        }
+    case DriverSQLite:
+        if c.SQLitePath == "" {
+            errs = append(errs, errors.New("sqlite_path is required"))
+        }
+    case DriverMemory:
+    default:
+        errs = append(errs, fmt.Errorf("unsupported driver %q (want %s, %s, %s or %s)",
+            c.Driver, DriverMySQL, DriverPostgres, DriverSQLite, DriverMemory))
+    }
+    return errors.Join(errs...)
+}
+
+// port returns c.Port, or defaultPort when it is 0
+func (c Config) port(defaultPort int) int {
+    if c.Port == 0 {
+        return defaultPort
+    }
+    return c.Port
+}
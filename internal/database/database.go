@@ -0,0 +1,170 @@
+package database
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "log"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Defaults for Config
+const (
+    // DefaultQueryTimeout bounds every repository query
+    DefaultQueryTimeout      = 5 * time.Second
+    DefaultMaxOpenConns      = 25
+    DefaultMaxIdleConns      = 5
+    DefaultConnMaxLifetime   = 5 * time.Minute
+    DefaultConnectAttempts   = 30
+    DefaultConnectRetryDelay = time.Second
+)
+
+// Supported values of Config.Driver
+const (
+    DriverMySQL    = "mysql"
+    DriverSQLite   = "sqlite"
+    DriverPostgres = "postgres"
+    // DriverMemory keeps users in process memory; it has no database
+    DriverMemory = "memory"
+)
+
+// DB is a connection pool for one of the supported drivers. Queries are
+// written with ? placeholders; ExecContext, QueryContext and
+// QueryRowContext rewrite them for drivers that number their parameters,
+// and trace each statement.
+type DB struct {
+    *sql.DB
+    // Driver is the Config.Driver the connection was opened with
+    Driver string
+    // QueryTimeout is applied on top of the caller's context to every query
+    QueryTimeout time.Duration
+}
+
+// WithQueryTimeout derives a context for a single query that is cancelled
+// when the parent is (e.g. the HTTP client disconnects) or after
+// QueryTimeout, whichever comes first
+func (db *DB) WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    if db.QueryTimeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+    return context.WithTimeout(ctx, db.QueryTimeout)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+    query = db.Rebind(query)
+    ctx, span := db.startQuery(ctx, query)
+    result, err := db.DB.ExecContext(ctx, query, args...)
+    endQuery(span, err)
+    return result, err
+}
+
+// QueryContext's span ends when the query returns, before the rows are read
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+    query = db.Rebind(query)
+    ctx, span := db.startQuery(ctx, query)
+    rows, err := db.DB.QueryContext(ctx, query, args...)
+    endQuery(span, err)
+    return rows, err
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+    query = db.Rebind(query)
+    ctx, span := db.startQuery(ctx, query)
+    row := db.DB.QueryRowContext(ctx, query, args...)
+    endQuery(span, row.Err())
+    return row
+}
+
+// Rebind rewrites the ? placeholders in query for the driver: Postgres
+// numbers them ($1, $2, ...), MySQL and SQLite take them as they are
+func (db *DB) Rebind(query string) string {
+    if db.Driver != DriverPostgres {
+        return query
+    }
+    return numberPlaceholders(query)
+}
+
+// numberPlaceholders replaces each ? outside quotes with $1, $2, ...
+func numberPlaceholders(query string) string {
+    var b strings.Builder
+    b.Grow(len(query) + 8)
+    n := 0
+    var quote byte
+    for i := 0; i < len(query); i++ {
+        c := query[i]
+        switch {
+        case quote != 0:
+            if c == quote {
+                quote = 0
+            }
+        case c == '\'' || c == '"':
+            quote = c
+        case c == '?':
+            n++
+            b.WriteString("$" + strconv.Itoa(n))
+            continue
+        }
+        b.WriteByte(c)
+    }
+    return b.String()
+}
+
+// Open connects to the database cfg.Driver selects
+func Open(cfg Config) (*DB, error) {
+    var db *DB
+    var err error
+    switch cfg.Driver {
+    case DriverMySQL:
+        db, err = NewMySQLConnection(cfg)
+    case DriverSQLite:
+        db, err = NewSQLiteConnection(cfg.SQLitePath)
+    case DriverPostgres:
+        db, err = NewPostgresConnection(cfg)
+    case DriverMemory:
+        return nil, fmt.Errorf("driver %q has no database to connect to", cfg.Driver)
+    default:
+        return nil, fmt.Errorf("unsupported driver %q", cfg.Driver)
+    }
+    if err != nil {
+        return nil, err
+    }
+    db.QueryTimeout = cfg.QueryTimeout
+    return db, nil
+}
+
+// connect opens dsn and pings it, retrying cfg.ConnectAttempts times to
+// handle container startup delays, and sizes the pool
+func connect(driverName, dsn string, cfg Config) (*sql.DB, error) {
+    var db *sql.DB
+    var err error
+
+    for i := 0; i < cfg.ConnectAttempts; i++ {
+        if i > 0 {
+            time.Sleep(cfg.ConnectRetryDelay)
+        }
+        db, err = sql.Open(driverName, dsn)
+        if err != nil {
+            log.Printf("Failed to open database: %v", err)
+            continue
+        }
+
+        err = db.Ping()
+        if err == nil {
+            break
+        }
+        db.Close()
+
+        log.Printf("Failed to ping database (attempt %d/%d): %v", i+1, cfg.ConnectAttempts, err)
+    }
+
+    if err != nil {
+        return nil, fmt.Errorf("failed to connect to database after %d attempts: %v", cfg.ConnectAttempts, err)
+    }
+
+    db.SetMaxOpenConns(cfg.MaxOpenConns)
+    db.SetMaxIdleConns(cfg.MaxIdleConns)
+    db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+    return db, nil
+}
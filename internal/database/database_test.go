@@ -0,0 +1,23 @@
+package database
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+    tests := []struct {
+        driver string
+        query  string
+        want   string
+    }{
+        {DriverMySQL, `SELECT * FROM users WHERE id = ? AND version = ?`, `SELECT * FROM users WHERE id = ? AND version = ?`},
+        {DriverSQLite, `SELECT * FROM users WHERE id = ?`, `SELECT * FROM users WHERE id = ?`},
+        {DriverPostgres, `SELECT * FROM users WHERE id = ? AND version = ?`, `SELECT * FROM users WHERE id = $1 AND version = $2`},
+        {DriverPostgres, `SELECT '?', "a?b" FROM t WHERE a LIKE ? ESCAPE '!'`, `SELECT '?', "a?b" FROM t WHERE a LIKE $1 ESCAPE '!'`},
+        {DriverPostgres, `SELECT 'it''s?' WHERE a = ?`, `SELECT 'it''s?' WHERE a = $1`},
+    }
+    for _, tt := range tests {
+        db := &DB{Driver: tt.driver}
+        if got := db.Rebind(tt.query); got != tt.want {
+            t.Errorf("Rebind(%q) for %s = %q, want %q", tt.query, tt.driver, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+    "log"
+    "net"
+    "net/url"
+    "strconv"
+
+    _ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// NewPostgresConnection connects to PostgreSQL; Port 0 selects 5432
+func NewPostgresConnection(cfg Config) (*DB, error) {
+    query := url.Values{}
+    query.Set("sslmode", cfg.SSLMode)
+    dsn := url.URL{
+        Scheme:   "postgres",
+        User:     url.UserPassword(cfg.User, cfg.Password),
+        Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.port(5432))),
+        Path:     "/" + cfg.Name,
+        RawQuery: query.Encode(),
+    }
+
+    db, err := connect("pgx", dsn.String(), cfg)
+    if err != nil {
+        return nil, err
+    }
+
+    log.Println("Successfully connected to PostgreSQL database")
+
+    return &DB{DB: db, Driver: DriverPostgres, QueryTimeout: cfg.QueryTimeout}, nil
+}
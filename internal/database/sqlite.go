@@ -0,0 +1,48 @@
+package database
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "net/url"
+
+    _ "modernc.org/sqlite"
+)
+
+// DefaultSQLitePath is the database file used unless configured otherwise
+const DefaultSQLitePath = "go-crud-api.db"
+
+// NewSQLiteConnection opens the SQLite database at path, creating the file
+// if needed. ":memory:" opens a private in-memory database. Queries are
+// bounded by DefaultQueryTimeout; Open applies the configured one.
+func NewSQLiteConnection(path string) (*DB, error) {
+    // Times are stored as text in a fixed, sortable format; foreign keys
+    // are off by default in SQLite; busy_timeout makes writers wait for
+    // one another instead of failing immediately
+    params := url.Values{}
+    params.Add("_pragma", "foreign_keys(1)")
+    params.Add("_pragma", "busy_timeout(5000)")
+    params.Set("_time_format", "sqlite")
+    if path != ":memory:" {
+        params.Add("_pragma", "journal_mode(WAL)")
+    }
+    dsn := "file:" + path + "?" + params.Encode()
+
+    db, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open database: %v", err)
+    }
+    if err := db.Ping(); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to open database %s: %v", path, err)
+    }
+
+    // SQLite allows a single writer, so one connection avoids busy errors
+    // between our own queries; it also keeps an in-memory database alive
+    // and shared
+    db.SetMaxOpenConns(1)
+
+    log.Printf("Successfully opened SQLite database %s", path)
+
+    return &DB{DB: db, Driver: DriverSQLite, QueryTimeout: DefaultQueryTimeout}, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+    "context"
+    "database/sql"
+    "strings"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("go-crud-api/internal/database")
+
+// startQuery starts a client span for query, named after its SQL verb
+// (SELECT, INSERT, ...). Statements use placeholders, so no values end
+// up in the span.
+func (db *DB) startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+    operation := "query"
+    if fields := strings.Fields(query); len(fields) > 0 {
+        operation = strings.ToUpper(fields[0])
+    }
+    return tracer.Start(ctx, operation,
+        trace.WithSpanKind(trace.SpanKindClient),
+        trace.WithAttributes(
+            db.system(),
+            semconv.DBOperation(operation),
+            semconv.DBStatement(query),
+        ))
+}
+
+// system is the OpenTelemetry db.system of the driver
+func (db *DB) system() attribute.KeyValue {
+    switch db.Driver {
+    case DriverPostgres:
+        return semconv.DBSystemPostgreSQL
+    case DriverSQLite:
+        return semconv.DBSystemSqlite
+    default:
+        return semconv.DBSystemMySQL
+    }
+}
+
+// endQuery ends span, failing it unless err is nil or sql.ErrNoRows
+func endQuery(span trace.Span, err error) {
+    if err != nil && err != sql.ErrNoRows {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "testing"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/codes"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySpans(t *testing.T) {
+    exporter := tracetest.NewInMemoryExporter()
+    previous := otel.GetTracerProvider()
+    otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+    t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+    db, err := NewSQLiteConnection(":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    ctx := context.Background()
+
+    if _, err := db.ExecContext(ctx, "CREATE TABLE t (id INTEGER)"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := db.ExecContext(ctx, "INSERT INTO t (id) VALUES (?)", 1); err != nil {
+        t.Fatal(err)
+    }
+    var id int
+    if err := db.QueryRowContext(ctx, "SELECT id FROM t WHERE id = ?", 2).Scan(&id); !errors.Is(err, sql.ErrNoRows) {
+        t.Fatalf("Scan() error = %v, want sql.ErrNoRows", err)
+    }
+    db.QueryContext(ctx, "SELECT missing FROM t")
+
+    spans := exporter.GetSpans()
+    want := []string{"CREATE", "INSERT", "SELECT", "SELECT"}
+    if len(spans) != len(want) {
+        t.Fatalf("Got %d spans, want %d", len(spans), len(want))
+    }
+    for i, span := range spans {
+        if span.Name != want[i] {
+            t.Errorf("Span %d = %q, want %q", i, span.Name, want[i])
+        }
+    }
+    for _, kv := range spans[1].Attributes {
+        if kv.Key == "db.statement" && kv.Value.AsString() != "INSERT INTO t (id) VALUES (?)" {
+            t.Errorf("db.statement = %q, want the statement without values", kv.Value.AsString())
+        }
+    }
+    if spans[2].Status.Code == codes.Error {
+        t.Error("No rows failed the span")
+    }
+    if spans[3].Status.Code != codes.Error {
+        t.Error("A failing query did not fail its span")
+    }
+}
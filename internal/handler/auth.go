@@ -0,0 +1,229 @@
+package handler
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "log"
+    "net/http"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/problem"
+    "go-crud-api/internal/repository"
+)
+
+type AuthHandler struct {
+    repo          repository.UserRepositoryInterface
+    refreshTokens repository.RefreshTokenRepositoryInterface
+    passwords     *password.Manager
+    tokens        *auth.TokenService
+    refreshTTL    time.Duration
+}
+
+func NewAuthHandler(repo repository.UserRepositoryInterface, refreshTokens repository.RefreshTokenRepositoryInterface, tokens *auth.TokenService, passwords *password.Manager) *AuthHandler {
+    return &AuthHandler{
+        repo:          repo,
+        refreshTokens: refreshTokens,
+        passwords:     passwords,
+        tokens:        tokens,
+        refreshTTL:    auth.DefaultRefreshTokenTTL,
+    }
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+    var req model.LoginRequest
+    if !decodeJSON(w, r, &req) {
+        return
+    }
+
+    user, err := h.repo.FindByEmail(r.Context(), model.NormalizeEmail(req.Email))
+    if errors.Is(err, repository.ErrNotFound) {
+        // Hash anyway so unknown emails take as long as wrong passwords
+        h.passwords.Hash(req.Password)
+        problem.Error(w, r, http.StatusUnauthorized, "Invalid email or password")
+        return
+    }
+    if err != nil {
+        writeRepositoryError(w, r, err, "Failed to log in")
+        return
+    }
+
+    rehashed, err := h.passwords.Verify(user.Password, req.Password)
+    if err != nil {
+        problem.Error(w, r, http.StatusUnauthorized, "Invalid email or password")
+        return
+    }
+
+    // The stored hash uses an old algorithm or parameters; upgrade it now
+    // that we know the plaintext. SetPassword keeps the version, so the
+    // user's ETags stay valid. Failure here must not block the login.
+    if rehashed != "" {
+        if err := h.repo.SetPassword(r.Context(), user.ID, rehashed); err != nil {
+            log.Printf("Failed to store rehashed password for user %s: %v", user.ID, err)
+        }
+    }
+
+    // Every login starts a new refresh token family
+    h.issueTokens(w, r, user, uuid.New().String())
+}
+
+// Refresh exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked; presenting a revoked
+// token again means it was stolen or replayed, so the whole family is
+// revoked and the session has to log in again.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+    var req model.RefreshRequest
+    if !decodeJSON(w, r, &req) {
+        return
+    }
+
+    current, err := h.refreshTokens.FindByHash(r.Context(), auth.HashRefreshToken(req.RefreshToken))
+    if err != nil {
+        writeRefreshError(w, r, err)
+        return
+    }
+    if current.Expired(time.Now()) {
+        problem.Error(w, r, http.StatusUnauthorized, "Invalid refresh token")
+        return
+    }
+
+    if current.Revoked() {
+        h.revokeFamily(r.Context(), current.FamilyID)
+        problem.Error(w, r, http.StatusUnauthorized, "Invalid refresh token")
+        return
+    }
+
+    user, err := h.repo.FindById(r.Context(), current.UserID)
+    if err != nil {
+        writeRefreshError(w, r, err)
+        return
+    }
+
+    next, err := h.newRefreshToken(user.ID, current.FamilyID)
+    if err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to issue token")
+        return
+    }
+
+    // The presented token is revoked before its successor is stored, so a
+    // failure in between never leaves a valid token the client was not
+    // given. Losing this race means another request rotated the same
+    // token concurrently, which is treated like reuse.
+    if err := h.refreshTokens.Revoke(r.Context(), current.ID, next.ID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            h.revokeFamily(r.Context(), current.FamilyID)
+        }
+        writeRefreshError(w, r, err)
+        return
+    }
+    if err := h.refreshTokens.Save(r.Context(), next.RefreshToken); err != nil {
+        writeRepositoryError(w, r, err, "Failed to issue token")
+        return
+    }
+
+    h.writeTokens(w, r, user, next)
+}
+
+// Logout revokes the session the refresh token belongs to
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+    var req model.RefreshRequest
+    if !decodeJSON(w, r, &req) {
+        return
+    }
+
+    // Unknown tokens are ignored so logout is idempotent
+    current, err := h.refreshTokens.FindByHash(r.Context(), auth.HashRefreshToken(req.RefreshToken))
+    if err == nil {
+        err = h.refreshTokens.RevokeFamily(r.Context(), current.FamilyID)
+    }
+    if err != nil && !errors.Is(err, repository.ErrNotFound) {
+        writeRepositoryError(w, r, err, "Failed to revoke refresh token")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRefreshError rejects a refresh whose token or user is gone with 401,
+// like any other invalid token, and maps everything else as usual
+func writeRefreshError(w http.ResponseWriter, r *http.Request, err error) {
+    if errors.Is(err, repository.ErrNotFound) {
+        problem.Error(w, r, http.StatusUnauthorized, "Invalid refresh token")
+        return
+    }
+    writeRepositoryError(w, r, err, "Failed to refresh token")
+}
+
+// issuedRefreshToken pairs a stored refresh token with its plaintext,
+// which is only ever sent to the client
+type issuedRefreshToken struct {
+    model.RefreshToken
+    plain string
+}
+
+// newRefreshToken generates a refresh token in the family without
+// storing it
+func (h *AuthHandler) newRefreshToken(userID, familyID string) (issuedRefreshToken, error) {
+    plain, hash, err := auth.NewRefreshToken()
+    if err != nil {
+        return issuedRefreshToken{}, err
+    }
+
+    now := time.Now()
+    token := model.RefreshToken{
+        ID:        uuid.New().String(),
+        UserID:    userID,
+        FamilyID:  familyID,
+        TokenHash: hash,
+        CreatedAt: now,
+        ExpiresAt: now.Add(h.refreshTTL),
+    }
+    return issuedRefreshToken{RefreshToken: token, plain: plain}, nil
+}
+
+func (h *AuthHandler) issueTokens(w http.ResponseWriter, r *http.Request, user model.User, familyID string) {
+    refresh, err := h.newRefreshToken(user.ID, familyID)
+    if err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to issue token")
+        return
+    }
+    if err := h.refreshTokens.Save(r.Context(), refresh.RefreshToken); err != nil {
+        writeRepositoryError(w, r, err, "Failed to issue token")
+        return
+    }
+    h.writeTokens(w, r, user, refresh)
+}
+
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, r *http.Request, user model.User, refresh issuedRefreshToken) {
+    token, _, err := h.tokens.Issue(user)
+    if err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to issue token")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    json.NewEncoder(w).Encode(model.TokenResponse{
+        AccessToken:  token,
+        TokenType:    "Bearer",
+        ExpiresIn:    int(h.tokens.TTL().Seconds()),
+        RefreshToken: refresh.plain,
+    })
+}
+
+func (h *AuthHandler) revokeFamily(ctx context.Context, familyID string) {
+    if err := h.refreshTokens.RevokeFamily(ctx, familyID); err != nil {
+        log.Printf("Failed to revoke refresh token family %s: %v", familyID, err)
+    }
+}
+
+func (h *AuthHandler) RegisterRoutes(r *mux.Router) {
+    r.HandleFunc("/auth/login", h.Login).Methods("POST")
+    r.HandleFunc("/auth/refresh", h.Refresh).Methods("POST")
+    r.HandleFunc("/auth/logout", h.Logout).Methods("POST")
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+    "context"
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/repository"
+)
+
+func setupAuthRouter(t *testing.T) (*mux.Router, *AuthHandler, *repository.MockUserRepository) {
+    t.Helper()
+
+    repo := repository.NewMockUserRepository()
+    tokens := auth.NewTokenService(auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef")), time.Minute)
+    handler := NewAuthHandler(repo, repository.NewMockRefreshTokenRepository(), tokens, password.NewDefaultManager())
+
+    hash, err := handler.passwords.Hash("secret123")
+    if err != nil {
+        t.Fatalf("Failed to hash password: %v", err)
+    }
+    repo.Save(context.Background(), model.User{ID: "login-123", Name: "Login User", Email: "login@example.com", Password: hash})
+
+    router := mux.NewRouter()
+    handler.RegisterRoutes(router)
+    return router, handler, repo
+}
+
+func TestLogin(t *testing.T) {
+    router, handler, _ := setupAuthRouter(t)
+
+    tests := []struct {
+        name         string
+        body         string
+        expectedCode int
+    }{
+        {
+            name:         "valid credentials",
+            body:         `{"email":"login@example.com","password":"secret123"}`,
+            expectedCode: http.StatusOK,
+        },
+        {
+            name:         "wrong password",
+            body:         `{"email":"login@example.com","password":"wrong"}`,
+            expectedCode: http.StatusUnauthorized,
+        },
+        {
+            name:         "unknown email",
+            body:         `{"email":"nobody@example.com","password":"secret123"}`,
+            expectedCode: http.StatusUnauthorized,
+        },
+        {
+            name:         "invalid JSON",
+            body:         `invalid json`,
+            expectedCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(tt.body))
+            w := httptest.NewRecorder()
+
+            router.ServeHTTP(w, req)
+
+            if w.Code != tt.expectedCode {
+                t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+            }
+
+            if w.Code == http.StatusOK {
+                var response model.TokenResponse
+                if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+                    t.Fatalf("Failed to decode response: %v", err)
+                }
+                if response.TokenType != "Bearer" || response.ExpiresIn != 60 || response.RefreshToken == "" {
+                    t.Errorf("Unexpected token response %+v", response)
+                }
+
+                identity, err := handler.tokens.Parse(response.AccessToken)
+                if err != nil {
+                    t.Fatalf("Issued token does not parse: %v", err)
+                }
+                if identity.UserID != "login-123" {
+                    t.Errorf("Expected subject login-123, got %s", identity.UserID)
+                }
+            }
+        })
+    }
+}
+
+func TestLoginRehashesLegacyPassword(t *testing.T) {
+    router, _, repo := setupAuthRouter(t)
+
+    legacy, _ := password.NewBcryptHasher(4).Hash("legacy123")
+    repo.Save(context.Background(), model.User{ID: "legacy-123", Email: "legacy@example.com", Password: legacy})
+
+    req := httptest.NewRequest("POST", "/auth/login",
+        bytes.NewBufferString(`{"email":"legacy@example.com","password":"legacy123"}`))
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    if w.Code != http.StatusOK {
+        t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+    }
+
+    stored, _ := repo.FindById(context.Background(), "legacy-123")
+    if !strings.HasPrefix(stored.Password, "$argon2id$") {
+        t.Errorf("Expected password to be rehashed with argon2id, got %s", stored.Password)
+    }
+    // The rehash must not invalidate the ETags the user's clients hold
+    if stored.Version != 1 {
+        t.Errorf("Expected the rehash to keep version 1, got %d", stored.Version)
+    }
+}
+
+
+func postTokens(t *testing.T, router *mux.Router, path, body string) (int, model.TokenResponse) {
+    t.Helper()
+
+    req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    var response model.TokenResponse
+    if w.Code == http.StatusOK {
+        if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+            t.Fatalf("Failed to decode response: %v", err)
+        }
+    }
+    return w.Code, response
+}
+
+func refreshBody(token string) string {
+    return `{"refresh_token":"` + token + `"}`
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+    router, _, _ := setupAuthRouter(t)
+
+    code, login := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+    if code != http.StatusOK {
+        t.Fatalf("Login failed with status %d", code)
+    }
+
+    code, refreshed := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken))
+    if code != http.StatusOK {
+        t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+    }
+    if refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+        t.Error("Expected a new refresh token")
+    }
+    if refreshed.AccessToken == "" {
+        t.Error("Expected a new access token")
+    }
+
+    code, _ = postTokens(t, router, "/auth/refresh", refreshBody(refreshed.RefreshToken))
+    if code != http.StatusOK {
+        t.Errorf("Rotated token should be usable, got status %d", code)
+    }
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+    router, _, _ := setupAuthRouter(t)
+
+    _, login := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+    _, rotated := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken))
+
+    // Replaying the first token is detected as reuse
+    code, _ := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken))
+    if code != http.StatusUnauthorized {
+        t.Fatalf("Expected reuse to be rejected with %d, got %d", http.StatusUnauthorized, code)
+    }
+
+    // ...which also kills the legitimately rotated token
+    code, _ = postTokens(t, router, "/auth/refresh", refreshBody(rotated.RefreshToken))
+    if code != http.StatusUnauthorized {
+        t.Errorf("Expected family to be revoked, got status %d", code)
+    }
+
+    // Other sessions are unaffected
+    _, other := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+    code, _ = postTokens(t, router, "/auth/refresh", refreshBody(other.RefreshToken))
+    if code != http.StatusOK {
+        t.Errorf("Expected unrelated session to keep working, got status %d", code)
+    }
+}
+
+// failingRevokeRepository fails Revoke with err and counts saved tokens
+type failingRevokeRepository struct {
+    repository.RefreshTokenRepositoryInterface
+    err   error
+    saved int
+}
+
+func (r *failingRevokeRepository) Save(ctx context.Context, token model.RefreshToken) error {
+    r.saved++
+    return r.RefreshTokenRepositoryInterface.Save(ctx, token)
+}
+
+func (r *failingRevokeRepository) Revoke(ctx context.Context, id string, replacedBy string) error {
+    return r.err
+}
+
+func TestRefreshRevokeFailureIssuesNothing(t *testing.T) {
+    router, handler, _ := setupAuthRouter(t)
+    _, login := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+
+    stored := handler.refreshTokens
+    failing := &failingRevokeRepository{RefreshTokenRepositoryInterface: stored, err: &repository.Error{Op: "revoke refresh token", Kind: repository.ErrUnavailable}}
+    handler.refreshTokens = failing
+
+    code, _ := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken))
+    if code != http.StatusServiceUnavailable {
+        t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, code)
+    }
+    if failing.saved != 0 {
+        t.Errorf("Expected no successor token to be stored, %d were", failing.saved)
+    }
+
+    // The presented token was not rotated, so the client can retry
+    handler.refreshTokens = stored
+    if code, _ := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken)); code != http.StatusOK {
+        t.Errorf("Expected the retry to succeed, got status %d", code)
+    }
+}
+
+func TestRefreshRejects(t *testing.T) {
+    router, handler, _ := setupAuthRouter(t)
+    handler.refreshTTL = -time.Minute
+
+    _, login := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+
+    tests := []struct {
+        name         string
+        body         string
+        expectedCode int
+    }{
+        {name: "expired token", body: refreshBody(login.RefreshToken), expectedCode: http.StatusUnauthorized},
+        {name: "unknown token", body: refreshBody("unknown"), expectedCode: http.StatusUnauthorized},
+        {name: "missing token", body: `{}`, expectedCode: http.StatusBadRequest},
+        {name: "invalid JSON", body: `invalid json`, expectedCode: http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if code, _ := postTokens(t, router, "/auth/refresh", tt.body); code != tt.expectedCode {
+                t.Errorf("Expected status %d, got %d", tt.expectedCode, code)
+            }
+        })
+    }
+}
+
+func TestLogout(t *testing.T) {
+    router, _, _ := setupAuthRouter(t)
+
+    _, login := postTokens(t, router, "/auth/login", `{"email":"login@example.com","password":"secret123"}`)
+    _, rotated := postTokens(t, router, "/auth/refresh", refreshBody(login.RefreshToken))
+
+    for _, token := range []string{rotated.RefreshToken, "unknown"} {
+        req := httptest.NewRequest("POST", "/auth/logout", bytes.NewBufferString(refreshBody(token)))
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        if w.Code != http.StatusNoContent {
+            t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+        }
+    }
+
+    code, _ := postTokens(t, router, "/auth/refresh", refreshBody(rotated.RefreshToken))
+    if code != http.StatusUnauthorized {
+        t.Errorf("Expected refresh after logout to fail, got status %d", code)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,137 @@
+package handler
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "reflect"
+    "strings"
+
+    "go-crud-api/internal/problem"
+    "go-crud-api/internal/validate"
+)
+
+// maxBodyBytes bounds JSON request bodies
+const maxBodyBytes = 1 << 20
+
+// decodeJSON decodes the request body into dst, rejecting unknown fields
+// and trailing data, and validates the result against dst's rules. On
+// failure it writes the problem response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+    return decodeAndValidate(w, r, http.MaxBytesReader(w, r.Body, maxBodyBytes), dst)
+}
+
+// decodeAndValidate is decodeJSON for a body that was already read
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, body io.Reader, dst interface{}) bool {
+    return decodeAndValidateFields(w, r, body, dst, func(string) bool { return true })
+}
+
+// decodePatched is decodeAndValidate for a patched document. Only the
+// top-level fields whose value differs from the document the patch was
+// applied to are validated, so a patch is not rejected for fields it left
+// alone, such as a name stored before the current rules existed.
+func decodePatched(w http.ResponseWriter, r *http.Request, original, patched []byte, dst interface{}) bool {
+    changed, err := changedFields(original, patched)
+    if err != nil {
+        writeDecodeError(w, r, err)
+        return false
+    }
+    return decodeAndValidateFields(w, r, bytes.NewReader(patched), dst, func(field string) bool {
+        return changed[field]
+    })
+}
+
+// decodeAndValidateFields decodes body into dst and validates it,
+// reporting only the errors of fields for which check returns true
+func decodeAndValidateFields(w http.ResponseWriter, r *http.Request, body io.Reader, dst interface{}, check func(field string) bool) bool {
+    _, span := tracer.Start(r.Context(), "decode request")
+    defer span.End()
+
+    dec := json.NewDecoder(body)
+    dec.DisallowUnknownFields()
+
+    err := dec.Decode(dst)
+    if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+        err = errors.New("trailing data after JSON value")
+    }
+    if err != nil {
+        writeDecodeError(w, r, err)
+        return false
+    }
+
+    if err := validate.Struct(dst); err != nil {
+        var verrs validate.Errors
+        if !errors.As(err, &verrs) {
+            problem.Error(w, r, http.StatusInternalServerError, "Failed to validate request")
+            return false
+        }
+        var fieldErrs []problem.FieldError
+        for _, fe := range verrs {
+            if check(topLevelField(fe.Field)) {
+                fieldErrs = append(fieldErrs, problem.FieldError{Field: fe.Field, Message: fe.Message})
+            }
+        }
+        if len(fieldErrs) > 0 {
+            problem.Write(w, r, problem.Validation(fieldErrs...))
+            return false
+        }
+    }
+    return true
+}
+
+// changedFields returns the top-level fields of two JSON objects whose
+// values differ, including fields present in only one of them
+func changedFields(original, patched []byte) (map[string]bool, error) {
+    var before, after map[string]interface{}
+    if err := json.Unmarshal(original, &before); err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(patched, &after); err != nil {
+        return nil, err
+    }
+
+    changed := make(map[string]bool)
+    for field, value := range after {
+        if old, ok := before[field]; !ok || !reflect.DeepEqual(old, value) {
+            changed[field] = true
+        }
+    }
+    for field := range before {
+        if _, ok := after[field]; !ok {
+            changed[field] = true
+        }
+    }
+    return changed, nil
+}
+
+// topLevelField returns the first element of a JSON path such as
+// "addresses[1].city"
+func topLevelField(path string) string {
+    if i := strings.IndexAny(path, ".["); i >= 0 {
+        return path[:i]
+    }
+    return path
+}
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+    var typeErr *json.UnmarshalTypeError
+    var maxBytesErr *http.MaxBytesError
+
+    switch {
+    case errors.As(err, &typeErr) && typeErr.Field != "":
+        problem.Write(w, r, problem.Validation(problem.FieldError{
+            Field:   typeErr.Field,
+            Message: "must be a " + typeErr.Type.String(),
+        }))
+    case strings.HasPrefix(err.Error(), "json: unknown field "):
+        // encoding/json has no typed error for unknown fields
+        field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+        problem.Write(w, r, problem.Validation(problem.FieldError{Field: field, Message: "is not a known field"}))
+    case errors.As(err, &maxBytesErr):
+        problem.Error(w, r, http.StatusRequestEntityTooLarge, "Request body too large")
+    default:
+        problem.Error(w, r, http.StatusBadRequest, "Invalid request body")
+    }
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+    "context"
+    "errors"
+    "log"
+    "net/http"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/patch"
+    "go-crud-api/internal/problem"
+    "go-crud-api/internal/repository"
+)
+
+// writeLookupError maps the error of a repository call that addresses one
+// user, responding 404 with the notFound detail when it does not exist
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error, notFound, fallback string) {
+    if errors.Is(err, repository.ErrNotFound) {
+        problem.Error(w, r, http.StatusNotFound, notFound)
+        return
+    }
+    writeRepositoryError(w, r, err, fallback)
+}
+
+// writeRepositoryError maps a repository error onto a problem response.
+// fallback is the detail for errors the repository could not classify,
+// including ErrNotFound, which callers that can expect it handle first.
+func writeRepositoryError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+    switch {
+    case errors.Is(err, repository.ErrConflict):
+        p := problem.Typed(problem.TypeConflict, "Conflict", http.StatusConflict,
+            "Conflicts with an existing resource")
+        if field := repository.ConflictField(err); field != "" {
+            p.Detail = "A user with this " + field + " already exists"
+            p.Errors = []problem.FieldError{{Field: field, Message: "is already taken"}}
+        }
+        problem.Write(w, r, p)
+    case errors.Is(err, repository.ErrStale):
+        // The precondition held when checked, but another write won the race
+        writePreconditionFailed(w, r, model.User{})
+    case errors.Is(err, repository.ErrUnavailable), errors.Is(err, context.Canceled):
+        w.Header().Set("Retry-After", "1")
+        problem.Write(w, r, problem.Typed(problem.TypeUnavailable, "Service unavailable", http.StatusServiceUnavailable,
+            "Service temporarily unavailable, retry later"))
+    default:
+        log.Printf("Unexpected repository error: %v", err)
+        problem.Error(w, r, http.StatusInternalServerError, fallback)
+    }
+}
+
+// writePatchError maps a failure to apply a PATCH body onto a problem
+// response, following RFC 5789 section 2.2
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+    switch {
+    case errors.Is(err, patch.ErrUnsupportedMediaType):
+        w.Header().Set("Accept-Patch", patch.MediaTypeMergePatch+", "+patch.MediaTypeJSONPatch)
+        problem.Error(w, r, http.StatusUnsupportedMediaType,
+            "Content-Type must be "+patch.MediaTypeMergePatch+" or "+patch.MediaTypeJSONPatch)
+    case errors.Is(err, patch.ErrInvalidPatch):
+        problem.Error(w, r, http.StatusBadRequest, err.Error())
+    case errors.Is(err, patch.ErrTestFailed):
+        problem.Error(w, r, http.StatusConflict, err.Error())
+    case errors.Is(err, patch.ErrUnprocessable):
+        problem.Error(w, r, http.StatusUnprocessableEntity, err.Error())
+    default:
+        log.Printf("Failed to apply patch: %v", err)
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to update user")
+    }
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+    "net/http"
+    "strconv"
+    "strings"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/problem"
+)
+
+// userETag is the strong entity tag of a user: its quoted version
+func userETag(u model.User) string {
+    return `"` + strconv.FormatInt(u.Version, 10) + `"`
+}
+
+// etagMatches reports whether header, an If-Match or If-None-Match list,
+// contains etag or "*". Weak tags only match when weak is set, as
+// If-None-Match uses weak and If-Match strong comparison (RFC 9110
+// section 8.8.3.2).
+func etagMatches(header, etag string, weak bool) bool {
+    for _, candidate := range strings.Split(header, ",") {
+        candidate = strings.TrimSpace(candidate)
+        if candidate == "*" {
+            return true
+        }
+        if strings.HasPrefix(candidate, "W/") {
+            if !weak {
+                continue
+            }
+            candidate = strings.TrimPrefix(candidate, "W/")
+        }
+        if candidate == etag {
+            return true
+        }
+    }
+    return false
+}
+
+// checkIfMatch enforces the If-Match precondition that PUT, PATCH and
+// DELETE require against the current user, writing 428 when it is missing
+// and 412 when it does not match
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current model.User) bool {
+    ifMatch := r.Header.Get("If-Match")
+    if ifMatch == "" {
+        problem.Error(w, r, http.StatusPreconditionRequired,
+            "If-Match with the user's current ETag is required")
+        return false
+    }
+    if !etagMatches(ifMatch, userETag(current), false) {
+        writePreconditionFailed(w, r, current)
+        return false
+    }
+    return true
+}
+
+// writePreconditionFailed answers 412 with the current ETag, so the client
+// can refetch and retry
+func writePreconditionFailed(w http.ResponseWriter, r *http.Request, current model.User) {
+    if current.ID != "" {
+        w.Header().Set("ETag", userETag(current))
+    }
+    problem.Error(w, r, http.StatusPreconditionFailed,
+        "The user was modified since it was read; fetch it again and retry")
+}
+
+// notModified answers 304 when If-None-Match matches the user's ETag
+func notModified(w http.ResponseWriter, r *http.Request, u model.User) bool {
+    ifNoneMatch := r.Header.Get("If-None-Match")
+    if ifNoneMatch == "" || !etagMatches(ifNoneMatch, userETag(u), true) {
+        return false
+    }
+    w.Header().Set("ETag", userETag(u))
+    w.WriteHeader(http.StatusNotModified)
+    return true
+}
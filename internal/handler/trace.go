@@ -0,0 +1,27 @@
+package handler
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("go-crud-api/internal/handler")
+
+// startSpan starts a span for a handler method as a child of the request's
+// span, and returns the request carrying it so the repository calls
+// become its children
+func startSpan(r *http.Request, name string) (*http.Request, trace.Span) {
+    ctx, span := tracer.Start(r.Context(), name)
+    return r.WithContext(ctx), span
+}
+
+// encodeJSON writes v as the response body in a span of its own, so slow
+// encoding can be told apart from slow queries
+func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+    _, span := tracer.Start(r.Context(), "encode response")
+    defer span.End()
+    json.NewEncoder(w).Encode(v)
+}
@@ -2,99 +2,514 @@ package handler
 
 import (
     "encoding/json"
+    "errors"
+    "io"
     "net/http"
+    "strconv"
+    "time"
     "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
     "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/patch"
+    "go-crud-api/internal/problem"
     "go-crud-api/internal/repository"
     "github.com/google/uuid"
 )
 
 type UserHandler struct {
-    repo repository.UserRepositoryInterface
+    repo      repository.UserRepositoryInterface
+    passwords *password.Manager
 }
 
-func NewUserHandler(repo repository.UserRepositoryInterface) *UserHandler {
-    return &UserHandler{repo: repo}
+func NewUserHandler(repo repository.UserRepositoryInterface, passwords *password.Manager) *UserHandler {
+    return &UserHandler{
+        repo:      repo,
+        passwords: passwords,
+    }
+}
+
+// hashPassword replaces the plaintext password on user with its hash
+func (h *UserHandler) hashPassword(user *model.User) error {
+    hash, err := h.passwords.Hash(user.Password)
+    if err != nil {
+        return err
+    }
+    user.Password = hash
+    return nil
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-    var user model.User
-    if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+    r, span := startSpan(r, "UserHandler.CreateUser")
+    defer span.End()
+
+    var req model.CreateUserRequest
+    if !decodeJSON(w, r, &req) {
         return
     }
     
+    user := req.ToUser()
     user.ID = uuid.New().String()
-    if err := h.repo.Save(user); err != nil {
-        http.Error(w, "Failed to create user", http.StatusInternalServerError)
+    user.CreatedAt = time.Now().UTC()
+    user.Version = 1
+    if err := h.hashPassword(&user); err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to create user")
+        return
+    }
+    if err := h.repo.Save(r.Context(), user); err != nil {
+        writeRepositoryError(w, r, err, "Failed to create user")
         return
     }
     
     w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("ETag", userETag(user))
     w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(user)
+    encodeJSON(w, r, model.NewUserResponse(user))
 }
 
+// GetAllUsers lists users one page at a time. Query parameters:
+//
+//   limit          page size (default 20, max 100)
+//   cursor         opaque cursor from a previous page's next link
+//   sort           comma separated fields, "-" prefix for descending
+//   name, email    case-insensitive prefix filters
+//   include_total  set to true to receive X-Total-Count
+//
+// The body stays a plain JSON array; the next page is advertised in a
+// Link header with rel="next".
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-    users, err := h.repo.GetAll()
+    r, span := startSpan(r, "UserHandler.GetAllUsers")
+    defer span.End()
+
+    opts, fieldErr := parseListOptions(r)
+    if fieldErr != nil {
+        problem.Write(w, r, problem.Validation(*fieldErr))
+        return
+    }
+    if opts.IncludeDeleted && !canReadDeleted(w, r) {
+        return
+    }
+    
+    page, err := h.repo.List(r.Context(), opts)
+    if errors.Is(err, repository.ErrInvalidCursor) {
+        problem.Write(w, r, problem.Validation(problem.FieldError{Field: "cursor", Message: "invalid or does not match sort"}))
+        return
+    }
     if err != nil {
-        http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+        writeRepositoryError(w, r, err, "Failed to fetch users")
         return
     }
     
+    if page.NextCursor != "" {
+        next := *r.URL
+        query := next.Query()
+        query.Set("cursor", page.NextCursor)
+        next.RawQuery = query.Encode()
+        w.Header().Set("Link", "<"+next.RequestURI()+">; rel=\"next\"")
+    }
+    if page.Total != nil {
+        w.Header().Set("X-Total-Count", strconv.Itoa(*page.Total))
+    }
+    
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(users)
+    encodeJSON(w, r, model.NewUserResponses(page.Users))
+}
+
+// SearchUsers finds users whose name or email contain words starting with
+// every term of q, ordered by relevance
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.SearchUsers")
+    defer span.End()
+
+    query := r.URL.Query()
+    opts := repository.SearchOptions{Query: query.Get("q")}
+    
+    if limit := query.Get("limit"); limit != "" {
+        n, err := strconv.Atoi(limit)
+        if err != nil || n < 1 || n > repository.MaxPageSize {
+            problem.Write(w, r, problem.Validation(limitError()))
+            return
+        }
+        opts.Limit = n
+    }
+    
+    results, err := h.repo.Search(r.Context(), opts)
+    if errors.Is(err, repository.ErrEmptySearch) {
+        problem.Write(w, r, problem.Validation(problem.FieldError{Field: "q", Message: "must contain at least one search term"}))
+        return
+    }
+    if err != nil {
+        writeRepositoryError(w, r, err, "Failed to search users")
+        return
+    }
+    
+    response := make([]model.UserSearchResult, 0, len(results))
+    for _, result := range results {
+        response = append(response, model.UserSearchResult{
+            UserResponse: model.NewUserResponse(result.User),
+            Score:        result.Score,
+            Highlights:   result.Highlights,
+        })
+    }
+    
+    w.Header().Set("Content-Type", "application/json")
+    encodeJSON(w, r, response)
+}
+
+func limitError() problem.FieldError {
+    return problem.FieldError{
+        Field:   "limit",
+        Message: "must be between 1 and " + strconv.Itoa(repository.MaxPageSize),
+    }
+}
+
+// parseListOptions reads the GetAllUsers query parameters, reporting the
+// first invalid one
+func parseListOptions(r *http.Request) (repository.ListOptions, *problem.FieldError) {
+    query := r.URL.Query()
+    opts := repository.ListOptions{
+        Cursor: query.Get("cursor"),
+        Filter: repository.UserFilter{
+            NamePrefix:  query.Get("name"),
+            EmailPrefix: query.Get("email"),
+        },
+    }
+    
+    if limit := query.Get("limit"); limit != "" {
+        n, err := strconv.Atoi(limit)
+        if err != nil || n < 1 || n > repository.MaxPageSize {
+            fieldErr := limitError()
+            return opts, &fieldErr
+        }
+        opts.Limit = n
+    }
+    
+    sort, err := repository.ParseSort(query.Get("sort"))
+    if err != nil {
+        return opts, &problem.FieldError{Field: "sort", Message: err.Error()}
+    }
+    opts.Sort = sort
+    
+    if total := query.Get("include_total"); total != "" {
+        include, err := strconv.ParseBool(total)
+        if err != nil {
+            return opts, &problem.FieldError{Field: "include_total", Message: "must be true or false"}
+        }
+        opts.IncludeTotal = include
+    }
+    
+    include, fieldErr := parseIncludeDeleted(r)
+    if fieldErr != nil {
+        return opts, fieldErr
+    }
+    opts.IncludeDeleted = include
+    
+    return opts, nil
+}
+
+// parseIncludeDeleted reads the include_deleted query parameter
+func parseIncludeDeleted(r *http.Request) (bool, *problem.FieldError) {
+    value := r.URL.Query().Get("include_deleted")
+    if value == "" {
+        return false, nil
+    }
+    include, err := strconv.ParseBool(value)
+    if err != nil {
+        return false, &problem.FieldError{Field: "include_deleted", Message: "must be true or false"}
+    }
+    return include, nil
+}
+
+// canReadDeleted rejects requests for deleted users by callers not allowed
+// to see them, writing the 403 response
+func canReadDeleted(w http.ResponseWriter, r *http.Request) bool {
+    identity, _ := auth.IdentityFromContext(r.Context())
+    if !identity.Can(auth.PermReadDeletedUsers) {
+        problem.Error(w, r, http.StatusForbidden, "Not allowed to read deleted users")
+        return false
+    }
+    return true
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.GetUser")
+    defer span.End()
+
     vars := mux.Vars(r)
-    id := vars["id"]
+    h.getUser(w, r, vars["id"])
+}
+
+// GetMe returns the authenticated caller's own record
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.GetMe")
+    defer span.End()
+
+    identity, ok := auth.IdentityFromContext(r.Context())
+    if !ok {
+        problem.Error(w, r, http.StatusUnauthorized, "Authentication required")
+        return
+    }
+    h.getUser(w, r, identity.UserID)
+}
+
+// getUser responds with the user; with include_deleted=true it also
+// finds soft-deleted users
+func (h *UserHandler) getUser(w http.ResponseWriter, r *http.Request, id string) {
+    include, fieldErr := parseIncludeDeleted(r)
+    if fieldErr != nil {
+        problem.Write(w, r, problem.Validation(*fieldErr))
+        return
+    }
+    if include && !canReadDeleted(w, r) {
+        return
+    }
     
-    user, exists := h.repo.FindById(id)
-    if !exists {
-        http.Error(w, "User not found", http.StatusNotFound)
+    find := h.repo.FindById
+    if include {
+        find = h.repo.FindByIdIncludingDeleted
+    }
+    user, err := find(r.Context(), id)
+    if err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to fetch user")
+        return
+    }
+    if notModified(w, r, user) {
         return
     }
     
+    writeUser(w, r, user)
+}
+
+// writeUser responds with a single user and its ETag
+func writeUser(w http.ResponseWriter, r *http.Request, user model.User) {
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(user)
+    w.Header().Set("ETag", userETag(user))
+    encodeJSON(w, r, model.NewUserResponse(user))
 }
 
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.UpdateUser")
+    defer span.End()
+
     vars := mux.Vars(r)
-    id := vars["id"]
+    h.updateUser(w, r, vars["id"])
+}
+
+// UpdateMe replaces the authenticated caller's own record
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.UpdateMe")
+    defer span.End()
+
+    identity, ok := auth.IdentityFromContext(r.Context())
+    if !ok {
+        problem.Error(w, r, http.StatusUnauthorized, "Authentication required")
+        return
+    }
+    h.updateUser(w, r, identity.UserID)
+}
+
+func (h *UserHandler) updateUser(w http.ResponseWriter, r *http.Request, id string) {
+    var req model.UpdateUserRequest
+    if !decodeJSON(w, r, &req) {
+        return
+    }
     
-    var user model.User
-    if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+    existing, err := h.repo.FindById(r.Context(), id)
+    if err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to update user")
+        return
+    }
+    if !checkIfMatch(w, r, existing) {
         return
     }
     
-    user.ID = id
-    if !h.repo.Update(user) {
-        http.Error(w, "User not found", http.StatusNotFound)
+    user := req.ToUser(id)
+    user.CreatedAt = existing.CreatedAt
+    user.Version = existing.Version
+    if user.Role == "" {
+        user.Role = existing.Role
+    } else if user.Role != existing.Role && !canChangeRole(w, r) {
         return
     }
     
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(user)
+    if user.Password == "" {
+        user.Password = existing.Password
+    } else if err := h.hashPassword(&user); err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to update user")
+        return
+    }
+    if err := h.repo.Update(r.Context(), user); err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to update user")
+        return
+    }
+    
+    user.Version++
+    writeUser(w, r, user)
+}
+
+// canChangeRole rejects role changes by callers not allowed to manage
+// roles, writing the 403 response
+func canChangeRole(w http.ResponseWriter, r *http.Request) bool {
+    identity, _ := auth.IdentityFromContext(r.Context())
+    if !identity.Can(auth.PermManageRoles) {
+        problem.Error(w, r, http.StatusForbidden, "Not allowed to change roles")
+        return false
+    }
+    return true
+}
+
+// PatchUser applies a JSON Merge Patch (application/merge-patch+json) or
+// a JSON Patch (application/json-patch+json) to a user. The fields the
+// patch changed are validated like a PUT body, and only they are written.
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.PatchUser")
+    defer span.End()
+
+    vars := mux.Vars(r)
+    h.patchUser(w, r, vars["id"])
+}
+
+// PatchMe patches the authenticated caller's own record
+func (h *UserHandler) PatchMe(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.PatchMe")
+    defer span.End()
+
+    identity, ok := auth.IdentityFromContext(r.Context())
+    if !ok {
+        problem.Error(w, r, http.StatusUnauthorized, "Authentication required")
+        return
+    }
+    h.patchUser(w, r, identity.UserID)
+}
+
+func (h *UserHandler) patchUser(w http.ResponseWriter, r *http.Request, id string) {
+    body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+    if err != nil {
+        writeDecodeError(w, r, err)
+        return
+    }
+    
+    existing, err := h.repo.FindById(r.Context(), id)
+    if err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to update user")
+        return
+    }
+    if !checkIfMatch(w, r, existing) {
+        return
+    }
+    
+    doc, err := json.Marshal(model.NewPatchDocument(existing))
+    if err != nil {
+        problem.Error(w, r, http.StatusInternalServerError, "Failed to update user")
+        return
+    }
+    patched, err := patch.Apply(r.Header.Get("Content-Type"), doc, body)
+    if err != nil {
+        writePatchError(w, r, err)
+        return
+    }
+    
+    var req model.UpdateUserRequest
+    if !decodePatched(w, r, doc, patched, &req) {
+        return
+    }
+    
+    changes, ok := h.userChanges(w, r, existing, req.ToUser(id))
+    if !ok {
+        return
+    }
+    changes.Version = existing.Version
+    if err := h.repo.Patch(r.Context(), id, changes); err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to update user")
+        return
+    }
+    
+    writeUser(w, r, changes.Apply(existing))
+}
+
+// userChanges returns the fields of patched that differ from existing,
+// with a new password hashed. An empty role or password keeps the
+// current one, as in a PUT.
+func (h *UserHandler) userChanges(w http.ResponseWriter, r *http.Request, existing, patched model.User) (repository.UserChanges, bool) {
+    var changes repository.UserChanges
+    if patched.Name != existing.Name {
+        changes.Name = &patched.Name
+    }
+    if patched.Email != existing.Email {
+        changes.Email = &patched.Email
+    }
+    if patched.Role != "" && patched.Role != existing.Role {
+        if !canChangeRole(w, r) {
+            return changes, false
+        }
+        changes.Role = &patched.Role
+    }
+    if patched.Password != "" {
+        if err := h.hashPassword(&patched); err != nil {
+            problem.Error(w, r, http.StatusInternalServerError, "Failed to update user")
+            return changes, false
+        }
+        changes.Password = &patched.Password
+    }
+    return changes, true
 }
 
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.DeleteUser")
+    defer span.End()
+
     vars := mux.Vars(r)
     id := vars["id"]
     
-    if !h.repo.Delete(id) {
-        http.Error(w, "User not found", http.StatusNotFound)
+    existing, err := h.repo.FindById(r.Context(), id)
+    if err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to delete user")
+        return
+    }
+    if !checkIfMatch(w, r, existing) {
+        return
+    }
+    
+    if err := h.repo.Delete(r.Context(), id, existing.Version); err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to delete user")
         return
     }
     
     w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreUser undeletes a soft-deleted user and responds with it
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+    r, span := startSpan(r, "UserHandler.RestoreUser")
+    defer span.End()
+
+    vars := mux.Vars(r)
+    id := vars["id"]
+    
+    if err := h.repo.Restore(r.Context(), id); err != nil {
+        writeLookupError(w, r, err, "Deleted user not found", "Failed to restore user")
+        return
+    }
+    
+    user, err := h.repo.FindById(r.Context(), id)
+    if err != nil {
+        writeLookupError(w, r, err, "User not found", "Failed to fetch user")
+        return
+    }
+    
+    writeUser(w, r, user)
+}
+
 func (h *UserHandler) RegisterRoutes(r *mux.Router) {
+    r.HandleFunc("/users", h.GetAllUsers).Methods("GET")
     r.HandleFunc("/users", h.CreateUser).Methods("POST")
+    r.HandleFunc("/users/search", h.SearchUsers).Methods("GET")
+    r.HandleFunc("/users/me", h.GetMe).Methods("GET")
+    r.HandleFunc("/users/me", h.UpdateMe).Methods("PUT")
+    r.HandleFunc("/users/me", h.PatchMe).Methods("PATCH")
     r.HandleFunc("/users/{id}", h.GetUser).Methods("GET")
     r.HandleFunc("/users/{id}", h.UpdateUser).Methods("PUT")
+    r.HandleFunc("/users/{id}", h.PatchUser).Methods("PATCH")
     r.HandleFunc("/users/{id}", h.DeleteUser).Methods("DELETE")
+    r.HandleFunc("/users/{id}/restore", h.RestoreUser).Methods("POST")
 }
\ No newline at end of file
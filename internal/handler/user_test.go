@@ -1,20 +1,29 @@
 package handler
 
 import (
+    "context"
+    "errors"
     "bytes"
     "encoding/json"
+    "fmt"
     "net/http"
     "net/http/httptest"
+    "regexp"
+    "strings"
     "testing"
+    "time"
     
     "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
     "go-crud-api/internal/model"
+    "go-crud-api/internal/password"
+    "go-crud-api/internal/problem"
     "go-crud-api/internal/repository"
 )
 
 func setupTestRouter() (*mux.Router, *UserHandler) {
     repo := repository.NewMockUserRepository()
-    handler := NewUserHandler(repo)
+    handler := NewUserHandler(repo, password.NewDefaultManager())
     router := mux.NewRouter()
     handler.RegisterRoutes(router)
     return router, handler
@@ -48,8 +57,8 @@ func TestCreateUser(t *testing.T) {
         {
             name:         "empty payload",
             payload:      map[string]string{},
-            expectedCode: http.StatusCreated,
-            checkBody:    true,
+            expectedCode: http.StatusBadRequest,
+            checkBody:    false,
         },
     }
     
@@ -78,7 +87,7 @@ func TestCreateUser(t *testing.T) {
             }
             
             if tt.checkBody && w.Code == http.StatusCreated {
-                var response model.User
+                var response model.UserResponse
                 if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
                     t.Fatalf("Failed to decode response: %v", err)
                 }
@@ -100,6 +109,260 @@ func TestCreateUser(t *testing.T) {
     }
 }
 
+func TestCreateUserHashesPassword(t *testing.T) {
+    router, handler := setupTestRouter()
+
+    body, _ := json.Marshal(map[string]string{
+        "name":     "Hash Me",
+        "email":    "hash@example.com",
+        "password": "secret123",
+    })
+    req := httptest.NewRequest("POST", "/users", bytes.NewBuffer(body))
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    if w.Code != http.StatusCreated {
+        t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+    }
+
+    var response model.UserResponse
+    json.NewDecoder(w.Body).Decode(&response)
+
+    stored, err := handler.repo.FindById(context.Background(), response.ID)
+    if err != nil {
+        t.Fatal("Created user not found in repository")
+    }
+    if stored.Password == "secret123" {
+        t.Fatal("Password was stored in plaintext")
+    }
+    if _, err := handler.passwords.Verify(stored.Password, "secret123"); err != nil {
+        t.Errorf("Stored hash does not verify: %v", err)
+    }
+}
+
+func TestCreateUserValidation(t *testing.T) {
+    router, _ := setupTestRouter()
+    
+    tests := []struct {
+        name       string
+        body       string
+        wantFields []string
+    }{
+        {
+            name:       "all invalid fields at once",
+            body:       `{"name":"","email":"nope","password":"short"}`,
+            wantFields: []string{"name", "email", "password"},
+        },
+        {
+            name:       "disallowed characters",
+            body:       `{"name":"<script>","email":"a@example.com","password":"secret123"}`,
+            wantFields: []string{"name"},
+        },
+        {
+            name:       "unknown field",
+            body:       `{"name":"Ann","email":"a@example.com","password":"secret123","role":"admin"}`,
+            wantFields: []string{"role"},
+        },
+        {
+            name:       "wrong type",
+            body:       `{"name":42,"email":"a@example.com","password":"secret123"}`,
+            wantFields: []string{"name"},
+        },
+    }
+    
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(tt.body))
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, req)
+            
+            if w.Code != http.StatusBadRequest {
+                t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+            }
+            var body problem.Problem
+            json.NewDecoder(w.Body).Decode(&body)
+            var fields []string
+            for _, fe := range body.Errors {
+                fields = append(fields, fe.Field)
+            }
+            if fmt.Sprint(fields) != fmt.Sprint(tt.wantFields) {
+                t.Errorf("Expected errors for %v, got %+v", tt.wantFields, body.Errors)
+            }
+        })
+    }
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+    router, _ := setupTestRouter()
+    
+    create := func(email string) *httptest.ResponseRecorder {
+        body := `{"name":"Ann","email":"` + email + `","password":"secret123"}`
+        req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(body))
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        return w
+    }
+    
+    if w := create("Ann@Example.com"); w.Code != http.StatusCreated {
+        t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+    } else {
+        var created model.UserResponse
+        json.NewDecoder(w.Body).Decode(&created)
+        if created.Email != "ann@example.com" {
+            t.Errorf("Expected the email to be stored lower case, got %q", created.Email)
+        }
+    }
+    
+    w := create("ann@example.COM")
+    if w.Code != http.StatusConflict {
+        t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+    }
+    var body problem.Problem
+    json.NewDecoder(w.Body).Decode(&body)
+    if body.Type != problem.TypeConflict || len(body.Errors) != 1 || body.Errors[0].Field != "email" {
+        t.Errorf("Expected a conflict naming email, got %+v", body)
+    }
+}
+
+func TestUpdateUserKeepsPasswordWhenOmitted(t *testing.T) {
+    router, handler := setupTestRouter()
+    hash, _ := handler.passwords.Hash("original123")
+    handler.repo.Save(context.Background(), model.User{ID: "keep-123", Name: "Keep", Email: "keep@example.com", Password: hash, Role: model.RoleUser})
+    
+    req := httptest.NewRequest("PUT", "/users/keep-123", bytes.NewBufferString(`{"name":"Kept","email":"keep@example.com"}`))
+    req.Header.Set("If-Match", `"1"`)
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    
+    if w.Code != http.StatusOK {
+        t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+    }
+    stored, _ := handler.repo.FindById(context.Background(), "keep-123")
+    if _, err := handler.passwords.Verify(stored.Password, "original123"); err != nil {
+        t.Errorf("Password changed by an update without one: %v", err)
+    }
+}
+
+func TestGetAllUsersPagination(t *testing.T) {
+    router, handler := setupTestRouter()
+    base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    for i := 0; i < 5; i++ {
+        handler.repo.Save(context.Background(), model.User{
+            ID:        fmt.Sprintf("page-%d", i),
+            Name:      fmt.Sprintf("User %d", i),
+            Email:     fmt.Sprintf("user%d@example.com", i),
+            CreatedAt: base.Add(time.Duration(i) * time.Minute),
+        })
+    }
+    
+    nextLink := regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+    
+    var ids []string
+    path := "/users?limit=2&sort=-created_at&include_total=true"
+    for pages := 0; path != ""; pages++ {
+        if pages > 5 {
+            t.Fatal("Pagination did not terminate")
+        }
+        
+        req := httptest.NewRequest("GET", path, nil)
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        
+        if w.Code != http.StatusOK {
+            t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+        }
+        if total := w.Header().Get("X-Total-Count"); total != "5" {
+            t.Errorf("Expected X-Total-Count 5, got %q", total)
+        }
+        
+        var response []model.UserResponse
+        if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+            t.Fatalf("Failed to decode response: %v", err)
+        }
+        for _, u := range response {
+            ids = append(ids, u.ID)
+        }
+        
+        path = ""
+        if m := nextLink.FindStringSubmatch(w.Header().Get("Link")); m != nil {
+            path = m[1]
+        }
+    }
+    
+    want := "[page-4 page-3 page-2 page-1 page-0]"
+    if fmt.Sprint(ids) != want {
+        t.Errorf("Got %v, want %s", ids, want)
+    }
+}
+
+func TestGetAllUsersInvalidParams(t *testing.T) {
+    router, _ := setupTestRouter()
+    
+    for query, field := range map[string]string{
+        "limit=0":             "limit",
+        "limit=101":           "limit",
+        "limit=abc":           "limit",
+        "sort=password":       "sort",
+        "cursor=garbage":      "cursor",
+        "include_total=maybe": "include_total",
+    } {
+        t.Run(query, func(t *testing.T) {
+            req := httptest.NewRequest("GET", "/users?"+query, nil)
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, req)
+            if w.Code != http.StatusBadRequest {
+                t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+            }
+            if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+                t.Errorf("Expected Content-Type %q, got %q", problem.ContentType, ct)
+            }
+            
+            var body problem.Problem
+            json.NewDecoder(w.Body).Decode(&body)
+            if body.Type != problem.TypeValidation || len(body.Errors) != 1 || body.Errors[0].Field != field {
+                t.Errorf("Expected a validation problem for %s, got %+v", field, body)
+            }
+        })
+    }
+}
+
+func TestSearchUsers(t *testing.T) {
+    router, handler := setupTestRouter()
+    handler.repo.Save(context.Background(), model.User{ID: "s-1", Name: "Jane Doe", Email: "jane@example.com", Password: "$argon2id$hash"})
+    handler.repo.Save(context.Background(), model.User{ID: "s-2", Name: "John Roe", Email: "john@example.com"})
+    
+    req := httptest.NewRequest("GET", "/users/search?q=jan", nil)
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    
+    if w.Code != http.StatusOK {
+        t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+    }
+    if bytes.Contains(w.Body.Bytes(), []byte("password")) || bytes.Contains(w.Body.Bytes(), []byte("argon2id")) {
+        t.Errorf("Search response leaks password: %s", w.Body.String())
+    }
+    
+    var response []model.UserSearchResult
+    if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+        t.Fatalf("Failed to decode response: %v", err)
+    }
+    if len(response) != 1 || response[0].ID != "s-1" {
+        t.Fatalf("Unexpected results %+v", response)
+    }
+    if response[0].Score <= 0 || response[0].Highlights["name"] != "<em>Jan</em>e Doe" {
+        t.Errorf("Unexpected score or highlights %+v", response[0])
+    }
+    
+    for _, query := range []string{"", "q=", "q=jan&limit=0"} {
+        req := httptest.NewRequest("GET", "/users/search?"+query, nil)
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        if w.Code != http.StatusBadRequest {
+            t.Errorf("Query %q: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+        }
+    }
+}
+
 func TestGetUser(t *testing.T) {
     router, handler := setupTestRouter()
     
@@ -111,7 +374,7 @@ func TestGetUser(t *testing.T) {
         Password: "password",
     }
     // Use the repo interface to save the user
-    handler.repo.Save(testUser)
+    handler.repo.Save(context.Background(), testUser)
     
     tests := []struct {
         name         string
@@ -142,7 +405,7 @@ func TestGetUser(t *testing.T) {
             }
             
             if w.Code == http.StatusOK {
-                var response model.User
+                var response model.UserResponse
                 if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
                     t.Fatalf("Failed to decode response: %v", err)
                 }
@@ -168,7 +431,7 @@ func TestUpdateUser(t *testing.T) {
         Email:    "original@example.com",
         Password: "password",
     }
-    handler.repo.Save(originalUser)
+    handler.repo.Save(context.Background(), originalUser)
     
     tests := []struct {
         name         string
@@ -189,7 +452,8 @@ func TestUpdateUser(t *testing.T) {
             name:   "update non-existing user",
             userID: "not-exists",
             payload: map[string]string{
-                "name": "Ghost User",
+                "name":  "Ghost User",
+                "email": "ghost@example.com",
             },
             expectedCode: http.StatusNotFound,
         },
@@ -217,6 +481,7 @@ func TestUpdateUser(t *testing.T) {
             
             req := httptest.NewRequest("PUT", "/users/"+tt.userID, bytes.NewBuffer(body))
             req.Header.Set("Content-Type", "application/json")
+            req.Header.Set("If-Match", `"1"`)
             w := httptest.NewRecorder()
             
             router.ServeHTTP(w, req)
@@ -226,7 +491,7 @@ func TestUpdateUser(t *testing.T) {
             }
             
             if w.Code == http.StatusOK {
-                var response model.User
+                var response model.UserResponse
                 if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
                     t.Fatalf("Failed to decode response: %v", err)
                 }
@@ -249,10 +514,10 @@ func TestDeleteUser(t *testing.T) {
     router, handler := setupTestRouter()
     
     // Create test users
-    user1 := model.User{ID: "test-delete-1", Name: "User 1"}
-    user2 := model.User{ID: "test-delete-2", Name: "User 2"}
-    handler.repo.Save(user1)
-    handler.repo.Save(user2)
+    user1 := model.User{ID: "test-delete-1", Name: "User 1", Email: "delete1@example.com"}
+    user2 := model.User{ID: "test-delete-2", Name: "User 2", Email: "delete2@example.com"}
+    handler.repo.Save(context.Background(), user1)
+    handler.repo.Save(context.Background(), user2)
     
     tests := []struct {
         name         string
@@ -274,6 +539,7 @@ func TestDeleteUser(t *testing.T) {
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
             req := httptest.NewRequest("DELETE", "/users/"+tt.userID, nil)
+            req.Header.Set("If-Match", `"1"`)
             w := httptest.NewRecorder()
             
             router.ServeHTTP(w, req)
@@ -284,8 +550,8 @@ func TestDeleteUser(t *testing.T) {
             
             // Verify user is actually deleted
             if w.Code == http.StatusNoContent {
-                _, found := handler.repo.FindById(tt.userID)
-                if found {
+                _, err := handler.repo.FindById(context.Background(), tt.userID)
+                if !errors.Is(err, repository.ErrNotFound) {
                     t.Error("User still exists after deletion")
                 }
             }
@@ -293,37 +559,599 @@ func TestDeleteUser(t *testing.T) {
     }
     
     // Verify other users are not affected
-    _, found := handler.repo.FindById("test-delete-2")
-    if !found {
+    if _, err := handler.repo.FindById(context.Background(), "test-delete-2"); err != nil {
         t.Error("Unrelated user was deleted")
     }
 }
 
+func TestSoftDeleteAndRestore(t *testing.T) {
+    router, handler := setupTestRouter()
+    handler.repo.Save(context.Background(), model.User{ID: "soft-123", Name: "Soft", Email: "soft@example.com", Role: model.RoleUser})
+    admin := auth.Identity{UserID: "admin-1", Role: model.RoleAdmin}
+    user := auth.Identity{UserID: "soft-123", Role: model.RoleUser}
+    
+    send := func(method, path string, id auth.Identity) *httptest.ResponseRecorder {
+        req := withIdentity(httptest.NewRequest(method, path, nil), id)
+        req.Header.Set("If-Match", "*")
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        return w
+    }
+    
+    steps := []struct {
+        name       string
+        method     string
+        path       string
+        identity   auth.Identity
+        wantStatus int
+    }{
+        {"delete", "DELETE", "/users/soft-123", admin, http.StatusNoContent},
+        {"deleted user is hidden", "GET", "/users/soft-123", admin, http.StatusNotFound},
+        {"deleted user cannot be updated", "PATCH", "/users/soft-123", admin, http.StatusNotFound},
+        {"admin reads deleted user", "GET", "/users/soft-123?include_deleted=true", admin, http.StatusOK},
+        {"user cannot read deleted users", "GET", "/users/soft-123?include_deleted=true", user, http.StatusForbidden},
+        {"user cannot list deleted users", "GET", "/users?include_deleted=true", user, http.StatusForbidden},
+        {"invalid include_deleted", "GET", "/users?include_deleted=maybe", admin, http.StatusBadRequest},
+        {"restore", "POST", "/users/soft-123/restore", admin, http.StatusOK},
+        {"restore again", "POST", "/users/soft-123/restore", admin, http.StatusNotFound},
+        {"restored user is visible", "GET", "/users/soft-123", admin, http.StatusOK},
+    }
+    
+    for _, step := range steps {
+        w := send(step.method, step.path, step.identity)
+        if w.Code != step.wantStatus {
+            t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.wantStatus, w.Code, w.Body.String())
+        }
+    }
+    
+    // Deleted users are listed only when asked for, with deleted_at set
+    send("DELETE", "/users/soft-123", admin)
+    for _, tt := range []struct {
+        query string
+        want  int
+    }{
+        {"", 0},
+        {"?include_deleted=true", 1},
+    } {
+        w := send("GET", "/users"+tt.query, admin)
+        var users []model.UserResponse
+        json.NewDecoder(w.Body).Decode(&users)
+        if len(users) != tt.want {
+            t.Fatalf("GET /users%s returned %d users, want %d", tt.query, len(users), tt.want)
+        }
+        if tt.want == 1 && users[0].DeletedAt == nil {
+            t.Errorf("GET /users%s: expected deleted_at on a deleted user", tt.query)
+        }
+    }
+}
+
+func TestConditionalRequests(t *testing.T) {
+    router, handler := setupTestRouter()
+    handler.repo.Save(context.Background(), model.User{ID: "etag-123", Name: "Etag", Email: "etag@example.com", Role: model.RoleUser})
+    
+    send := func(method, ifMatch, ifNoneMatch string) *httptest.ResponseRecorder {
+        body := bytes.NewBufferString(`{"name":"Etag Two","email":"etag@example.com"}`)
+        req := httptest.NewRequest(method, "/users/etag-123", body)
+        if ifMatch != "" {
+            req.Header.Set("If-Match", ifMatch)
+        }
+        if ifNoneMatch != "" {
+            req.Header.Set("If-None-Match", ifNoneMatch)
+        }
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, req)
+        return w
+    }
+    
+    steps := []struct {
+        name        string
+        method      string
+        ifMatch     string
+        ifNoneMatch string
+        wantStatus  int
+        wantETag    string
+    }{
+        {"read returns ETag", "GET", "", "", http.StatusOK, `"1"`},
+        {"matching If-None-Match", "GET", "", `"0", W/"1"`, http.StatusNotModified, `"1"`},
+        {"stale If-None-Match", "GET", "", `"0"`, http.StatusOK, `"1"`},
+        {"write without If-Match", "PUT", "", "", http.StatusPreconditionRequired, ""},
+        {"write with stale If-Match", "PUT", `"0"`, "", http.StatusPreconditionFailed, `"1"`},
+        {"weak tags never match If-Match", "PATCH", `W/"1"`, "", http.StatusPreconditionFailed, `"1"`},
+        {"write with current If-Match", "PUT", `"1"`, "", http.StatusOK, `"2"`},
+        {"replayed write", "PUT", `"1"`, "", http.StatusPreconditionFailed, `"2"`},
+        {"delete with stale If-Match", "DELETE", `"1"`, "", http.StatusPreconditionFailed, `"2"`},
+        {"delete with current If-Match", "DELETE", `"2"`, "", http.StatusNoContent, ""},
+    }
+    
+    for _, step := range steps {
+        w := send(step.method, step.ifMatch, step.ifNoneMatch)
+        if w.Code != step.wantStatus {
+            t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.wantStatus, w.Code, w.Body.String())
+        }
+        if got := w.Header().Get("ETag"); got != step.wantETag {
+            t.Errorf("%s: expected ETag %q, got %q", step.name, step.wantETag, got)
+        }
+        if step.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+            t.Errorf("%s: 304 must not have a body", step.name)
+        }
+    }
+}
+
+// recordingUserRepository remembers the changes passed to Patch
+type recordingUserRepository struct {
+    *repository.MockUserRepository
+    changes *repository.UserChanges
+}
+
+func (r recordingUserRepository) Patch(ctx context.Context, id string, changes repository.UserChanges) error {
+    *r.changes = changes
+    return r.MockUserRepository.Patch(ctx, id, changes)
+}
+
+func TestPatchUser(t *testing.T) {
+    tests := []struct {
+        name           string
+        contentType    string
+        body           string
+        identity       auth.Identity
+        expectedStatus int
+        wantName       string
+        wantEmail      string
+        wantChanged    []string
+    }{
+        {
+            name:           "merge patch sets only supplied fields",
+            contentType:    "application/merge-patch+json",
+            body:           `{"name":"Patched"}`,
+            expectedStatus: http.StatusOK,
+            wantName:       "Patched",
+            wantEmail:      "patch@example.com",
+            wantChanged:    []string{"name"},
+        },
+        {
+            name:           "json patch with passing test",
+            contentType:    "application/json-patch+json",
+            body:           `[{"op":"test","path":"/email","value":"patch@example.com"},{"op":"replace","path":"/email","value":"New@Example.com"}]`,
+            expectedStatus: http.StatusOK,
+            wantName:       "Patch",
+            wantEmail:      "new@example.com",
+            wantChanged:    []string{"email"},
+        },
+        {
+            name:           "password is hashed and written",
+            contentType:    "application/merge-patch+json",
+            body:           `{"password":"newsecret123"}`,
+            expectedStatus: http.StatusOK,
+            wantName:       "Patch",
+            wantEmail:      "patch@example.com",
+            wantChanged:    []string{"password"},
+        },
+        {
+            name:           "unchanged values write nothing",
+            contentType:    "application/merge-patch+json",
+            body:           `{"name":"Patch"}`,
+            expectedStatus: http.StatusOK,
+            wantName:       "Patch",
+            wantEmail:      "patch@example.com",
+        },
+        {
+            name:           "removing a required field fails validation",
+            contentType:    "application/merge-patch+json",
+            body:           `{"name":null}`,
+            expectedStatus: http.StatusBadRequest,
+        },
+        {
+            name:           "unknown field",
+            contentType:    "application/merge-patch+json",
+            body:           `{"id":"other"}`,
+            expectedStatus: http.StatusBadRequest,
+        },
+        {
+            name:           "failed test",
+            contentType:    "application/json-patch+json",
+            body:           `[{"op":"test","path":"/name","value":"Someone else"},{"op":"replace","path":"/name","value":"X"}]`,
+            expectedStatus: http.StatusConflict,
+        },
+        {
+            name:           "missing path",
+            contentType:    "application/json-patch+json",
+            body:           `[{"op":"remove","path":"/nickname"}]`,
+            expectedStatus: http.StatusUnprocessableEntity,
+        },
+        {
+            name:           "malformed patch",
+            contentType:    "application/json-patch+json",
+            body:           `{"op":"remove"}`,
+            expectedStatus: http.StatusBadRequest,
+        },
+        {
+            name:           "unsupported media type",
+            contentType:    "application/json",
+            body:           `{"name":"X"}`,
+            expectedStatus: http.StatusUnsupportedMediaType,
+        },
+        {
+            name:           "role change requires permission",
+            contentType:    "application/merge-patch+json",
+            body:           `{"role":"admin"}`,
+            identity:       auth.Identity{UserID: "patch-123", Role: model.RoleUser},
+            expectedStatus: http.StatusForbidden,
+        },
+        {
+            name:           "admin may change role",
+            contentType:    "application/merge-patch+json",
+            body:           `{"role":"read-only"}`,
+            identity:       auth.Identity{UserID: "admin-1", Role: model.RoleAdmin},
+            expectedStatus: http.StatusOK,
+            wantName:       "Patch",
+            wantEmail:      "patch@example.com",
+            wantChanged:    []string{"role"},
+        },
+    }
+    
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var changes repository.UserChanges
+            repo := recordingUserRepository{repository.NewMockUserRepository(), &changes}
+            repo.Save(context.Background(), model.User{ID: "patch-123", Name: "Patch", Email: "patch@example.com", Password: "$argon2id$old", Role: model.RoleUser})
+            handler := NewUserHandler(repo, password.NewDefaultManager())
+            router := mux.NewRouter()
+            handler.RegisterRoutes(router)
+            
+            req := httptest.NewRequest("PATCH", "/users/patch-123", bytes.NewBufferString(tt.body))
+            req.Header.Set("Content-Type", tt.contentType)
+            req.Header.Set("If-Match", `"1"`)
+            req = withIdentity(req, tt.identity)
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, req)
+            
+            if w.Code != tt.expectedStatus {
+                t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+            }
+            if tt.expectedStatus == http.StatusUnsupportedMediaType && w.Header().Get("Accept-Patch") == "" {
+                t.Error("Expected an Accept-Patch header")
+            }
+            if tt.expectedStatus != http.StatusOK {
+                return
+            }
+            
+            var response model.UserResponse
+            json.NewDecoder(w.Body).Decode(&response)
+            if response.Name != tt.wantName || response.Email != tt.wantEmail {
+                t.Errorf("Got %+v, want name %q and email %q", response, tt.wantName, tt.wantEmail)
+            }
+            
+            var changed []string
+            if changes.Name != nil {
+                changed = append(changed, "name")
+            }
+            if changes.Email != nil {
+                changed = append(changed, "email")
+            }
+            if changes.Password != nil {
+                changed = append(changed, "password")
+                if _, err := handler.passwords.Verify(*changes.Password, "newsecret123"); err != nil {
+                    t.Errorf("Patched password is not a hash of the new password: %v", err)
+                }
+            }
+            if changes.Role != nil {
+                changed = append(changed, "role")
+            }
+            if fmt.Sprint(changed) != fmt.Sprint(tt.wantChanged) {
+                t.Errorf("Changed fields %v, want %v", changed, tt.wantChanged)
+            }
+        })
+    }
+}
+
+// Rows stored before the validation rules existed may break them; a
+// patch must only be held to the rules for the fields it changes
+func TestPatchLegacyUser(t *testing.T) {
+    tests := []struct {
+        name           string
+        body           string
+        expectedStatus int
+        wantFields     []string
+    }{
+        {name: "password only", body: `{"password":"newsecret123"}`, expectedStatus: http.StatusOK},
+        {name: "valid new name", body: `{"name":"Admin User"}`, expectedStatus: http.StatusOK},
+        {name: "invalid new name", body: `{"name":"<b>"}`, expectedStatus: http.StatusBadRequest, wantFields: []string{"name"}},
+        {name: "invalid new email", body: `{"email":"admin@intranet"}`, expectedStatus: http.StatusBadRequest, wantFields: []string{"email"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            repo := repository.NewMockUserRepository()
+            repo.Save(context.Background(), model.User{ID: "legacy-1", Name: "Admin_User", Email: "user@localhost", Password: "$argon2id$old", Role: model.RoleUser})
+            handler := NewUserHandler(repo, password.NewDefaultManager())
+            router := mux.NewRouter()
+            handler.RegisterRoutes(router)
+
+            req := httptest.NewRequest("PATCH", "/users/legacy-1", bytes.NewBufferString(tt.body))
+            req.Header.Set("Content-Type", "application/merge-patch+json")
+            req.Header.Set("If-Match", `"1"`)
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, req)
+
+            if w.Code != tt.expectedStatus {
+                t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+            }
+            if tt.expectedStatus != http.StatusBadRequest {
+                return
+            }
+            var response problem.Problem
+            json.NewDecoder(w.Body).Decode(&response)
+            var fields []string
+            for _, fe := range response.Errors {
+                fields = append(fields, fe.Field)
+            }
+            if fmt.Sprint(fields) != fmt.Sprint(tt.wantFields) {
+                t.Errorf("Errors for %v, want only %v", fields, tt.wantFields)
+            }
+        })
+    }
+}
+
+// failingUserRepository fails every lookup and write with err
+type failingUserRepository struct {
+    *repository.MockUserRepository
+    err error
+}
+
+func (r failingUserRepository) FindById(ctx context.Context, id string) (model.User, error) {
+    return model.User{}, r.err
+}
+
+func (r failingUserRepository) Save(ctx context.Context, user model.User) error {
+    return r.err
+}
+
+func TestRepositoryErrorStatus(t *testing.T) {
+    tests := []struct {
+        name           string
+        err            error
+        method         string
+        path           string
+        body           string
+        expectedStatus int
+    }{
+        {"not found", repository.ErrNotFound, http.MethodGet, "/users/u-1", "", http.StatusNotFound},
+        {"outage is not a 404", &repository.Error{Op: "find user by id", Kind: repository.ErrUnavailable}, http.MethodGet, "/users/u-1", "", http.StatusServiceUnavailable},
+        {"conflict", &repository.Error{Op: "save user", Kind: repository.ErrConflict}, http.MethodPost, "/users", `{"name":"A","email":"a@example.com","password":"secret123"}`, http.StatusConflict},
+        {"unclassified", errors.New("boom"), http.MethodGet, "/users/u-1", "", http.StatusInternalServerError},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            handler := NewUserHandler(failingUserRepository{repository.NewMockUserRepository(), tt.err}, password.NewDefaultManager())
+            router := mux.NewRouter()
+            handler.RegisterRoutes(router)
+
+            req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+            rr := httptest.NewRecorder()
+            router.ServeHTTP(rr, req)
+
+            if rr.Code != tt.expectedStatus {
+                t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+            }
+            if tt.expectedStatus == http.StatusServiceUnavailable && rr.Header().Get("Retry-After") == "" {
+                t.Error("Expected a Retry-After header on 503")
+            }
+        })
+    }
+}
+
+func withIdentity(req *http.Request, id auth.Identity) *http.Request {
+    return req.WithContext(auth.WithIdentity(req.Context(), id))
+}
+
+func TestMe(t *testing.T) {
+    router, handler := setupTestRouter()
+    handler.repo.Save(context.Background(), model.User{ID: "me-123", Name: "Me", Email: "me@example.com", Role: model.RoleUser})
+    me := auth.Identity{UserID: "me-123", Role: model.RoleUser}
+
+    req := withIdentity(httptest.NewRequest("GET", "/users/me", nil), me)
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+
+    var response model.UserResponse
+    json.NewDecoder(w.Body).Decode(&response)
+    if w.Code != http.StatusOK || response.ID != "me-123" {
+        t.Fatalf("GET /users/me = %d %+v", w.Code, response)
+    }
+
+    req = withIdentity(httptest.NewRequest("PUT", "/users/me",
+        bytes.NewBufferString(`{"name":"Renamed","email":"me@example.com"}`)), me)
+    req.Header.Set("If-Match", `"1"`)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    if w.Code != http.StatusOK {
+        t.Fatalf("PUT /users/me returned %d", w.Code)
+    }
+    stored, _ := handler.repo.FindById(context.Background(), "me-123")
+    if stored.Name != "Renamed" || stored.Role != model.RoleUser {
+        t.Errorf("Unexpected stored user %+v", stored)
+    }
+
+    req = httptest.NewRequest("GET", "/users/me", nil)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    if w.Code != http.StatusUnauthorized {
+        t.Errorf("Expected anonymous GET /users/me to return %d, got %d", http.StatusUnauthorized, w.Code)
+    }
+}
+
+func TestUpdateUserRole(t *testing.T) {
+    router, handler := setupTestRouter()
+    handler.repo.Save(context.Background(), model.User{ID: "role-123", Name: "Role", Email: "role@example.com", Role: model.RoleUser})
+
+    admin := auth.Identity{UserID: "admin-1", Role: model.RoleAdmin}
+    user := auth.Identity{UserID: "role-123", Role: model.RoleUser}
+
+    tests := []struct {
+        name         string
+        identity     auth.Identity
+        role         string
+        expectedCode int
+        wantRole     model.Role
+    }{
+        {name: "user escalates self", identity: user, role: "admin", expectedCode: http.StatusForbidden, wantRole: model.RoleUser},
+        {name: "role omitted keeps role", identity: user, role: "", expectedCode: http.StatusOK, wantRole: model.RoleUser},
+        {name: "unchanged role", identity: user, role: "user", expectedCode: http.StatusOK, wantRole: model.RoleUser},
+        {name: "admin sets invalid role", identity: admin, role: "root", expectedCode: http.StatusBadRequest, wantRole: model.RoleUser},
+        {name: "admin changes role", identity: admin, role: "read-only", expectedCode: http.StatusOK, wantRole: model.RoleReadOnly},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            body, _ := json.Marshal(map[string]string{"name": "Role", "email": "role@example.com", "role": tt.role})
+            req := withIdentity(httptest.NewRequest("PUT", "/users/role-123", bytes.NewBuffer(body)), tt.identity)
+            req.Header.Set("If-Match", "*")
+            w := httptest.NewRecorder()
+
+            router.ServeHTTP(w, req)
+
+            if w.Code != tt.expectedCode {
+                t.Errorf("Expected status %d, got %d", tt.expectedCode, w.Code)
+            }
+            stored, _ := handler.repo.FindById(context.Background(), "role-123")
+            if stored.Role != tt.wantRole {
+                t.Errorf("Expected role %q, got %q", tt.wantRole, stored.Role)
+            }
+        })
+    }
+}
+
+// TestNoEndpointEmitsPassword sends a successful request to every route
+// the user and auth handlers register, and fails for a route it has no
+// request for, so a new endpoint cannot leak passwords unnoticed
+func TestNoEndpointEmitsPassword(t *testing.T) {
+    requests := []struct {
+        route       string
+        path        string
+        contentType string
+        // body may contain {refresh}, replaced by a refresh token from a
+        // fresh login
+        body string
+    }{
+        {route: "GET /users", path: "/users"},
+        {route: "POST /users", path: "/users", body: `{"name":"New","email":"new@example.com","password":"secret456"}`},
+        {route: "GET /users/search", path: "/users/search?q=leak"},
+        {route: "GET /users/me", path: "/users/me"},
+        {route: "PUT /users/me", path: "/users/me", body: `{"name":"Leak","email":"leak@example.com","password":"secret456"}`},
+        {route: "PATCH /users/me", path: "/users/me", contentType: "application/merge-patch+json", body: `{"password":"secret456"}`},
+        {route: "GET /users/{id}", path: "/users/leak-123"},
+        {route: "PUT /users/{id}", path: "/users/leak-123", body: `{"name":"Leak","email":"leak@example.com","password":"secret456"}`},
+        {route: "PATCH /users/{id}", path: "/users/leak-123", contentType: "application/merge-patch+json", body: `{"password":"secret456"}`},
+        {route: "PATCH /users/{id}", path: "/users/leak-123", contentType: "application/json-patch+json", body: `[{"op":"add","path":"/password","value":"secret456"}]`},
+        {route: "DELETE /users/{id}", path: "/users/leak-123"},
+        {route: "POST /users/{id}/restore", path: "/users/gone-123/restore"},
+        {route: "POST /auth/login", path: "/auth/login", body: `{"email":"leak@example.com","password":"secret123"}`},
+        {route: "POST /auth/refresh", path: "/auth/refresh", body: `{"refresh_token":"{refresh}"}`},
+        {route: "POST /auth/logout", path: "/auth/logout", body: `{"refresh_token":"{refresh}"}`},
+    }
+
+    setup := func(t *testing.T) *mux.Router {
+        router, handler, repo := setupAuthRouter(t)
+        NewUserHandler(repo, handler.passwords).RegisterRoutes(router)
+        hash, _ := handler.passwords.Hash("secret123")
+        repo.Save(context.Background(), model.User{ID: "leak-123", Name: "Leak Test", Email: "leak@example.com", Password: hash, Role: model.RoleAdmin})
+        repo.Save(context.Background(), model.User{ID: "gone-123", Name: "Gone", Email: "gone@example.com", Password: hash})
+        repo.Delete(context.Background(), "gone-123", 0)
+        return router
+    }
+
+    covered := make(map[string]bool)
+    for _, tt := range requests {
+        covered[tt.route] = true
+    }
+    setup(t).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+        template, _ := route.GetPathTemplate()
+        methods, _ := route.GetMethods()
+        for _, method := range methods {
+            if !covered[method+" "+template] {
+                t.Errorf("No request for %s %s; add one to check it does not leak passwords", method, template)
+            }
+        }
+        return nil
+    })
+
+    for _, tt := range requests {
+        t.Run(strings.TrimSpace(tt.route+" "+tt.contentType), func(t *testing.T) {
+            router := setup(t)
+            body := tt.body
+            if bytes.Contains([]byte(body), []byte("{refresh}")) {
+                req := httptest.NewRequest("POST", "/auth/login", bytes.NewBufferString(`{"email":"leak@example.com","password":"secret123"}`))
+                w := httptest.NewRecorder()
+                router.ServeHTTP(w, req)
+                var tokens model.TokenResponse
+                json.NewDecoder(w.Body).Decode(&tokens)
+                body = string(bytes.ReplaceAll([]byte(body), []byte("{refresh}"), []byte(tokens.RefreshToken)))
+            }
+
+            method, _, _ := strings.Cut(tt.route, " ")
+            req := httptest.NewRequest(method, tt.path, bytes.NewBufferString(body))
+            req = withIdentity(req, auth.Identity{UserID: "leak-123", Role: model.RoleAdmin})
+            if tt.contentType != "" {
+                req.Header.Set("Content-Type", tt.contentType)
+            }
+            req.Header.Set("If-Match", "*")
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, req)
+
+            if w.Code >= 300 {
+                t.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+            }
+            for _, secret := range []string{"password", "$argon2id$", "secret123", "secret456"} {
+                if strings.Contains(w.Body.String(), secret) {
+                    t.Errorf("Response leaks %q: %s", secret, w.Body.String())
+                }
+            }
+        })
+    }
+}
+
 func TestRegisterRoutes(t *testing.T) {
     repo := repository.NewMockUserRepository()
-    handler := NewUserHandler(repo)
+    handler := NewUserHandler(repo, password.NewDefaultManager())
     router := mux.NewRouter()
     
     handler.RegisterRoutes(router)
     
-    // Test that routes are registered correctly
     routes := []struct {
         method string
         path   string
     }{
+        {"GET", "/users"},
         {"POST", "/users"},
+        {"GET", "/users/search"},
+        {"GET", "/users/me"},
+        {"PUT", "/users/me"},
+        {"PATCH", "/users/me"},
         {"GET", "/users/{id}"},
         {"PUT", "/users/{id}"},
+        {"PATCH", "/users/{id}"},
         {"DELETE", "/users/{id}"},
+        {"POST", "/users/{id}/restore"},
     }
     
     for _, route := range routes {
         t.Run(route.method+" "+route.path, func(t *testing.T) {
-            // This is a basic check to ensure routes are registered
-            // In a real scenario, you might want to test the actual routing
-            if router == nil {
-                t.Error("Router should not be nil after registering routes")
+            // The request must reach the route with this template, so
+            // /users/search and /users/me are not taken for an ID
+            path := strings.ReplaceAll(route.path, "{id}", "user-123")
+            var match mux.RouteMatch
+            if !router.Match(httptest.NewRequest(route.method, path, nil), &match) || match.Route == nil {
+                t.Fatalf("%s %s matches no route", route.method, path)
+            }
+            if template, _ := match.Route.GetPathTemplate(); template != route.path {
+                t.Errorf("%s %s matches %s, want %s", route.method, path, template, route.path)
             }
         })
     }
+
+    var match mux.RouteMatch
+    if router.Match(httptest.NewRequest("POST", "/users/user-123", nil), &match) && match.MatchErr == nil {
+        t.Error("POST /users/{id} should not match a route")
+    }
 }
\ No newline at end of file
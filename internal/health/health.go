@@ -0,0 +1,158 @@
+// Package health serves the liveness and readiness endpoints. /healthz
+// reports that the process is serving requests; /readyz additionally
+// checks every dependency and reports not ready once shutdown begins, so
+// load balancers stop routing new requests before the server drains.
+package health
+
+import (
+    "context"
+    "encoding/json"
+    "log"
+    "net/http"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+// DefaultCheckTimeout bounds each readiness check
+const DefaultCheckTimeout = 2 * time.Second
+
+// Config controls the readiness checks. The yaml, toml and env tags name
+// each setting in configuration files and the environment.
+type Config struct {
+    // CheckTimeout bounds each dependency check; a check that takes
+    // longer reports its dependency down
+    CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+    return Config{CheckTimeout: DefaultCheckTimeout}
+}
+
+// Check reports whether a dependency is usable, e.g. by pinging it
+type Check func(ctx context.Context) error
+
+// Status values in responses
+const (
+    StatusOK       = "ok"
+    StatusReady    = "ready"
+    StatusNotReady = "not_ready"
+    StatusUp       = "up"
+    StatusDown     = "down"
+)
+
+// Response is the body of /healthz and /readyz
+type Response struct {
+    Status string `json:"status"`
+    // Checks holds the result for each dependency by name (readiness only)
+    Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of one dependency check. /readyz is public,
+// so why a check failed is logged rather than returned.
+type CheckResult struct {
+    Status     string  `json:"status"`
+    DurationMS float64 `json:"duration_ms"`
+}
+
+// Handler serves the health endpoints
+type Handler struct {
+    cfg      Config
+    names    []string
+    checks   []Check
+    draining atomic.Bool
+}
+
+// NewHandler returns a Handler without dependencies; add them with Add
+// before serving
+func NewHandler(cfg Config) *Handler {
+    return &Handler{cfg: cfg}
+}
+
+// Add registers a readiness check for the named dependency
+func (h *Handler) Add(name string, check Check) {
+    h.names = append(h.names, name)
+    h.checks = append(h.checks, check)
+}
+
+// Drain makes readiness fail from now on, while liveness still succeeds.
+// Call it when graceful shutdown begins.
+func (h *Handler) Drain() {
+    h.draining.Store(true)
+}
+
+// RegisterRoutes adds /healthz and /readyz to r
+func (h *Handler) RegisterRoutes(r *mux.Router) {
+    r.HandleFunc("/healthz", h.Live).Methods("GET", "HEAD")
+    r.HandleFunc("/readyz", h.Ready).Methods("GET", "HEAD")
+}
+
+// Live reports that the process is up; it checks no dependencies, so a
+// failing database does not get the process restarted
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+    writeResponse(w, http.StatusOK, Response{Status: StatusOK})
+}
+
+// Ready runs every check concurrently and responds 200 when all pass, or
+// 503 when any fails or the server is shutting down
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+    results := make(map[string]CheckResult, len(h.checks))
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    for i, check := range h.checks {
+        wg.Add(1)
+        go func(name string, check Check) {
+            defer wg.Done()
+            result := h.run(r.Context(), name, check)
+            mu.Lock()
+            results[name] = result
+            mu.Unlock()
+        }(h.names[i], check)
+    }
+    wg.Wait()
+
+    response := Response{Status: StatusReady, Checks: results}
+    status := http.StatusOK
+    for _, result := range results {
+        if result.Status != StatusUp {
+            response.Status = StatusNotReady
+            status = http.StatusServiceUnavailable
+        }
+    }
+    if h.draining.Load() {
+        response.Status = StatusNotReady
+        status = http.StatusServiceUnavailable
+    }
+    writeResponse(w, status, response)
+}
+
+func (h *Handler) run(ctx context.Context, name string, check Check) CheckResult {
+    if h.cfg.CheckTimeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, h.cfg.CheckTimeout)
+        defer cancel()
+    }
+
+    start := time.Now()
+    err := check(ctx)
+    result := CheckResult{
+        Status:     StatusUp,
+        DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+    }
+    if err != nil {
+        result.Status = StatusDown
+        log.Printf("Readiness check %s failed: %v", name, err)
+    }
+    return result
+}
+
+func writeResponse(w http.ResponseWriter, status int, response Response) {
+    // Probes must always see the current state
+    w.Header().Set("Cache-Control", "no-store")
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(response)
+}
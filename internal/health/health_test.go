@@ -0,0 +1,131 @@
+package health
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+func get(t *testing.T, h *Handler, path string) (int, Response) {
+    t.Helper()
+    rec := serve(h, path)
+
+    if got := rec.Header().Get("Content-Type"); got != "application/json" {
+        t.Errorf("Content-Type = %q, want application/json", got)
+    }
+    var response Response
+    if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+        t.Fatalf("Invalid response body: %v", err)
+    }
+    return rec.Code, response
+}
+
+func serve(h *Handler, path string) *httptest.ResponseRecorder {
+    r := mux.NewRouter()
+    h.RegisterRoutes(r)
+    rec := httptest.NewRecorder()
+    r.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+    return rec
+}
+
+func up(ctx context.Context) error {
+    return nil
+}
+
+func TestLive(t *testing.T) {
+    h := NewHandler(DefaultConfig())
+    h.Add("database", func(ctx context.Context) error {
+        return errors.New("connection refused")
+    })
+    h.Drain()
+
+    // Liveness ignores dependencies and shutdown
+    code, response := get(t, h, "/healthz")
+    if code != http.StatusOK || response.Status != StatusOK {
+        t.Errorf("GET /healthz = %d %+v, want 200 ok", code, response)
+    }
+}
+
+func TestReady(t *testing.T) {
+    h := NewHandler(DefaultConfig())
+    h.Add("database", up)
+    h.Add("cache", up)
+
+    code, response := get(t, h, "/readyz")
+    if code != http.StatusOK || response.Status != StatusReady {
+        t.Errorf("GET /readyz = %d %q, want 200 ready", code, response.Status)
+    }
+    for _, name := range []string{"database", "cache"} {
+        if response.Checks[name].Status != StatusUp {
+            t.Errorf("Check %s = %+v, want up", name, response.Checks[name])
+        }
+    }
+}
+
+func TestReadyDependencyDown(t *testing.T) {
+    h := NewHandler(DefaultConfig())
+    h.Add("database", func(ctx context.Context) error {
+        return errors.New("connection refused")
+    })
+    h.Add("cache", up)
+
+    code, response := get(t, h, "/readyz")
+    if code != http.StatusServiceUnavailable || response.Status != StatusNotReady {
+        t.Errorf("GET /readyz = %d %q, want 503 not_ready", code, response.Status)
+    }
+    if got := response.Checks["database"]; got.Status != StatusDown {
+        t.Errorf("database check = %+v, want down", got)
+    }
+    if got := response.Checks["cache"]; got.Status != StatusUp {
+        t.Errorf("cache check = %+v, want up", got)
+    }
+}
+
+// /readyz is public, so driver errors, which can name hosts and ports,
+// must not reach the response
+func TestReadyHidesCheckErrors(t *testing.T) {
+    h := NewHandler(DefaultConfig())
+    h.Add("database", func(ctx context.Context) error {
+        return errors.New("dial tcp 10.0.0.5:3306: connect: connection refused")
+    })
+
+    rec := serve(h, "/readyz")
+    if body := rec.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "refused") {
+        t.Errorf("GET /readyz body = %s, want no check error details", body)
+    }
+}
+
+func TestReadyCheckTimeout(t *testing.T) {
+    h := NewHandler(Config{CheckTimeout: 20 * time.Millisecond})
+    h.Add("database", func(ctx context.Context) error {
+        <-ctx.Done()
+        return ctx.Err()
+    })
+
+    start := time.Now()
+    code, response := get(t, h, "/readyz")
+    if elapsed := time.Since(start); elapsed > time.Second {
+        t.Errorf("GET /readyz took %s, want it bounded by the check timeout", elapsed)
+    }
+    if code != http.StatusServiceUnavailable || response.Checks["database"].Status != StatusDown {
+        t.Errorf("GET /readyz = %d %+v, want 503 with the database down", code, response)
+    }
+}
+
+func TestReadyDraining(t *testing.T) {
+    h := NewHandler(DefaultConfig())
+    h.Add("database", up)
+    h.Drain()
+
+    code, response := get(t, h, "/readyz")
+    if code != http.StatusServiceUnavailable || response.Status != StatusNotReady {
+        t.Errorf("GET /readyz while draining = %d %q, want 503 not_ready", code, response.Status)
+    }
+}
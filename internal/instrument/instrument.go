@@ -0,0 +1,60 @@
+// Package instrument holds what the metrics and tracing middleware both
+// need to know about a request: the route template it matches and the
+// status code it was answered with
+package instrument
+
+import (
+    "net/http"
+
+    "github.com/gorilla/mux"
+)
+
+// RouteTemplate returns the path template of the router route r matches,
+// e.g. /users/{id}, and false when it matches none
+func RouteTemplate(router *mux.Router, r *http.Request) (string, bool) {
+    var match mux.RouteMatch
+    if !router.Match(r, &match) || match.Route == nil {
+        return "", false
+    }
+    template, err := match.Route.GetPathTemplate()
+    if err != nil {
+        return "", false
+    }
+    return template, true
+}
+
+// StatusRecorder remembers the status code written to a response
+type StatusRecorder struct {
+    http.ResponseWriter
+    status      int
+    wroteHeader bool
+}
+
+// NewStatusRecorder wraps w. Status is 200 until a header is written,
+// as it is for a handler that only calls Write or writes nothing.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+    return &StatusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status returns the status code the response was sent with
+func (r *StatusRecorder) Status() int {
+    return r.status
+}
+
+func (r *StatusRecorder) WriteHeader(status int) {
+    if !r.wroteHeader {
+        r.status = status
+        r.wroteHeader = true
+    }
+    r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *StatusRecorder) Write(b []byte) (int, error) {
+    r.wroteHeader = true
+    return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+    return r.ResponseWriter
+}
@@ -0,0 +1,65 @@
+package instrument
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+)
+
+func TestRouteTemplate(t *testing.T) {
+    router := mux.NewRouter()
+    router.HandleFunc("/users/{id}", func(http.ResponseWriter, *http.Request) {}).Methods("GET")
+
+    tests := []struct {
+        name     string
+        method   string
+        path     string
+        expected string
+        matched  bool
+    }{
+        {name: "matched", method: "GET", path: "/users/123", expected: "/users/{id}", matched: true},
+        {name: "unknown path", method: "GET", path: "/nope", matched: false},
+        {name: "wrong method", method: "DELETE", path: "/users/123", matched: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            template, ok := RouteTemplate(router, httptest.NewRequest(tt.method, tt.path, nil))
+            if ok != tt.matched || template != tt.expected {
+                t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.matched, template, ok)
+            }
+        })
+    }
+}
+
+func TestStatusRecorder(t *testing.T) {
+    tests := []struct {
+        name     string
+        handler  func(w http.ResponseWriter)
+        expected int
+    }{
+        {name: "nothing written", handler: func(http.ResponseWriter) {}, expected: http.StatusOK},
+        {name: "body only", handler: func(w http.ResponseWriter) { w.Write([]byte("ok")) }, expected: http.StatusOK},
+        {name: "explicit status", handler: func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound) }, expected: http.StatusNotFound},
+        {name: "first status wins", handler: func(w http.ResponseWriter) {
+            w.WriteHeader(http.StatusCreated)
+            w.WriteHeader(http.StatusInternalServerError)
+        }, expected: http.StatusCreated},
+        {name: "status after body ignored", handler: func(w http.ResponseWriter) {
+            w.Write([]byte("ok"))
+            w.WriteHeader(http.StatusInternalServerError)
+        }, expected: http.StatusOK},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            recorder := NewStatusRecorder(httptest.NewRecorder())
+            tt.handler(recorder)
+            if recorder.Status() != tt.expected {
+                t.Errorf("Expected status %d, got %d", tt.expected, recorder.Status())
+            }
+        })
+    }
+}
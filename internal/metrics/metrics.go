@@ -0,0 +1,101 @@
+// Package metrics exposes Prometheus metrics: per-route HTTP request
+// counts, latencies and in-flight requests, database connection pool
+// statistics and repository operation timings.
+package metrics
+
+import (
+    "database/sql"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+
+    "go-crud-api/internal/instrument"
+)
+
+// UnmatchedRoute labels requests that match no route, so arbitrary paths
+// cannot create new time series
+const UnmatchedRoute = "unmatched"
+
+// Metrics holds the collectors and the registry they are exposed from
+type Metrics struct {
+    registry     *prometheus.Registry
+    requests     *prometheus.CounterVec
+    duration     *prometheus.HistogramVec
+    inFlight     *prometheus.GaugeVec
+    repoDuration *prometheus.HistogramVec
+}
+
+// New returns Metrics registered in a fresh registry along with the Go
+// runtime and process collectors
+func New() *Metrics {
+    m := &Metrics{
+        registry: prometheus.NewRegistry(),
+        requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Name: "http_requests_total",
+            Help: "HTTP requests by route template, method and status code.",
+        }, []string{"route", "method", "code"}),
+        duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name:    "http_request_duration_seconds",
+            Help:    "HTTP request latency by route template and method.",
+            Buckets: prometheus.DefBuckets,
+        }, []string{"route", "method"}),
+        inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Name: "http_requests_in_flight",
+            Help: "HTTP requests being served by route template and method.",
+        }, []string{"route", "method"}),
+        repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+            Name: "repository_operation_duration_seconds",
+            Help: "Repository operation latency by repository, operation and outcome.",
+            // Queries are usually much faster than whole requests
+            Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+        }, []string{"repository", "operation", "outcome"}),
+    }
+    m.registry.MustRegister(
+        m.requests,
+        m.duration,
+        m.inFlight,
+        m.repoDuration,
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+    )
+    return m
+}
+
+// RegisterDB exports the sql.DBStats of db, labelled with name
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+    return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the Prometheus text exposition format
+func (m *Metrics) Handler() http.Handler {
+    return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Instrument records every request next serves under the template of the
+// router route it matches, e.g. /users/{id}, rather than its raw path.
+// It wraps the whole handler chain, so requests answered before or
+// instead of routing, like CORS preflights and 404s, are counted as well.
+func (m *Metrics) Instrument(router *mux.Router, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        route, ok := instrument.RouteTemplate(router, r)
+        if !ok {
+            route = UnmatchedRoute
+        }
+
+        inFlight := m.inFlight.WithLabelValues(route, r.Method)
+        inFlight.Inc()
+        defer inFlight.Dec()
+
+        recorder := instrument.NewStatusRecorder(w)
+        start := time.Now()
+        next.ServeHTTP(recorder, r)
+
+        m.duration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+        m.requests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.Status())).Inc()
+    })
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+    "context"
+    "database/sql"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    _ "modernc.org/sqlite"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+// scrape returns the exposition text served by m
+func scrape(t *testing.T, m *Metrics) string {
+    t.Helper()
+    rec := httptest.NewRecorder()
+    m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+    if rec.Code != http.StatusOK {
+        t.Fatalf("GET /metrics = %d", rec.Code)
+    }
+    if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+        t.Errorf("Content-Type = %q, want the text exposition format", got)
+    }
+    return rec.Body.String()
+}
+
+func assertMetric(t *testing.T, exposition, line string) {
+    t.Helper()
+    if !strings.Contains(exposition, line+"\n") {
+        t.Errorf("Missing %s in:\n%s", line, exposition)
+    }
+}
+
+func TestInstrument(t *testing.T) {
+    m := New()
+    r := mux.NewRouter()
+    r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, "user")
+    }).Methods("GET")
+    r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusNoContent)
+    }).Methods("DELETE")
+    handler := m.Instrument(r, r)
+
+    requests := []struct {
+        method, path string
+    }{
+        {"GET", "/users/1"},
+        {"GET", "/users/2"},
+        {"DELETE", "/users/3"},
+        {"GET", "/nope/1"},
+        {"GET", "/nope/2"},
+    }
+    for _, req := range requests {
+        handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+    }
+
+    exposition := scrape(t, m)
+    // Raw paths are folded into their route template
+    assertMetric(t, exposition, `http_requests_total{code="200",method="GET",route="/users/{id}"} 2`)
+    assertMetric(t, exposition, `http_requests_total{code="204",method="DELETE",route="/users/{id}"} 1`)
+    assertMetric(t, exposition, `http_requests_total{code="404",method="GET",route="unmatched"} 2`)
+    assertMetric(t, exposition, `http_request_duration_seconds_count{method="GET",route="/users/{id}"} 2`)
+    assertMetric(t, exposition, `http_requests_in_flight{method="GET",route="/users/{id}"} 0`)
+    if strings.Contains(exposition, "/users/1") {
+        t.Error("Raw request path leaked into the labels")
+    }
+}
+
+func TestInstrumentInFlight(t *testing.T) {
+    m := New()
+    r := mux.NewRouter()
+    started := make(chan struct{})
+    release := make(chan struct{})
+    r.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        <-release
+    })
+    handler := m.Instrument(r, r)
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+    }()
+    <-started
+    assertMetric(t, scrape(t, m), `http_requests_in_flight{method="GET",route="/slow"} 1`)
+    close(release)
+    <-done
+    assertMetric(t, scrape(t, m), `http_requests_in_flight{method="GET",route="/slow"} 0`)
+}
+
+func TestRepositoryTimings(t *testing.T) {
+    m := New()
+    repo := NewUserRepository(repository.NewMemoryUserRepository(), m)
+    ctx := context.Background()
+
+    if err := repo.Save(ctx, model.User{ID: "1", Name: "Ann", Email: "ann@example.com", Role: model.RoleUser}); err != nil {
+        t.Fatal(err)
+    }
+    repo.Save(ctx, model.User{ID: "2", Name: "Ann", Email: "ann@example.com", Role: model.RoleUser})
+    repo.FindById(ctx, "1")
+    repo.FindById(ctx, "missing")
+
+    exposition := scrape(t, m)
+    assertMetric(t, exposition, `repository_operation_duration_seconds_count{operation="Save",outcome="ok",repository="users"} 1`)
+    assertMetric(t, exposition, `repository_operation_duration_seconds_count{operation="Save",outcome="conflict",repository="users"} 1`)
+    assertMetric(t, exposition, `repository_operation_duration_seconds_count{operation="FindById",outcome="ok",repository="users"} 1`)
+    assertMetric(t, exposition, `repository_operation_duration_seconds_count{operation="FindById",outcome="not_found",repository="users"} 1`)
+}
+
+func TestRegisterDB(t *testing.T) {
+    db, err := sql.Open("sqlite", ":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    db.SetMaxOpenConns(3)
+
+    m := New()
+    if err := m.RegisterDB(db, "sqlite"); err != nil {
+        t.Fatal(err)
+    }
+    assertMetric(t, scrape(t, m), `go_sql_max_open_connections{db_name="sqlite"} 3`)
+}
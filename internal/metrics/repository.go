@@ -0,0 +1,205 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+var (
+    _ repository.UserRepositoryInterface         = (*UserRepository)(nil)
+    _ repository.RefreshTokenRepositoryInterface = (*RefreshTokenRepository)(nil)
+)
+
+// Outcome labels of repository operations
+const (
+    OutcomeOK          = "ok"
+    OutcomeNotFound    = "not_found"
+    OutcomeConflict    = "conflict"
+    OutcomeStale       = "stale"
+    OutcomeUnavailable = "unavailable"
+    OutcomeError       = "error"
+)
+
+// outcome classifies err by the repository error taxonomy
+func outcome(err error) string {
+    switch {
+    case err == nil:
+        return OutcomeOK
+    case errors.Is(err, repository.ErrNotFound):
+        return OutcomeNotFound
+    case errors.Is(err, repository.ErrConflict):
+        return OutcomeConflict
+    case errors.Is(err, repository.ErrStale):
+        return OutcomeStale
+    case errors.Is(err, repository.ErrUnavailable):
+        return OutcomeUnavailable
+    default:
+        return OutcomeError
+    }
+}
+
+// observe records an operation that started at start
+func (m *Metrics) observe(repo, operation string, start time.Time, err error) {
+    m.repoDuration.WithLabelValues(repo, operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// UserRepository times every operation of the user repository it wraps
+type UserRepository struct {
+    next    repository.UserRepositoryInterface
+    metrics *Metrics
+}
+
+// NewUserRepository wraps next, recording its operations in m
+func NewUserRepository(next repository.UserRepositoryInterface, m *Metrics) *UserRepository {
+    return &UserRepository{next: next, metrics: m}
+}
+
+func (r *UserRepository) observe(operation string, start time.Time, err error) {
+    r.metrics.observe("users", operation, start, err)
+}
+
+func (r *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+    start := time.Now()
+    users, err := r.next.GetAll(ctx)
+    r.observe("GetAll", start, err)
+    return users, err
+}
+
+func (r *UserRepository) List(ctx context.Context, opts repository.ListOptions) (repository.UserPage, error) {
+    start := time.Now()
+    page, err := r.next.List(ctx, opts)
+    r.observe("List", start, err)
+    return page, err
+}
+
+func (r *UserRepository) Search(ctx context.Context, opts repository.SearchOptions) ([]repository.SearchResult, error) {
+    start := time.Now()
+    results, err := r.next.Search(ctx, opts)
+    r.observe("Search", start, err)
+    return results, err
+}
+
+func (r *UserRepository) Save(ctx context.Context, user model.User) error {
+    start := time.Now()
+    err := r.next.Save(ctx, user)
+    r.observe("Save", start, err)
+    return err
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id string) (model.User, error) {
+    start := time.Now()
+    user, err := r.next.FindById(ctx, id)
+    r.observe("FindById", start, err)
+    return user, err
+}
+
+func (r *UserRepository) FindByIdIncludingDeleted(ctx context.Context, id string) (model.User, error) {
+    start := time.Now()
+    user, err := r.next.FindByIdIncludingDeleted(ctx, id)
+    r.observe("FindByIdIncludingDeleted", start, err)
+    return user, err
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+    start := time.Now()
+    user, err := r.next.FindByEmail(ctx, email)
+    r.observe("FindByEmail", start, err)
+    return user, err
+}
+
+func (r *UserRepository) Update(ctx context.Context, user model.User) error {
+    start := time.Now()
+    err := r.next.Update(ctx, user)
+    r.observe("Update", start, err)
+    return err
+}
+
+func (r *UserRepository) Patch(ctx context.Context, id string, changes repository.UserChanges) error {
+    start := time.Now()
+    err := r.next.Patch(ctx, id, changes)
+    r.observe("Patch", start, err)
+    return err
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, id string, hash string) error {
+    start := time.Now()
+    err := r.next.SetPassword(ctx, id, hash)
+    r.observe("SetPassword", start, err)
+    return err
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string, version int64) error {
+    start := time.Now()
+    err := r.next.Delete(ctx, id, version)
+    r.observe("Delete", start, err)
+    return err
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+    start := time.Now()
+    err := r.next.Restore(ctx, id)
+    r.observe("Restore", start, err)
+    return err
+}
+
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+    start := time.Now()
+    purged, err := r.next.Purge(ctx, deletedBefore)
+    r.observe("Purge", start, err)
+    return purged, err
+}
+
+// RefreshTokenRepository times every operation of the refresh token
+// repository it wraps
+type RefreshTokenRepository struct {
+    next    repository.RefreshTokenRepositoryInterface
+    metrics *Metrics
+}
+
+// NewRefreshTokenRepository wraps next, recording its operations in m
+func NewRefreshTokenRepository(next repository.RefreshTokenRepositoryInterface, m *Metrics) *RefreshTokenRepository {
+    return &RefreshTokenRepository{next: next, metrics: m}
+}
+
+func (r *RefreshTokenRepository) observe(operation string, start time.Time, err error) {
+    r.metrics.observe("refresh_tokens", operation, start, err)
+}
+
+func (r *RefreshTokenRepository) Save(ctx context.Context, token model.RefreshToken) error {
+    start := time.Now()
+    err := r.next.Save(ctx, token)
+    r.observe("Save", start, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+    start := time.Now()
+    token, err := r.next.FindByHash(ctx, hash)
+    r.observe("FindByHash", start, err)
+    return token, err
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id string, replacedBy string) error {
+    start := time.Now()
+    err := r.next.Revoke(ctx, id, replacedBy)
+    r.observe("Revoke", start, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+    start := time.Now()
+    err := r.next.RevokeFamily(ctx, familyID)
+    r.observe("RevokeFamily", start, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+    start := time.Now()
+    deleted, err := r.next.DeleteExpired(ctx, before)
+    r.observe("DeleteExpired", start, err)
+    return deleted, err
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+    "net/http"
+    "strings"
+
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/problem"
+)
+
+// Authenticate rejects requests without a valid "Authorization: Bearer"
+// access token and stores the caller identity in the request context
+func Authenticate(tokens *auth.TokenService) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            header := r.Header.Get("Authorization")
+            scheme, token, found := strings.Cut(header, " ")
+            if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+                w.Header().Set("WWW-Authenticate", `Bearer realm="go-crud-api"`)
+                problem.Error(w, r, http.StatusUnauthorized, "Missing bearer token")
+                return
+            }
+
+            identity, err := tokens.Parse(token)
+            if err != nil {
+                w.Header().Set("WWW-Authenticate", `Bearer realm="go-crud-api", error="invalid_token"`)
+                problem.Write(w, r, problem.Typed(problem.TypeInvalidToken, "Invalid token",
+                    http.StatusUnauthorized, "The access token is invalid or expired"))
+                return
+            }
+
+            next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+        })
+    }
+}
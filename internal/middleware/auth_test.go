@@ -0,0 +1,60 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/model"
+)
+
+func TestAuthenticate(t *testing.T) {
+    tokens := auth.NewTokenService(auth.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef")), time.Minute)
+    valid, _, err := tokens.Issue(model.User{ID: "user-123", Email: "john@example.com"})
+    if err != nil {
+        t.Fatalf("Failed to issue token: %v", err)
+    }
+
+    var got auth.Identity
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got, _ = auth.IdentityFromContext(r.Context())
+    })
+    handler := Authenticate(tokens)(next)
+
+    tests := []struct {
+        name         string
+        header       string
+        expectedCode int
+    }{
+        {name: "valid token", header: "Bearer " + valid, expectedCode: http.StatusOK},
+        {name: "lowercase scheme", header: "bearer " + valid, expectedCode: http.StatusOK},
+        {name: "missing header", header: "", expectedCode: http.StatusUnauthorized},
+        {name: "wrong scheme", header: "Basic dXNlcjpwYXNz", expectedCode: http.StatusUnauthorized},
+        {name: "invalid token", header: "Bearer not-a-token", expectedCode: http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got = auth.Identity{}
+            req := httptest.NewRequest("GET", "/users", nil)
+            if tt.header != "" {
+                req.Header.Set("Authorization", tt.header)
+            }
+            w := httptest.NewRecorder()
+
+            handler.ServeHTTP(w, req)
+
+            if w.Code != tt.expectedCode {
+                t.Fatalf("Expected status %d, got %d", tt.expectedCode, w.Code)
+            }
+            if w.Code == http.StatusOK && got.UserID != "user-123" {
+                t.Errorf("Expected identity user-123 in context, got %+v", got)
+            }
+            if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+                t.Error("Expected WWW-Authenticate header on 401")
+            }
+        })
+    }
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+    "net/http"
+
+    "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/problem"
+)
+
+// RequirePermission rejects callers whose role does not grant p. It must
+// run after Authenticate.
+func RequirePermission(p auth.Permission) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            identity, ok := auth.IdentityFromContext(r.Context())
+            if !ok {
+                problem.Error(w, r, http.StatusUnauthorized, "Authentication required")
+                return
+            }
+            if !identity.Can(p) {
+                problem.Error(w, r, http.StatusForbidden, "Your role does not allow this operation")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+// RequireOwnerOrPermission guards routes with an {id} variable. Callers
+// with anyPerm may act on every user; callers with ownPerm only on the
+// user whose id matches their own.
+func RequireOwnerOrPermission(anyPerm, ownPerm auth.Permission) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            identity, ok := auth.IdentityFromContext(r.Context())
+            if !ok {
+                problem.Error(w, r, http.StatusUnauthorized, "Authentication required")
+                return
+            }
+
+            isOwner := mux.Vars(r)["id"] == identity.UserID
+            if !identity.Can(anyPerm) && !(isOwner && identity.Can(ownPerm)) {
+                problem.Error(w, r, http.StatusForbidden, "Your role does not allow this operation")
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go-crud-api/internal/auth"
+    "go-crud-api/internal/model"
+)
+
+func serveAs(handler http.Handler, identity *auth.Identity, path string) int {
+    req := httptest.NewRequest("GET", path, nil)
+    if identity != nil {
+        req = req.WithContext(auth.WithIdentity(req.Context(), *identity))
+    }
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, req)
+    return w.Code
+}
+
+func TestRequirePermission(t *testing.T) {
+    ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+    handler := RequirePermission(auth.PermListUsers)(ok)
+
+    tests := []struct {
+        name         string
+        identity     *auth.Identity
+        expectedCode int
+    }{
+        {name: "admin", identity: &auth.Identity{UserID: "1", Role: model.RoleAdmin}, expectedCode: http.StatusOK},
+        {name: "user", identity: &auth.Identity{UserID: "2", Role: model.RoleUser}, expectedCode: http.StatusForbidden},
+        {name: "anonymous", identity: nil, expectedCode: http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if code := serveAs(handler, tt.identity, "/users"); code != tt.expectedCode {
+                t.Errorf("Expected status %d, got %d", tt.expectedCode, code)
+            }
+        })
+    }
+}
+
+func TestRequireOwnerOrPermission(t *testing.T) {
+    router := mux.NewRouter()
+    router.Handle("/users/{id}", RequireOwnerOrPermission(auth.PermReadAnyUser, auth.PermReadOwnUser)(
+        http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+    admin := &auth.Identity{UserID: "admin-1", Role: model.RoleAdmin}
+    user := &auth.Identity{UserID: "user-1", Role: model.RoleUser}
+    noRole := &auth.Identity{UserID: "user-2"}
+
+    tests := []struct {
+        name         string
+        identity     *auth.Identity
+        path         string
+        expectedCode int
+    }{
+        {name: "admin reads other user", identity: admin, path: "/users/user-1", expectedCode: http.StatusOK},
+        {name: "user reads self", identity: user, path: "/users/user-1", expectedCode: http.StatusOK},
+        {name: "user reads other user", identity: user, path: "/users/admin-1", expectedCode: http.StatusForbidden},
+        {name: "unknown role reads self", identity: noRole, path: "/users/user-2", expectedCode: http.StatusForbidden},
+        {name: "anonymous", identity: nil, path: "/users/user-1", expectedCode: http.StatusUnauthorized},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if code := serveAs(router, tt.identity, tt.path); code != tt.expectedCode {
+                t.Errorf("Expected status %d, got %d", tt.expectedCode, code)
+            }
+        })
+    }
+}
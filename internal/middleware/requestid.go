@@ -0,0 +1,22 @@
+package middleware
+
+import (
+    "net/http"
+
+    "go-crud-api/internal/requestid"
+)
+
+// RequestID reuses the caller's X-Request-ID when it is well formed and
+// generates one otherwise. The ID is echoed in the response and stored in
+// the request context, where error responses pick it up.
+func RequestID(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        id := r.Header.Get(requestid.Header)
+        if !requestid.Valid(id) {
+            id = requestid.New()
+        }
+
+        w.Header().Set(requestid.Header, id)
+        next.ServeHTTP(w, r.WithContext(requestid.WithRequestID(r.Context(), id)))
+    })
+}
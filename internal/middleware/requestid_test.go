@@ -0,0 +1,46 @@
+package middleware
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "go-crud-api/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+    var got string
+    handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got = requestid.FromContext(r.Context())
+    }))
+
+    tests := []struct {
+        name     string
+        incoming string
+        reused   bool
+    }{
+        {name: "generated", incoming: "", reused: false},
+        {name: "reused", incoming: "abc-123", reused: true},
+        {name: "rejects spaces", incoming: "abc 123", reused: false},
+        {name: "rejects oversized", incoming: strings.Repeat("a", 129), reused: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            req := httptest.NewRequest(http.MethodGet, "/", nil)
+            if tt.incoming != "" {
+                req.Header.Set(requestid.Header, tt.incoming)
+            }
+            rr := httptest.NewRecorder()
+            handler.ServeHTTP(rr, req)
+
+            if got == "" || rr.Header().Get(requestid.Header) != got {
+                t.Fatalf("context ID %q, response header %q", got, rr.Header().Get(requestid.Header))
+            }
+            if (got == tt.incoming) != tt.reused {
+                t.Errorf("ID = %q, incoming %q, want reused %v", got, tt.incoming, tt.reused)
+            }
+        })
+    }
+}
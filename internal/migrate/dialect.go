@@ -0,0 +1,149 @@
+package migrate
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "hash/fnv"
+    "strconv"
+    "time"
+)
+
+// Dialect holds the database specific parts of running migrations
+type Dialect interface {
+    // CreateVersionTable returns the statement creating schema_migrations
+    // if it does not exist
+    CreateVersionTable() string
+    // Placeholder returns the bind parameter for the nth (1-based) argument
+    Placeholder(n int) string
+    // Lock blocks until conn holds the migration lock, or fails with
+    // ErrLocked after timeout
+    Lock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error
+    Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// lockName identifies the migration lock among the database's named locks
+const lockName = "go-crud-api.schema_migrations"
+
+// MySQL uses a named lock (GET_LOCK), which belongs to the connection and
+// is released when the connection closes even if the process dies
+type MySQL struct{}
+
+func (MySQL) CreateVersionTable() string {
+    return `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT PRIMARY KEY,
+        name VARCHAR(255) NOT NULL,
+        checksum CHAR(64) NOT NULL,
+        applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )`
+}
+
+func (MySQL) Placeholder(n int) string {
+    return "?"
+}
+
+func (MySQL) Lock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+    seconds := int(timeout / time.Second)
+    if seconds < 1 {
+        seconds = 1
+    }
+
+    var acquired sql.NullInt64
+    err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, lockName, seconds).Scan(&acquired)
+    if err != nil {
+        return fmt.Errorf("failed to acquire migration lock: %w", err)
+    }
+    if !acquired.Valid || acquired.Int64 != 1 {
+        return fmt.Errorf("%w after %s", ErrLocked, timeout)
+    }
+    return nil
+}
+
+func (MySQL) Unlock(ctx context.Context, conn *sql.Conn) error {
+    var released sql.NullInt64
+    return conn.QueryRowContext(ctx, `SELECT RELEASE_LOCK(?)`, lockName).Scan(&released)
+}
+
+// SQLite has no named locks, and a transaction held for the whole run
+// would block the migrations' own transactions. Instead its DDL is
+// transactional and each migration runs in a transaction, so when two
+// runs on the same file race, the second waits for the first to commit
+// (up to the connection's busy timeout), fails on the migration it
+// applied, rolls back and skips it as applied.
+type SQLite struct{}
+
+func (SQLite) CreateVersionTable() string {
+    return `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        checksum TEXT NOT NULL,
+        applied_at TIMESTAMP NOT NULL
+    )`
+}
+
+func (SQLite) Placeholder(n int) string {
+    return "?"
+}
+
+func (SQLite) Lock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+    return nil
+}
+
+func (SQLite) Unlock(ctx context.Context, conn *sql.Conn) error {
+    return nil
+}
+
+// Postgres uses a session-level advisory lock, which like MySQL's named
+// lock is released when the connection closes. Its DDL is transactional,
+// so a failing migration leaves nothing behind.
+type Postgres struct{}
+
+// advisoryLockKey is the Postgres advisory lock id, derived from lockName
+var advisoryLockKey = func() int64 {
+    h := fnv.New64a()
+    h.Write([]byte(lockName))
+    return int64(h.Sum64())
+}()
+
+func (Postgres) CreateVersionTable() string {
+    return `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT PRIMARY KEY,
+        name VARCHAR(255) NOT NULL,
+        checksum CHAR(64) NOT NULL,
+        applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+    )`
+}
+
+func (Postgres) Placeholder(n int) string {
+    return "$" + strconv.Itoa(n)
+}
+
+// Lock polls pg_try_advisory_lock, as pg_advisory_lock has no timeout of
+// its own
+func (Postgres) Lock(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    ticker := time.NewTicker(100 * time.Millisecond)
+    defer ticker.Stop()
+    for {
+        var acquired bool
+        err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, advisoryLockKey).Scan(&acquired)
+        if err != nil && ctx.Err() == nil {
+            return fmt.Errorf("failed to acquire migration lock: %w", err)
+        }
+        if acquired {
+            return nil
+        }
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("%w after %s", ErrLocked, timeout)
+        case <-ticker.C:
+        }
+    }
+}
+
+func (Postgres) Unlock(ctx context.Context, conn *sql.Conn) error {
+    var released bool
+    return conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey).Scan(&released)
+}
@@ -0,0 +1,442 @@
+// Package migrate applies versioned SQL schema migrations.
+//
+// Migrations are pairs of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, usually embedded in the binary. Applied
+// migrations are recorded with a checksum of their up file in the
+// schema_migrations table, and runs hold a database lock where the
+// database has one so that API replicas starting at the same time do not
+// race.
+package migrate
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io/fs"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// DefaultLockTimeout is how long a run waits for another replica's run to
+// finish before giving up
+const DefaultLockTimeout = time.Minute
+
+var (
+    // ErrChecksumMismatch means an applied migration's file was edited
+    ErrChecksumMismatch = errors.New("migration checksum mismatch")
+    // ErrIrreversible means a migration has no down file
+    ErrIrreversible = errors.New("migration has no down file")
+    // ErrUnknownMigration means the database has a migration this binary
+    // does not know, e.g. one applied by a newer release
+    ErrUnknownMigration = errors.New("unknown migration")
+    // ErrLocked means the lock was not acquired within the lock timeout
+    ErrLocked = errors.New("migration lock not acquired")
+)
+
+// Migration is one schema version
+type Migration struct {
+    Version int64
+    Name    string
+    Up      string
+    Down    string
+    // Checksum is the hex SHA-256 of the up file
+    Checksum string
+}
+
+// Status describes a migration known to the binary or the database
+type Status struct {
+    Version int64
+    Name    string
+    // AppliedAt is nil for pending migrations
+    AppliedAt *time.Time
+    // Unknown is set for applied migrations the binary has no file for
+    Unknown bool
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+    Version   int64
+    Name      string
+    Checksum  string
+    AppliedAt time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in the root of fsys, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, ".")
+    if err != nil {
+        return nil, fmt.Errorf("failed to read migrations: %w", err)
+    }
+
+    byVersion := make(map[int64]*Migration)
+    for _, entry := range entries {
+        if entry.IsDir() {
+            continue
+        }
+        match := fileName.FindStringSubmatch(entry.Name())
+        if match == nil {
+            return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+        }
+        version, err := strconv.ParseInt(match[1], 10, 64)
+        if err != nil || version <= 0 {
+            return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+        }
+        content, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+        if err != nil {
+            return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+        }
+
+        m, exists := byVersion[version]
+        if !exists {
+            m = &Migration{Version: version, Name: match[2]}
+            byVersion[version] = m
+        }
+        if m.Name != match[2] {
+            return nil, fmt.Errorf("migration %d has files with different names", version)
+        }
+        if match[3] == "up" {
+            sum := sha256.Sum256(content)
+            m.Up = string(content)
+            m.Checksum = hex.EncodeToString(sum[:])
+        } else {
+            m.Down = string(content)
+        }
+    }
+
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, m := range byVersion {
+        if m.Checksum == "" {
+            return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+        }
+        migrations = append(migrations, *m)
+    }
+    sort.Slice(migrations, func(i, j int) bool {
+        return migrations[i].Version < migrations[j].Version
+    })
+    return migrations, nil
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+    db         *sql.DB
+    dialect    Dialect
+    migrations []Migration
+    // LockTimeout bounds the wait for a concurrent run; defaults to
+    // DefaultLockTimeout
+    LockTimeout time.Duration
+}
+
+// New loads the migrations in fsys for db
+func New(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+    migrations, err := Load(fsys)
+    if err != nil {
+        return nil, err
+    }
+    return &Migrator{db: db, dialect: dialect, migrations: migrations, LockTimeout: DefaultLockTimeout}, nil
+}
+
+// Up applies every pending migration in version order and returns the
+// ones it applied. It fails without applying anything if an applied
+// migration's file has changed.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+    var done []Migration
+    err := m.locked(ctx, func(conn *sql.Conn, current map[int64]applied) error {
+        todo, err := pending(m.migrations, current)
+        if err != nil {
+            return err
+        }
+        for _, migration := range todo {
+            applied, err := m.applyUp(ctx, conn, migration)
+            if err != nil {
+                return err
+            }
+            if applied {
+                done = append(done, migration)
+            }
+        }
+        return nil
+    })
+    return done, err
+}
+
+// applyUp applies migration, or reports false when a concurrent run has
+// applied it since the applied migrations were read. That only happens on
+// databases without a lock, like SQLite, where the second run fails on
+// the first run's schema or schema_migrations row.
+func (m *Migrator) applyUp(ctx context.Context, conn *sql.Conn, migration Migration) (bool, error) {
+    err := m.apply(ctx, conn, migration, migration.Up, true)
+    if err == nil {
+        return true, nil
+    }
+
+    var checksum string
+    row := conn.QueryRowContext(ctx, `SELECT checksum FROM schema_migrations WHERE version = `+m.dialect.Placeholder(1), migration.Version)
+    if row.Scan(&checksum) == nil && checksum == migration.Checksum {
+        return false, nil
+    }
+    return false, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and
+// returns the ones it reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+    var done []Migration
+    err := m.locked(ctx, func(conn *sql.Conn, current map[int64]applied) error {
+        todo, err := rollback(m.migrations, current, steps)
+        if err != nil {
+            return err
+        }
+        for _, migration := range todo {
+            if err := m.apply(ctx, conn, migration, migration.Down, false); err != nil {
+                return err
+            }
+            done = append(done, migration)
+        }
+        return nil
+    })
+    return done, err
+}
+
+// Status lists every known and applied migration by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+
+    if _, err := conn.ExecContext(ctx, m.dialect.CreateVersionTable()); err != nil {
+        return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+    }
+    current, err := m.applied(ctx, conn)
+    if err != nil {
+        return nil, err
+    }
+
+    var statuses []Status
+    for _, migration := range m.migrations {
+        status := Status{Version: migration.Version, Name: migration.Name}
+        if row, ok := current[migration.Version]; ok {
+            appliedAt := row.AppliedAt
+            status.AppliedAt = &appliedAt
+            delete(current, migration.Version)
+        }
+        statuses = append(statuses, status)
+    }
+    for _, row := range current {
+        appliedAt := row.AppliedAt
+        statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Unknown: true})
+    }
+    sort.Slice(statuses, func(i, j int) bool {
+        return statuses[i].Version < statuses[j].Version
+    })
+    return statuses, nil
+}
+
+// locked runs fn on a single connection holding the migration lock, with
+// the applied migrations read under the lock
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn, map[int64]applied) error) error {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    timeout := m.LockTimeout
+    if timeout <= 0 {
+        timeout = DefaultLockTimeout
+    }
+    if err := m.dialect.Lock(ctx, conn, timeout); err != nil {
+        return err
+    }
+    // Release even if ctx is cancelled, or the lock outlives the run
+    // until the connection is closed
+    defer m.dialect.Unlock(context.Background(), conn)
+
+    if _, err := conn.ExecContext(ctx, m.dialect.CreateVersionTable()); err != nil {
+        return fmt.Errorf("failed to create schema_migrations: %w", err)
+    }
+    current, err := m.applied(ctx, conn)
+    if err != nil {
+        return err
+    }
+    return fn(conn, current)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+    rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+    }
+    defer rows.Close()
+
+    current := make(map[int64]applied)
+    for rows.Next() {
+        var row applied
+        if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+            return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+        }
+        current[row.Version] = row
+    }
+    return current, rows.Err()
+}
+
+// apply runs one direction of a migration and records it in the same
+// transaction. Databases such as MySQL commit DDL implicitly, so a
+// failing multi-statement migration may be left partially applied there.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, script string, up bool) error {
+    direction := "down"
+    if up {
+        direction = "up"
+    }
+    fail := func(err error) error {
+        return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+    }
+
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return fail(err)
+    }
+    defer tx.Rollback()
+
+    for _, statement := range SplitStatements(script) {
+        if _, err := tx.ExecContext(ctx, statement); err != nil {
+            return fail(err)
+        }
+    }
+
+    p := m.dialect.Placeholder
+    if up {
+        _, err = tx.ExecContext(ctx,
+            `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (`+
+                p(1)+`, `+p(2)+`, `+p(3)+`, `+p(4)+`)`,
+            migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+    } else {
+        _, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = `+p(1), migration.Version)
+    }
+    if err != nil {
+        return fail(err)
+    }
+    if err := tx.Commit(); err != nil {
+        return fail(err)
+    }
+    return nil
+}
+
+// pending returns the migrations not yet applied, after checking that the
+// applied ones are unchanged. Applied migrations unknown to the binary are
+// left alone, so an older release can still start against a newer schema.
+func pending(migrations []Migration, current map[int64]applied) ([]Migration, error) {
+    var todo []Migration
+    for _, migration := range migrations {
+        row, ok := current[migration.Version]
+        if !ok {
+            todo = append(todo, migration)
+            continue
+        }
+        if row.Checksum != migration.Checksum {
+            return nil, fmt.Errorf("%w: %d_%s was changed after it was applied", ErrChecksumMismatch, migration.Version, migration.Name)
+        }
+    }
+    return todo, nil
+}
+
+// rollback returns the last steps applied migrations, newest first
+func rollback(migrations []Migration, current map[int64]applied, steps int) ([]Migration, error) {
+    versions := make([]int64, 0, len(current))
+    for version := range current {
+        versions = append(versions, version)
+    }
+    sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+    if steps < len(versions) {
+        versions = versions[:steps]
+    }
+
+    byVersion := make(map[int64]Migration, len(migrations))
+    for _, migration := range migrations {
+        byVersion[migration.Version] = migration
+    }
+
+    todo := make([]Migration, 0, len(versions))
+    for _, version := range versions {
+        migration, ok := byVersion[version]
+        if !ok {
+            return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, current[version].Name)
+        }
+        if current[version].Checksum != migration.Checksum {
+            return nil, fmt.Errorf("%w: %d_%s was changed after it was applied", ErrChecksumMismatch, version, migration.Name)
+        }
+        if strings.TrimSpace(migration.Down) == "" {
+            return nil, fmt.Errorf("%w: %d_%s", ErrIrreversible, version, migration.Name)
+        }
+        todo = append(todo, migration)
+    }
+    return todo, nil
+}
+
+// SplitStatements splits a script into statements at semicolons outside
+// quotes and comments, dropping comments and empty statements
+func SplitStatements(script string) []string {
+    var statements []string
+    var current strings.Builder
+    var quote byte
+
+    flush := func() {
+        if s := strings.TrimSpace(current.String()); s != "" {
+            statements = append(statements, s)
+        }
+        current.Reset()
+    }
+
+    for i := 0; i < len(script); i++ {
+        c := script[i]
+        switch {
+        case quote != 0:
+            current.WriteByte(c)
+            if c == quote {
+                // A doubled quote is an escaped quote, not the end
+                if i+1 < len(script) && script[i+1] == quote {
+                    current.WriteByte(script[i+1])
+                    i++
+                } else {
+                    quote = 0
+                }
+            } else if c == '\\' && quote != '`' && i+1 < len(script) {
+                current.WriteByte(script[i+1])
+                i++
+            }
+        case c == '\'' || c == '"' || c == '`':
+            quote = c
+            current.WriteByte(c)
+        case c == '-' && strings.HasPrefix(script[i:], "--"):
+            end := strings.IndexByte(script[i:], '\n')
+            if end < 0 {
+                i = len(script)
+            } else {
+                i += end - 1
+            }
+        case c == '/' && strings.HasPrefix(script[i:], "/*"):
+            end := strings.Index(script[i+2:], "*/")
+            if end < 0 {
+                i = len(script)
+            } else {
+                i += end + 3
+            }
+        case c == ';':
+            flush()
+        default:
+            current.WriteByte(c)
+        }
+    }
+    flush()
+    return statements
+}
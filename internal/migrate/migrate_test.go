@@ -0,0 +1,364 @@
+package migrate
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "io/fs"
+    "path/filepath"
+    "reflect"
+    "sync"
+    "testing"
+    "testing/fstest"
+
+    _ "modernc.org/sqlite"
+)
+
+func TestLoad(t *testing.T) {
+    fsys := fstest.MapFS{
+        "0002_add_index.up.sql":      {Data: []byte("CREATE INDEX idx ON t (a);")},
+        "0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (a INT);")},
+        "0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+    }
+
+    migrations, err := Load(fsys)
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    if len(migrations) != 2 {
+        t.Fatalf("Load() returned %d migrations, want 2", len(migrations))
+    }
+    first, second := migrations[0], migrations[1]
+    if first.Version != 1 || first.Name != "create_table" || first.Down != "DROP TABLE t;" {
+        t.Errorf("Unexpected first migration %+v", first)
+    }
+    if second.Version != 2 || second.Down != "" {
+        t.Errorf("Unexpected second migration %+v", second)
+    }
+    if len(first.Checksum) != 64 || first.Checksum == second.Checksum {
+        t.Errorf("Unexpected checksums %q, %q", first.Checksum, second.Checksum)
+    }
+}
+
+func TestLoadRejects(t *testing.T) {
+    tests := []struct {
+        name  string
+        files []string
+    }{
+        {"bad file name", []string{"create_table.up.sql"}},
+        {"bad direction", []string{"0001_create_table.sideways.sql"}},
+        {"version zero", []string{"0000_create_table.up.sql"}},
+        {"missing up file", []string{"0001_create_table.down.sql"}},
+        {"mismatched names", []string{"0001_create_table.up.sql", "0001_drop_table.down.sql"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            fsys := fstest.MapFS{}
+            for _, name := range tt.files {
+                fsys[name] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+            }
+            if _, err := Load(fsys); err == nil {
+                t.Error("Load() error = nil, want an error")
+            }
+        })
+    }
+}
+
+func TestMySQLMigrations(t *testing.T) {
+    migrations, err := Load(MySQLMigrations())
+    if err != nil {
+        t.Fatalf("Load() error = %v", err)
+    }
+    for i, m := range migrations {
+        if m.Version != int64(i+1) {
+            t.Errorf("Migration %d_%s breaks the version sequence", m.Version, m.Name)
+        }
+        if len(SplitStatements(m.Up)) == 0 || len(SplitStatements(m.Down)) == 0 {
+            t.Errorf("Migration %d_%s needs both up and down statements", m.Version, m.Name)
+        }
+    }
+}
+
+func TestSplitStatements(t *testing.T) {
+    script := `-- leading comment; not a statement
+CREATE TABLE t (
+    a VARCHAR(10) DEFAULT 'x;y', -- trailing comment
+    b VARCHAR(10) DEFAULT 'it''s'
+);
+/* block; comment */
+INSERT INTO t (a) VALUES ('back\'slash;');
+
+;`
+
+    want := []string{
+        "CREATE TABLE t (\n    a VARCHAR(10) DEFAULT 'x;y', \n    b VARCHAR(10) DEFAULT 'it''s'\n)",
+        `INSERT INTO t (a) VALUES ('back\'slash;')`,
+    }
+    if got := SplitStatements(script); !reflect.DeepEqual(got, want) {
+        t.Errorf("SplitStatements() = %q, want %q", got, want)
+    }
+}
+
+func TestPending(t *testing.T) {
+    migrations := []Migration{
+        {Version: 1, Name: "one", Checksum: "a"},
+        {Version: 2, Name: "two", Checksum: "b"},
+        {Version: 3, Name: "three", Checksum: "c"},
+    }
+
+    todo, err := pending(migrations, map[int64]applied{1: {Version: 1, Checksum: "a"}, 9: {Version: 9}})
+    if err != nil {
+        t.Fatalf("pending() error = %v", err)
+    }
+    if len(todo) != 2 || todo[0].Version != 2 || todo[1].Version != 3 {
+        t.Errorf("pending() = %+v, want versions 2 and 3", todo)
+    }
+
+    _, err = pending(migrations, map[int64]applied{2: {Version: 2, Checksum: "changed"}})
+    if !errors.Is(err, ErrChecksumMismatch) {
+        t.Errorf("pending() with an edited migration = %v, want ErrChecksumMismatch", err)
+    }
+}
+
+func TestRollback(t *testing.T) {
+    migrations := []Migration{
+        {Version: 1, Name: "one", Checksum: "a", Down: "DROP TABLE one;"},
+        {Version: 2, Name: "two", Checksum: "b", Down: "DROP TABLE two;"},
+        {Version: 3, Name: "three", Checksum: "c"},
+    }
+    current := map[int64]applied{1: {Version: 1, Checksum: "a"}, 2: {Version: 2, Checksum: "b"}}
+
+    todo, err := rollback(migrations, current, 5)
+    if err != nil {
+        t.Fatalf("rollback() error = %v", err)
+    }
+    if len(todo) != 2 || todo[0].Version != 2 || todo[1].Version != 1 {
+        t.Errorf("rollback() = %+v, want versions 2 then 1", todo)
+    }
+
+    tests := []struct {
+        name    string
+        current map[int64]applied
+        wantErr error
+    }{
+        {"no down file", map[int64]applied{3: {Version: 3, Checksum: "c"}}, ErrIrreversible},
+        {"edited migration", map[int64]applied{2: {Version: 2, Checksum: "changed"}}, ErrChecksumMismatch},
+        {"unknown migration", map[int64]applied{9: {Version: 9, Name: "newer"}}, ErrUnknownMigration},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := rollback(migrations, tt.current, 1); !errors.Is(err, tt.wantErr) {
+                t.Errorf("rollback() error = %v, want %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestMigrationSetsMatch(t *testing.T) {
+    mysql, err := Load(MySQLMigrations())
+    if err != nil {
+        t.Fatalf("Load(MySQL) error = %v", err)
+    }
+    for name, fsys := range map[string]fs.FS{"SQLite": SQLiteMigrations(), "Postgres": PostgresMigrations()} {
+        other, err := Load(fsys)
+        if err != nil {
+            t.Fatalf("Load(%s) error = %v", name, err)
+        }
+        if len(mysql) != len(other) {
+            t.Fatalf("MySQL has %d migrations, %s %d", len(mysql), name, len(other))
+        }
+        for i := range mysql {
+            if mysql[i].Version != other[i].Version || mysql[i].Name != other[i].Name {
+                t.Errorf("MySQL migration %d_%s does not match %s %d_%s",
+                    mysql[i].Version, mysql[i].Name, name, other[i].Version, other[i].Name)
+            }
+        }
+    }
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+    t.Helper()
+    db, err := sql.Open("sqlite", ":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Every connection to :memory: is a separate database
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+    return db
+}
+
+func TestMigratorSQLite(t *testing.T) {
+    ctx := context.Background()
+    db := openSQLite(t)
+    fsys := fstest.MapFS{
+        "0001_create_t.up.sql":   {Data: []byte("CREATE TABLE t (a TEXT);")},
+        "0001_create_t.down.sql": {Data: []byte("DROP TABLE t;")},
+        "0002_seed_t.up.sql":     {Data: []byte("INSERT INTO t VALUES ('x;y'); INSERT INTO t VALUES ('z');")},
+        "0002_seed_t.down.sql":   {Data: []byte("DELETE FROM t;")},
+    }
+    migrator, err := New(db, SQLite{}, fsys)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    applied, err := migrator.Up(ctx)
+    if err != nil || len(applied) != 2 {
+        t.Fatalf("Up() = %d migrations, %v, want 2", len(applied), err)
+    }
+    var rows int
+    db.QueryRow(`SELECT COUNT(*) FROM t`).Scan(&rows)
+    if rows != 2 {
+        t.Errorf("Expected 2 seeded rows, got %d", rows)
+    }
+    if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+        t.Errorf("Second Up() = %d migrations, %v, want none", len(applied), err)
+    }
+
+    reverted, err := migrator.Down(ctx, 1)
+    if err != nil || len(reverted) != 1 || reverted[0].Version != 2 {
+        t.Fatalf("Down(1) = %+v, %v, want migration 2", reverted, err)
+    }
+    statuses, err := migrator.Status(ctx)
+    if err != nil || len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+        t.Fatalf("Status() = %+v, %v, want 1 applied and 2 pending", statuses, err)
+    }
+
+    // Editing an applied migration stops further runs
+    fsys["0001_create_t.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE t (a TEXT, b TEXT);")}
+    edited, err := New(db, SQLite{}, fsys)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := edited.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+        t.Errorf("Up() with an edited migration = %v, want ErrChecksumMismatch", err)
+    }
+}
+
+func TestMigratorRollsBackFailedMigration(t *testing.T) {
+    ctx := context.Background()
+    db := openSQLite(t)
+    fsys := fstest.MapFS{
+        "0001_broken.up.sql": {Data: []byte("CREATE TABLE t (a TEXT); INSERT INTO missing VALUES (1);")},
+    }
+    migrator, err := New(db, SQLite{}, fsys)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := migrator.Up(ctx); err == nil {
+        t.Fatal("Up() error = nil, want the failing statement's error")
+    }
+    var tables int
+    db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 't'`).Scan(&tables)
+    if tables != 0 {
+        t.Error("Expected the failed migration to be rolled back")
+    }
+    if statuses, _ := migrator.Status(ctx); len(statuses) != 1 || statuses[0].AppliedAt != nil {
+        t.Errorf("Expected the failed migration to stay pending, got %+v", statuses)
+    }
+}
+
+func TestSQLiteMigrationsFromBaseline(t *testing.T) {
+    ctx := context.Background()
+    db := openSQLite(t)
+    // A database created before migrations existed: the baseline table
+    // with data but no schema_migrations
+    baseline, err := Load(SQLiteMigrations())
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, statement := range SplitStatements(baseline[0].Up) {
+        if _, err := db.Exec(statement); err != nil {
+            t.Fatalf("Baseline statement failed: %v", err)
+        }
+    }
+    if _, err := db.Exec(`INSERT INTO users (id, name, email, password) VALUES ('1', 'Old User', 'old@example.com', 'secret')`); err != nil {
+        t.Fatal(err)
+    }
+
+    migrator, err := New(db, SQLite{}, SQLiteMigrations())
+    if err != nil {
+        t.Fatal(err)
+    }
+    applied, err := migrator.Up(ctx)
+    if err != nil || len(applied) != len(baseline) {
+        t.Fatalf("Up() = %d migrations, %v, want %d", len(applied), err, len(baseline))
+    }
+
+    var role string
+    var version int64
+    var deletedAt sql.NullString
+    err = db.QueryRow(`SELECT role, version, deleted_at FROM users WHERE id = '1'`).Scan(&role, &version, &deletedAt)
+    if err != nil {
+        t.Fatalf("Reading the existing user failed: %v", err)
+    }
+    if role != "user" || version != 1 || deletedAt.Valid {
+        t.Errorf("Existing user has role %q, version %d, deleted_at %v", role, version, deletedAt)
+    }
+    if _, err := db.Exec(`INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) VALUES ('t', '1', 'f', 'h', '2030-01-01 00:00:00+00:00')`); err != nil {
+        t.Errorf("Inserting a refresh token failed: %v", err)
+    }
+    if _, err := db.Exec(`INSERT INTO users (id, name, email, password) VALUES ('2', 'Other', 'OLD@example.com', 'secret')`); err == nil {
+        t.Error("Expected the email constraint to survive the migrations")
+    }
+
+    // Every migration reverts cleanly back to an empty database
+    if _, err := migrator.Down(ctx, len(baseline)); err != nil {
+        t.Fatalf("Down() error = %v", err)
+    }
+    var tables int
+    db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name IN ('users', 'refresh_tokens')`).Scan(&tables)
+    if tables != 0 {
+        t.Errorf("Expected Down() to drop every table, %d left", tables)
+    }
+}
+
+func TestSQLiteConcurrentUp(t *testing.T) {
+    // Separate pools on one file behave like separate processes
+    dsn := "file:" + filepath.Join(t.TempDir(), "race.db") +
+        "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+    const runs = 4
+    results := make(chan int, runs)
+    errs := make(chan error, runs)
+    var wg sync.WaitGroup
+    for i := 0; i < runs; i++ {
+        db, err := sql.Open("sqlite", dsn)
+        if err != nil {
+            t.Fatal(err)
+        }
+        db.SetMaxOpenConns(1)
+        t.Cleanup(func() { db.Close() })
+        migrator, err := New(db, SQLite{}, SQLiteMigrations())
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            applied, err := migrator.Up(context.Background())
+            if err != nil {
+                errs <- err
+                return
+            }
+            results <- len(applied)
+        }()
+    }
+    wg.Wait()
+    close(results)
+    close(errs)
+
+    for err := range errs {
+        t.Errorf("Concurrent Up() error = %v", err)
+    }
+    total := 0
+    for n := range results {
+        total += n
+    }
+    migrations, _ := Load(SQLiteMigrations())
+    if total != len(migrations) {
+        t.Errorf("Concurrent runs applied %d migrations in total, want each of the %d once", total, len(migrations))
+    }
+}
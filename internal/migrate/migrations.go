@@ -0,0 +1,39 @@
+package migrate
+
+import (
+    "embed"
+    "io/fs"
+)
+
+// Each database has its own files, as the SQL differs, but the sets are
+// kept in step: every version exists for every database with the same
+// name, which TestMigrationSetsMatch checks. Column types, index syntax
+// and what ALTER TABLE supports differ too much to share DDL, so every
+// schema change is written out once per database, and a version that
+// has nothing to do on one database is a comment-only file there.
+//
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// MySQLMigrations returns the migrations for the MySQL schema
+func MySQLMigrations() fs.FS {
+    return sub("mysql")
+}
+
+// SQLiteMigrations returns the migrations for the SQLite schema
+func SQLiteMigrations() fs.FS {
+    return sub("sqlite")
+}
+
+// PostgresMigrations returns the migrations for the PostgreSQL schema
+func PostgresMigrations() fs.FS {
+    return sub("postgres")
+}
+
+func sub(dir string) fs.FS {
+    sub, err := fs.Sub(files, dir)
+    if err != nil {
+        panic(err)
+    }
+    return sub
+}
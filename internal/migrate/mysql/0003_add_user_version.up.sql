@@ -0,0 +1,2 @@
+-- Incremented on every write; exposed as the ETag for If-Match
+ALTER TABLE users ADD COLUMN version BIGINT UNSIGNED NOT NULL DEFAULT 1 AFTER role;
@@ -0,0 +1,5 @@
+-- Set when the user is soft-deleted; the row is purged after the
+-- retention period
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP NULL AFTER updated_at,
+    ADD INDEX idx_deleted_at (deleted_at);
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP INDEX idx_created_at_id,
+    DROP INDEX idx_name_id,
+    DROP INDEX uq_users_email,
+    ADD UNIQUE INDEX email (email),
+    ADD INDEX idx_email (email);
@@ -0,0 +1,11 @@
+-- Names the email constraint, which the repository maps to the email
+-- field on conflicts, and replaces the redundant idx_email with the
+-- indexes behind keyset pagination by name and creation time.
+-- Emails are stored lower case; the case-insensitive default collation
+-- keeps the constraint case-insensitive for older rows too.
+ALTER TABLE users
+    DROP INDEX email,
+    DROP INDEX idx_email,
+    ADD CONSTRAINT uq_users_email UNIQUE (email),
+    ADD INDEX idx_name_id (name, id),
+    ADD INDEX idx_created_at_id (created_at, id);
@@ -0,0 +1 @@
+ALTER TABLE users DROP INDEX idx_fulltext_name_email;
@@ -0,0 +1 @@
+ALTER TABLE users ADD FULLTEXT INDEX idx_fulltext_name_email (name, email);
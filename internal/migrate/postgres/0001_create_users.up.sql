@@ -0,0 +1,15 @@
+-- Mirrors the MySQL baseline schema. CITEXT gives name and email the
+-- case-insensitive comparison, ordering and LIKE that MySQL's collation
+-- provides; citext is a trusted extension, so the database owner can
+-- create it.
+CREATE EXTENSION IF NOT EXISTS citext;
+
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(36) PRIMARY KEY,
+    name CITEXT NOT NULL,
+    email CITEXT NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
@@ -0,0 +1,2 @@
+-- Incremented on every write; exposed as the ETag for If-Match
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
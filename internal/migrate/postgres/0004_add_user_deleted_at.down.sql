@@ -0,0 +1,2 @@
+-- Dropping the column drops its index
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+-- Set when the user is soft-deleted; the row is purged after the
+-- retention period
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
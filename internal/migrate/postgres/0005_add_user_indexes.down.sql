@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_created_at_id;
+DROP INDEX IF EXISTS idx_users_name_id;
+
+CREATE INDEX idx_users_email ON users (email);
+
+ALTER TABLE users RENAME CONSTRAINT uq_users_email TO users_email_key;
@@ -0,0 +1,9 @@
+-- Names the email constraint, which the repository maps to the email
+-- field on conflicts, and replaces the redundant email index with the
+-- indexes behind keyset pagination by name and creation time
+ALTER TABLE users RENAME CONSTRAINT users_email_key TO uq_users_email;
+
+DROP INDEX IF EXISTS idx_users_email;
+
+CREATE INDEX idx_users_name_id ON users (name, id);
+CREATE INDEX idx_users_created_at_id ON users (created_at, id);
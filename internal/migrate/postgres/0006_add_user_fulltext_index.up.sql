@@ -0,0 +1,2 @@
+-- PostgreSQL has no FULLTEXT index; search matches with LIKE instead.
+-- The version exists to keep the migration sets in step.
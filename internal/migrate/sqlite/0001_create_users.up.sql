@@ -0,0 +1,13 @@
+-- Mirrors the MySQL baseline schema. NOCASE gives name and email the
+-- case-insensitive comparison and ordering MySQL's collation provides.
+-- Times are UTC text in the format the driver writes, so they sort and
+-- compare correctly as strings.
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL COLLATE NOCASE,
+    email TEXT NOT NULL COLLATE NOCASE UNIQUE,
+    password TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_email ON users (email);
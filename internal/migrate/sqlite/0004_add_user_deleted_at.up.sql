@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX idx_deleted_at ON users (deleted_at);
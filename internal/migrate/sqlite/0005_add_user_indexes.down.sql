@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_created_at_id;
+DROP INDEX IF EXISTS idx_name_id;
+
+CREATE INDEX idx_email ON users (email);
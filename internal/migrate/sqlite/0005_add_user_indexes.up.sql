@@ -0,0 +1,6 @@
+-- The email constraint keeps its automatic name: SQLite cannot rename
+-- it, and reports conflicts by column rather than by constraint
+DROP INDEX IF EXISTS idx_email;
+
+CREATE INDEX idx_name_id ON users (name, id);
+CREATE INDEX idx_created_at_id ON users (created_at, id);
@@ -0,0 +1,20 @@
+package model
+
+// LoginRequest is the body accepted by POST /auth/login
+type LoginRequest struct {
+    Email    string `json:"email" validate:"required"`
+    Password string `json:"password" validate:"required"`
+}
+
+// RefreshRequest is the body accepted by POST /auth/refresh and POST /auth/logout
+type RefreshRequest struct {
+    RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse is returned when credentials are exchanged for tokens
+type TokenResponse struct {
+    AccessToken  string `json:"access_token"`
+    TokenType    string `json:"token_type"`
+    ExpiresIn    int    `json:"expires_in"`
+    RefreshToken string `json:"refresh_token"`
+}
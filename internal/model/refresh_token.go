@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the
+// token is kept; every token issued by rotating another one shares the
+// FamilyID of the login that started the session.
+type RefreshToken struct {
+    ID         string
+    UserID     string
+    FamilyID   string
+    TokenHash  string
+    ExpiresAt  time.Time
+    CreatedAt  time.Time
+    RevokedAt  *time.Time
+    ReplacedBy string
+}
+
+// Revoked reports whether the token has been rotated or explicitly revoked
+func (t RefreshToken) Revoked() bool {
+    return t.RevokedAt != nil
+}
+
+// Expired reports whether the token is past its expiry at now
+func (t RefreshToken) Expired(now time.Time) bool {
+    return !now.Before(t.ExpiresAt)
+}
@@ -1,8 +1,145 @@
 package model
 
+import (
+    "strings"
+    "time"
+)
+
+// User is the stored user record. Handlers decode requests into the
+// *Request types and respond with UserResponse; the password hash is
+// additionally excluded from JSON so it cannot leak if a User is
+// serialized by mistake.
 type User struct {
-    ID       string `json:"id"`
-    Name     string `json:"name"`
-    Email    string `json:"email"`
-    Password string `json:"password,omitempty"`
+    ID        string    `json:"id"`
+    Name      string    `json:"name"`
+    Email     string    `json:"email"`
+    Password  string    `json:"-"`
+    Role      Role      `json:"role"`
+    CreatedAt time.Time `json:"created_at"`
+    // Version increases with every write and is exposed as the ETag
+    Version   int64     `json:"-"`
+    // DeletedAt is set when the user is soft-deleted
+    DeletedAt *time.Time `json:"-"`
+}
+
+// Deleted reports whether the user has been soft-deleted
+func (u User) Deleted() bool {
+    return u.DeletedAt != nil
+}
+
+// Role determines what a user is allowed to do
+type Role string
+
+const (
+    RoleAdmin    Role = "admin"
+    RoleUser     Role = "user"
+    RoleReadOnly Role = "read-only"
+)
+
+// Valid reports whether r is one of the known roles
+func (r Role) Valid() bool {
+    switch r {
+    case RoleAdmin, RoleUser, RoleReadOnly:
+        return true
+    }
+    return false
+}
+
+// NormalizeEmail returns the canonical form emails are stored and looked
+// up in. Uniqueness is case-insensitive, so the canonical form is lower
+// case.
+func NormalizeEmail(email string) string {
+    return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateUserRequest is the body accepted by POST /users
+type CreateUserRequest struct {
+    Name     string `json:"name" validate:"required,max=100,name"`
+    Email    string `json:"email" validate:"required,max=254,email"`
+    Password string `json:"password" validate:"required,min=8,max=128"`
+}
+
+// ToUser builds a new user record from the request. Self-registered
+// users always get the ordinary user role.
+func (r CreateUserRequest) ToUser() User {
+    return User{
+        Name:     r.Name,
+        Email:    NormalizeEmail(r.Email),
+        Password: r.Password,
+        Role:     RoleUser,
+    }
+}
+
+// UpdateUserRequest is the body accepted by PUT /users/{id}. Password
+// is optional and keeps the current one when omitted; role is optional
+// and may only be changed by callers allowed to manage roles.
+type UpdateUserRequest struct {
+    Name     string `json:"name" validate:"required,max=100,name"`
+    Email    string `json:"email" validate:"required,max=254,email"`
+    Password string `json:"password,omitempty" validate:"omitempty,min=8,max=128"`
+    Role     Role   `json:"role,omitempty" validate:"omitempty,oneof=admin user read-only"`
+}
+
+// NewPatchDocument returns the JSON document PATCH /users/{id} applies
+// patches to: the writable fields of u. The password is write-only, so
+// it is absent until a patch adds it. The patched document is decoded
+// and validated as an UpdateUserRequest.
+func NewPatchDocument(u User) UpdateUserRequest {
+    return UpdateUserRequest{
+        Name:  u.Name,
+        Email: u.Email,
+        Role:  u.Role,
+    }
+}
+
+// ToUser builds the replacement record for the user with the given id
+func (r UpdateUserRequest) ToUser(id string) User {
+    return User{
+        ID:       id,
+        Name:     r.Name,
+        Email:    NormalizeEmail(r.Email),
+        Password: r.Password,
+        Role:     r.Role,
+    }
+}
+
+// UserResponse is the public view of a user returned by the API
+type UserResponse struct {
+    ID        string    `json:"id"`
+    Name      string    `json:"name"`
+    Email     string    `json:"email"`
+    Role      Role      `json:"role"`
+    CreatedAt time.Time `json:"created_at"`
+    // DeletedAt is only present on soft-deleted users, which are only
+    // returned to admins asking for them
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewUserResponse(u User) UserResponse {
+    return UserResponse{
+        ID:        u.ID,
+        Name:      u.Name,
+        Email:     u.Email,
+        Role:      u.Role,
+        CreatedAt: u.CreatedAt,
+        DeletedAt: u.DeletedAt,
+    }
+}
+
+func NewUserResponses(users []User) []UserResponse {
+    responses := make([]UserResponse, 0, len(users))
+    for _, u := range users {
+        responses = append(responses, NewUserResponse(u))
+    }
+    return responses
+}
+
+
+// UserSearchResult is one hit returned by GET /users/search. Highlights
+// holds HTML-escaped copies of the matching fields with matches wrapped
+// in <em> tags.
+type UserSearchResult struct {
+    UserResponse
+    Score      float64           `json:"score"`
+    Highlights map[string]string `json:"highlights"`
 }
\ No newline at end of file
@@ -3,8 +3,11 @@ package model
 import (
     "encoding/json"
     "testing"
+    "time"
 )
 
+var testCreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
 func TestUserJSONMarshaling(t *testing.T) {
     tests := []struct {
         name string
@@ -14,22 +17,26 @@ func TestUserJSONMarshaling(t *testing.T) {
         {
             name: "complete user",
             user: User{
-                ID:       "123",
-                Name:     "John Doe",
-                Email:    "john@example.com",
-                Password: "secret",
+                ID:        "123",
+                Name:      "John Doe",
+                Email:     "john@example.com",
+                Password:  "secret",
+                Role:      RoleAdmin,
+                CreatedAt: testCreatedAt,
             },
-            want: `{"id":"123","name":"John Doe","email":"john@example.com","password":"secret"}`,
+            want: `{"id":"123","name":"John Doe","email":"john@example.com","role":"admin","created_at":"2024-01-02T03:04:05Z"}`,
         },
         {
-            name: "user without password in response",
+            name: "user without password",
             user: User{
-                ID:       "456",
-                Name:     "Jane Doe",
-                Email:    "jane@example.com",
-                Password: "",
+                ID:        "456",
+                Name:      "Jane Doe",
+                Email:     "jane@example.com",
+                Password:  "",
+                Role:      RoleUser,
+                CreatedAt: testCreatedAt,
             },
-            want: `{"id":"456","name":"Jane Doe","email":"jane@example.com"}`,
+            want: `{"id":"456","name":"Jane Doe","email":"jane@example.com","role":"user","created_at":"2024-01-02T03:04:05Z"}`,
         },
     }
 
@@ -54,13 +61,12 @@ func TestUserJSONUnmarshaling(t *testing.T) {
         wantErr bool
     }{
         {
-            name: "valid user JSON",
+            name: "password is never decoded into User",
             json: `{"id":"789","name":"Bob Smith","email":"bob@example.com","password":"pass123"}`,
             want: User{
-                ID:       "789",
-                Name:     "Bob Smith",
-                Email:    "bob@example.com",
-                Password: "pass123",
+                ID:    "789",
+                Name:  "Bob Smith",
+                Email: "bob@example.com",
             },
             wantErr: false,
         },
@@ -94,4 +100,62 @@ func TestUserJSONUnmarshaling(t *testing.T) {
             }
         })
     }
+}
+
+func TestCreateUserRequest(t *testing.T) {
+    var req CreateUserRequest
+    err := json.Unmarshal([]byte(`{"name":"Bob","email":"bob@example.com","password":"pass123"}`), &req)
+    if err != nil {
+        t.Fatalf("Failed to unmarshal request: %v", err)
+    }
+
+    want := User{Name: "Bob", Email: "bob@example.com", Password: "pass123", Role: RoleUser}
+    if got := req.ToUser(); got != want {
+        t.Errorf("ToUser() = %+v, want %+v", got, want)
+    }
+}
+
+func TestUpdateUserRequest(t *testing.T) {
+    req := UpdateUserRequest{Name: "Bob", Email: "bob@example.com", Password: "pass123"}
+
+    want := User{ID: "42", Name: "Bob", Email: "bob@example.com", Password: "pass123"}
+    if got := req.ToUser("42"); got != want {
+        t.Errorf("ToUser() = %+v, want %+v", got, want)
+    }
+}
+
+func TestUserResponseOmitsPassword(t *testing.T) {
+    user := User{ID: "1", Name: "Bob", Email: "bob@example.com", Password: "$argon2id$hash", Role: RoleUser, CreatedAt: testCreatedAt}
+
+    got, err := json.Marshal(NewUserResponse(user))
+    if err != nil {
+        t.Fatalf("Failed to marshal response: %v", err)
+    }
+    want := `{"id":"1","name":"Bob","email":"bob@example.com","role":"user","created_at":"2024-01-02T03:04:05Z"}`
+    if string(got) != want {
+        t.Errorf("Got %s, want %s", string(got), want)
+    }
+
+    list, _ := json.Marshal(NewUserResponses([]User{user}))
+    if string(list) != "["+want+"]" {
+        t.Errorf("Got %s, want [%s]", string(list), want)
+    }
+
+    empty, _ := json.Marshal(NewUserResponses(nil))
+    if string(empty) != "[]" {
+        t.Errorf("Expected empty list to encode as [], got %s", string(empty))
+    }
+}
+
+func TestRoleValid(t *testing.T) {
+    for _, role := range []Role{RoleAdmin, RoleUser, RoleReadOnly} {
+        if !role.Valid() {
+            t.Errorf("Expected %q to be valid", role)
+        }
+    }
+    for _, role := range []Role{"", "root", "Admin"} {
+        if role.Valid() {
+            t.Errorf("Expected %q to be invalid", role)
+        }
+    }
 }
\ No newline at end of file
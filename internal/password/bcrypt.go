@@ -0,0 +1,58 @@
+package password
+
+import (
+    "errors"
+    "strings"
+
+    "golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is used for new bcrypt hashes
+const DefaultBcryptCost = 12
+
+// BcryptHasher produces standard $2a$/$2b$ bcrypt hashes. It is kept for
+// compatibility with hashes imported from other systems.
+type BcryptHasher struct {
+    cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+    return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Algorithm() string {
+    return "bcrypt"
+}
+
+func (h *BcryptHasher) Hash(plain string) (string, error) {
+    hash, err := bcrypt.GenerateFromPassword([]byte(plain), h.cost)
+    if err != nil {
+        return "", err
+    }
+    return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(encoded, plain string) (bool, error) {
+    err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plain))
+    if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+        return false, nil
+    }
+    if err != nil {
+        return false, ErrMalformedHash
+    }
+    return true, nil
+}
+
+func (h *BcryptHasher) Recognizes(encoded string) bool {
+    return strings.HasPrefix(encoded, "$2a$") ||
+        strings.HasPrefix(encoded, "$2b$") ||
+        strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+    cost, err := bcrypt.Cost([]byte(encoded))
+    if err != nil {
+        return true
+    }
+    return cost != h.cost
+}
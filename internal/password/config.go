@@ -0,0 +1,86 @@
+package password
+
+import (
+    "errors"
+    "fmt"
+    "math"
+
+    "golang.org/x/crypto/bcrypt"
+)
+
+// Algorithms that Config.Algorithm selects
+const (
+    AlgorithmArgon2id = "argon2id"
+    AlgorithmBcrypt   = "bcrypt"
+)
+
+// Config selects how new passwords are hashed. Hashes made with the other
+// algorithm or older parameters are still verified, and replaced at the
+// user's next login. The yaml, toml and env tags name each setting in
+// configuration files and the environment.
+type Config struct {
+    // Algorithm is argon2id or bcrypt
+    Algorithm string `yaml:"algorithm" toml:"algorithm" env:"PASSWORD_ALGORITHM"`
+    // Argon2idMemory is in KiB
+    Argon2idMemory      int `yaml:"argon2id_memory" toml:"argon2id_memory" env:"PASSWORD_ARGON2ID_MEMORY"`
+    Argon2idIterations  int `yaml:"argon2id_iterations" toml:"argon2id_iterations" env:"PASSWORD_ARGON2ID_ITERATIONS"`
+    Argon2idParallelism int `yaml:"argon2id_parallelism" toml:"argon2id_parallelism" env:"PASSWORD_ARGON2ID_PARALLELISM"`
+    BcryptCost          int `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST"`
+}
+
+// DefaultConfig returns argon2id with DefaultArgon2idParams, as
+// NewDefaultManager uses
+func DefaultConfig() Config {
+    return Config{
+        Algorithm:           AlgorithmArgon2id,
+        Argon2idMemory:      int(DefaultArgon2idParams.Memory),
+        Argon2idIterations:  int(DefaultArgon2idParams.Iterations),
+        Argon2idParallelism: int(DefaultArgon2idParams.Parallelism),
+        BcryptCost:          DefaultBcryptCost,
+    }
+}
+
+// Validate reports an unknown algorithm or parameters out of range
+func (c Config) Validate() error {
+    var errs []error
+    switch c.Algorithm {
+    case AlgorithmArgon2id, AlgorithmBcrypt:
+    default:
+        errs = append(errs, fmt.Errorf("unsupported algorithm %q (want %s or %s)",
+            c.Algorithm, AlgorithmArgon2id, AlgorithmBcrypt))
+    }
+    if c.Argon2idMemory < 8*c.Argon2idParallelism || c.Argon2idMemory > math.MaxUint32 {
+        errs = append(errs, errors.New("argon2id_memory must be at least 8 KiB per thread"))
+    }
+    if c.Argon2idIterations < 1 || c.Argon2idIterations > math.MaxUint32 {
+        errs = append(errs, errors.New("argon2id_iterations must be at least 1"))
+    }
+    if c.Argon2idParallelism < 1 || c.Argon2idParallelism > math.MaxUint8 {
+        errs = append(errs, fmt.Errorf("argon2id_parallelism must be between 1 and %d", math.MaxUint8))
+    }
+    if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+        errs = append(errs, fmt.Errorf("bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+    }
+    return errors.Join(errs...)
+}
+
+// NewConfiguredManager returns a manager hashing with the algorithm and
+// parameters cfg selects, which verifies hashes of the other algorithm too
+func NewConfiguredManager(cfg Config) (*Manager, error) {
+    if err := cfg.Validate(); err != nil {
+        return nil, err
+    }
+
+    argon2id := NewArgon2idHasher(Argon2idParams{
+        Memory:      uint32(cfg.Argon2idMemory),
+        Iterations:  uint32(cfg.Argon2idIterations),
+        Parallelism: uint8(cfg.Argon2idParallelism),
+        SaltLength:  DefaultArgon2idParams.SaltLength,
+        KeyLength:   DefaultArgon2idParams.KeyLength,
+    })
+    bcryptHasher := NewBcryptHasher(cfg.BcryptCost)
+    if cfg.Algorithm == AlgorithmBcrypt {
+        return NewManager(bcryptHasher, argon2id), nil
+    }
+    return NewManager(argon2id, bcryptHasher), nil
+}
@@ -0,0 +1,98 @@
+package password
+
+import (
+    "errors"
+    "strings"
+)
+
+var (
+    // ErrMismatch is returned when a password does not match its hash
+    ErrMismatch = errors.New("password: hash does not match password")
+    // ErrUnknownAlgorithm is returned for encoded hashes no registered hasher understands
+    ErrUnknownAlgorithm = errors.New("password: unknown hash algorithm")
+    // ErrMalformedHash is returned when an encoded hash cannot be parsed
+    ErrMalformedHash = errors.New("password: malformed hash")
+)
+
+// Hasher hashes and verifies passwords for a single algorithm.
+// Encoded hashes carry their own parameters so they can be verified
+// after the configured parameters change.
+type Hasher interface {
+    // Algorithm returns the identifier used in encoded hashes (e.g. "argon2id")
+    Algorithm() string
+    // Hash returns the encoded hash of the plaintext password
+    Hash(plain string) (string, error)
+    // Verify reports whether plain matches the encoded hash
+    Verify(encoded, plain string) (bool, error)
+    // Recognizes reports whether the encoded hash was produced by this algorithm
+    Recognizes(encoded string) bool
+    // NeedsRehash reports whether the encoded hash uses outdated parameters
+    NeedsRehash(encoded string) bool
+}
+
+// Manager hashes new passwords with the current hasher and verifies
+// existing hashes with whichever registered hasher produced them.
+type Manager struct {
+    current Hasher
+    hashers []Hasher
+}
+
+// NewManager creates a manager hashing with current and also accepting
+// hashes produced by any of the legacy hashers.
+func NewManager(current Hasher, legacy ...Hasher) *Manager {
+    return &Manager{
+        current: current,
+        hashers: append([]Hasher{current}, legacy...),
+    }
+}
+
+// NewDefaultManager returns a manager using argon2id with default
+// parameters that still verifies bcrypt hashes.
+func NewDefaultManager() *Manager {
+    return NewManager(NewArgon2idHasher(DefaultArgon2idParams), NewBcryptHasher(DefaultBcryptCost))
+}
+
+// Hash hashes plain with the current hasher
+func (m *Manager) Hash(plain string) (string, error) {
+    return m.current.Hash(plain)
+}
+
+// Verify checks plain against encoded. When the password matches but the
+// hash was produced by a different algorithm or with outdated parameters,
+// rehashed contains a fresh hash that the caller should persist.
+func (m *Manager) Verify(encoded, plain string) (rehashed string, err error) {
+    h := m.hasherFor(encoded)
+    if h == nil {
+        return "", ErrUnknownAlgorithm
+    }
+
+    ok, err := h.Verify(encoded, plain)
+    if err != nil {
+        return "", err
+    }
+    if !ok {
+        return "", ErrMismatch
+    }
+
+    if h != m.current || m.current.NeedsRehash(encoded) {
+        return m.current.Hash(plain)
+    }
+    return "", nil
+}
+
+// IsHashed reports whether s is an encoded hash understood by the manager
+func (m *Manager) IsHashed(s string) bool {
+    return m.hasherFor(s) != nil
+}
+
+func (m *Manager) hasherFor(encoded string) Hasher {
+    if !strings.HasPrefix(encoded, "$") {
+        return nil
+    }
+    for _, h := range m.hashers {
+        if h.Recognizes(encoded) {
+            return h
+        }
+    }
+    return nil
+}
@@ -0,0 +1,214 @@
+package password
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+// testArgon2idParams keeps the tests fast
+var testArgon2idParams = Argon2idParams{
+    Memory:      1024,
+    Iterations:  1,
+    Parallelism: 1,
+    SaltLength:  16,
+    KeyLength:   32,
+}
+
+func TestArgon2idHasher_HashAndVerify(t *testing.T) {
+    h := NewArgon2idHasher(testArgon2idParams)
+
+    encoded, err := h.Hash("secret123")
+    if err != nil {
+        t.Fatalf("Hash returned error: %v", err)
+    }
+    if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+        t.Errorf("Unexpected encoding: %s", encoded)
+    }
+
+    other, _ := h.Hash("secret123")
+    if other == encoded {
+        t.Error("Expected different salts to produce different hashes")
+    }
+
+    tests := []struct {
+        name  string
+        plain string
+        want  bool
+    }{
+        {name: "correct password", plain: "secret123", want: true},
+        {name: "wrong password", plain: "secret124", want: false},
+        {name: "empty password", plain: "", want: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ok, err := h.Verify(encoded, tt.plain)
+            if err != nil {
+                t.Fatalf("Verify returned error: %v", err)
+            }
+            if ok != tt.want {
+                t.Errorf("Verify() = %v, want %v", ok, tt.want)
+            }
+        })
+    }
+}
+
+func TestArgon2idHasher_VerifyMalformed(t *testing.T) {
+    h := NewArgon2idHasher(testArgon2idParams)
+
+    for _, encoded := range []string{
+        "$argon2id$",
+        "$argon2id$v=19$m=1024,t=1,p=1$salt",
+        "$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+        "$argon2id$v=19$bogus$c2FsdA$a2V5",
+        "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+    } {
+        if _, err := h.Verify(encoded, "secret"); !errors.Is(err, ErrMalformedHash) {
+            t.Errorf("Verify(%q) error = %v, want ErrMalformedHash", encoded, err)
+        }
+    }
+}
+
+func TestArgon2idHasher_NeedsRehash(t *testing.T) {
+    h := NewArgon2idHasher(testArgon2idParams)
+    encoded, _ := h.Hash("secret123")
+
+    if h.NeedsRehash(encoded) {
+        t.Error("Hash with current parameters should not need rehash")
+    }
+
+    stronger := testArgon2idParams
+    stronger.Iterations = 2
+    if !NewArgon2idHasher(stronger).NeedsRehash(encoded) {
+        t.Error("Hash with outdated parameters should need rehash")
+    }
+}
+
+func TestBcryptHasher(t *testing.T) {
+    h := NewBcryptHasher(4)
+
+    encoded, err := h.Hash("secret123")
+    if err != nil {
+        t.Fatalf("Hash returned error: %v", err)
+    }
+    if !h.Recognizes(encoded) {
+        t.Errorf("Hasher does not recognize its own hash %s", encoded)
+    }
+
+    if ok, _ := h.Verify(encoded, "secret123"); !ok {
+        t.Error("Expected correct password to verify")
+    }
+    if ok, _ := h.Verify(encoded, "wrong"); ok {
+        t.Error("Expected wrong password not to verify")
+    }
+
+    if h.NeedsRehash(encoded) {
+        t.Error("Hash with current cost should not need rehash")
+    }
+    if !NewBcryptHasher(5).NeedsRehash(encoded) {
+        t.Error("Hash with different cost should need rehash")
+    }
+}
+
+func TestManager_Verify(t *testing.T) {
+    argon := NewArgon2idHasher(testArgon2idParams)
+    bcryptHasher := NewBcryptHasher(4)
+    m := NewManager(argon, bcryptHasher)
+
+    current, _ := m.Hash("secret123")
+    legacy, _ := bcryptHasher.Hash("secret123")
+
+    stronger := testArgon2idParams
+    stronger.Memory = 2048
+    outdated, _ := NewArgon2idHasher(stronger).Hash("secret123")
+
+    tests := []struct {
+        name        string
+        encoded     string
+        plain       string
+        wantErr     error
+        wantRehash  bool
+    }{
+        {name: "current hash", encoded: current, plain: "secret123"},
+        {name: "wrong password", encoded: current, plain: "nope", wantErr: ErrMismatch},
+        {name: "legacy bcrypt hash", encoded: legacy, plain: "secret123", wantRehash: true},
+        {name: "outdated parameters", encoded: outdated, plain: "secret123", wantRehash: true},
+        {name: "plaintext", encoded: "secret123", plain: "secret123", wantErr: ErrUnknownAlgorithm},
+        {name: "unknown algorithm", encoded: "$scrypt$abc", plain: "secret123", wantErr: ErrUnknownAlgorithm},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rehashed, err := m.Verify(tt.encoded, tt.plain)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+            }
+            if (rehashed != "") != tt.wantRehash {
+                t.Errorf("Verify() rehashed = %q, wantRehash %v", rehashed, tt.wantRehash)
+            }
+            if rehashed != "" {
+                if argon.NeedsRehash(rehashed) {
+                    t.Error("Rehashed password should use current parameters")
+                }
+                if _, err := m.Verify(rehashed, tt.plain); err != nil {
+                    t.Errorf("Rehashed password does not verify: %v", err)
+                }
+            }
+        })
+    }
+}
+
+func TestManager_IsHashed(t *testing.T) {
+    m := NewManager(NewArgon2idHasher(testArgon2idParams), NewBcryptHasher(4))
+    hash, _ := m.Hash("secret")
+
+    tests := []struct {
+        value string
+        want  bool
+    }{
+        {hash, true},
+        {"$2b$04$abcdefghijklmnopqrstuv", true},
+        {"admin123", false},
+        {"", false},
+    }
+
+    for _, tt := range tests {
+        if got := m.IsHashed(tt.value); got != tt.want {
+            t.Errorf("IsHashed(%q) = %v, want %v", tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestNewConfiguredManager(t *testing.T) {
+    cfg := DefaultConfig()
+    cfg.Argon2idMemory = 1024
+    cfg.Argon2idParallelism = 1
+    argon2id, err := NewConfiguredManager(cfg)
+    if err != nil {
+        t.Fatalf("NewConfiguredManager(argon2id) error = %v", err)
+    }
+    encoded, _ := argon2id.Hash("secret123")
+    if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+        t.Errorf("Unexpected encoding: %s", encoded)
+    }
+
+    // Switching algorithm still verifies old hashes and upgrades them
+    cfg.Algorithm = AlgorithmBcrypt
+    cfg.BcryptCost = 4
+    bcryptManager, err := NewConfiguredManager(cfg)
+    if err != nil {
+        t.Fatalf("NewConfiguredManager(bcrypt) error = %v", err)
+    }
+    rehashed, err := bcryptManager.Verify(encoded, "secret123")
+    if err != nil || !strings.HasPrefix(rehashed, "$2a$04$") {
+        t.Errorf("Verify() = %q, %v, want a bcrypt rehash", rehashed, err)
+    }
+
+    invalid := DefaultConfig()
+    invalid.Algorithm = "md5"
+    invalid.BcryptCost = 99
+    if _, err := NewConfiguredManager(invalid); err == nil || !strings.Contains(err.Error(), "md5") || !strings.Contains(err.Error(), "bcrypt_cost") {
+        t.Errorf("NewConfiguredManager() error = %v, want both invalid settings reported", err)
+    }
+}
@@ -0,0 +1,347 @@
+// Package patch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values
+package patch
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "mime"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// Media types accepted by Apply
+const (
+    MediaTypeMergePatch = "application/merge-patch+json"
+    MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+    // ErrUnsupportedMediaType is returned by Apply for any other media type
+    ErrUnsupportedMediaType = errors.New("unsupported patch media type")
+    // ErrInvalidPatch means the patch document itself is malformed
+    ErrInvalidPatch = errors.New("invalid patch document")
+    // ErrUnprocessable means a well-formed JSON Patch cannot be applied to
+    // the document, e.g. because a path does not exist
+    ErrUnprocessable = errors.New("patch cannot be applied")
+    // ErrTestFailed means a JSON Patch "test" operation did not match
+    ErrTestFailed = errors.New("patch test failed")
+)
+
+// Apply patches doc according to contentType, the request's Content-Type
+func Apply(contentType string, doc, patch []byte) ([]byte, error) {
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil {
+        return nil, ErrUnsupportedMediaType
+    }
+    switch mediaType {
+    case MediaTypeMergePatch:
+        return MergePatch(doc, patch)
+    case MediaTypeJSONPatch:
+        return JSONPatch(doc, patch)
+    default:
+        return nil, ErrUnsupportedMediaType
+    }
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc: object members in
+// the patch replace those in doc, recursively, and null members remove
+// them. Any other patch value replaces doc entirely.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+    var target, p interface{}
+    if err := json.Unmarshal(doc, &target); err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(patch, &p); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+    return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+    p, ok := patch.(map[string]interface{})
+    if !ok {
+        return patch
+    }
+    t, ok := target.(map[string]interface{})
+    if !ok {
+        t = map[string]interface{}{}
+    }
+    for key, value := range p {
+        if value == nil {
+            delete(t, key)
+        } else {
+            t[key] = mergePatch(t[key], value)
+        }
+    }
+    return t
+}
+
+// operation is one entry of a JSON Patch document. Value stays raw so a
+// missing value can be told apart from an explicit null.
+type operation struct {
+    Op    string          `json:"op"`
+    Path  *string         `json:"path"`
+    From  *string         `json:"from"`
+    Value json.RawMessage `json:"value"`
+}
+
+// JSONPatch applies an RFC 6902 patch to doc. Operations are applied in
+// order and the patch is atomic: any failing operation fails the whole
+// patch.
+func JSONPatch(doc, patch []byte) ([]byte, error) {
+    var target interface{}
+    if err := json.Unmarshal(doc, &target); err != nil {
+        return nil, err
+    }
+    var ops []operation
+    if err := json.Unmarshal(patch, &ops); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+    }
+
+    for i, op := range ops {
+        var err error
+        target, err = apply(target, op)
+        if err != nil {
+            return nil, fmt.Errorf("operation %d (%s): %w", i, op.Op, err)
+        }
+    }
+    return json.Marshal(target)
+}
+
+func apply(doc interface{}, op operation) (interface{}, error) {
+    if op.Path == nil {
+        return nil, fmt.Errorf("%w: missing path", ErrInvalidPatch)
+    }
+    path, err := parsePointer(*op.Path)
+    if err != nil {
+        return nil, err
+    }
+
+    switch op.Op {
+    case "add", "replace", "test":
+        if len(op.Value) == 0 {
+            return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+        }
+        var value interface{}
+        if err := json.Unmarshal(op.Value, &value); err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+        }
+        switch op.Op {
+        case "add":
+            return add(doc, path, value)
+        case "replace":
+            if len(path) == 0 {
+                return value, nil
+            }
+            if doc, _, err = remove(doc, path); err != nil {
+                return nil, err
+            }
+            return add(doc, path, value)
+        default:
+            current, err := get(doc, path)
+            if err != nil {
+                return nil, err
+            }
+            if !reflect.DeepEqual(current, value) {
+                return nil, fmt.Errorf("%w at %q", ErrTestFailed, *op.Path)
+            }
+            return doc, nil
+        }
+
+    case "remove":
+        doc, _, err = remove(doc, path)
+        return doc, err
+
+    case "move", "copy":
+        if op.From == nil {
+            return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+        }
+        from, err := parsePointer(*op.From)
+        if err != nil {
+            return nil, err
+        }
+        var value interface{}
+        if op.Op == "move" {
+            if isPrefix(from, path) && len(from) < len(path) {
+                return nil, fmt.Errorf("%w: cannot move %q into itself", ErrUnprocessable, *op.From)
+            }
+            if doc, value, err = remove(doc, from); err != nil {
+                return nil, err
+            }
+        } else {
+            if value, err = get(doc, from); err != nil {
+                return nil, err
+            }
+            value = deepCopy(value)
+        }
+        return add(doc, path, value)
+
+    default:
+        return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+    }
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+    if pointer == "" {
+        return nil, nil
+    }
+    if !strings.HasPrefix(pointer, "/") {
+        return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+    }
+    tokens := strings.Split(pointer[1:], "/")
+    for i, t := range tokens {
+        tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+    }
+    return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+    if len(prefix) > len(path) {
+        return false
+    }
+    for i := range prefix {
+        if prefix[i] != path[i] {
+            return false
+        }
+    }
+    return true
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+    for _, token := range path {
+        switch n := node.(type) {
+        case map[string]interface{}:
+            child, ok := n[token]
+            if !ok {
+                return nil, fmt.Errorf("%w: %q does not exist", ErrUnprocessable, token)
+            }
+            node = child
+        case []interface{}:
+            i, err := arrayIndex(token, len(n)-1)
+            if err != nil {
+                return nil, err
+            }
+            node = n[i]
+        default:
+            return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrUnprocessable, token)
+        }
+    }
+    return node, nil
+}
+
+// arrayIndex parses an array index token, which must lie in [0, max]
+func arrayIndex(token string, max int) (int, error) {
+    i, err := strconv.Atoi(token)
+    if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+        return 0, fmt.Errorf("%w: invalid array index %q", ErrUnprocessable, token)
+    }
+    return i, nil
+}
+
+// update replaces the container holding the last token of path with the
+// result of fn, and returns the updated node. Objects are modified in
+// place; arrays may be reallocated, so the new slice is stored in the
+// parent on the way back up.
+func update(node interface{}, path []string, fn func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+    if len(path) == 1 {
+        return fn(node, path[0])
+    }
+
+    child, err := get(node, path[:1])
+    if err != nil {
+        return nil, err
+    }
+    child, err = update(child, path[1:], fn)
+    if err != nil {
+        return nil, err
+    }
+
+    switch n := node.(type) {
+    case map[string]interface{}:
+        n[path[0]] = child
+    case []interface{}:
+        i, _ := strconv.Atoi(path[0])
+        n[i] = child
+    }
+    return node, nil
+}
+
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+    if len(path) == 0 {
+        return value, nil
+    }
+    return update(doc, path, func(container interface{}, token string) (interface{}, error) {
+        switch c := container.(type) {
+        case map[string]interface{}:
+            c[token] = value
+            return c, nil
+        case []interface{}:
+            i := len(c)
+            if token != "-" {
+                var err error
+                if i, err = arrayIndex(token, len(c)); err != nil {
+                    return nil, err
+                }
+            }
+            c = append(c, nil)
+            copy(c[i+1:], c[i:])
+            c[i] = value
+            return c, nil
+        default:
+            return nil, fmt.Errorf("%w: cannot add %q to a scalar", ErrUnprocessable, token)
+        }
+    })
+}
+
+// remove deletes the value at path and returns it
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+    if len(path) == 0 {
+        return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrUnprocessable)
+    }
+    var removed interface{}
+    doc, err := update(doc, path, func(container interface{}, token string) (interface{}, error) {
+        switch c := container.(type) {
+        case map[string]interface{}:
+            value, ok := c[token]
+            if !ok {
+                return nil, fmt.Errorf("%w: %q does not exist", ErrUnprocessable, token)
+            }
+            removed = value
+            delete(c, token)
+            return c, nil
+        case []interface{}:
+            i, err := arrayIndex(token, len(c)-1)
+            if err != nil {
+                return nil, err
+            }
+            removed = c[i]
+            return append(c[:i:i], c[i+1:]...), nil
+        default:
+            return nil, fmt.Errorf("%w: cannot remove %q from a scalar", ErrUnprocessable, token)
+        }
+    })
+    return doc, removed, err
+}
+
+func deepCopy(value interface{}) interface{} {
+    switch v := value.(type) {
+    case map[string]interface{}:
+        c := make(map[string]interface{}, len(v))
+        for key, child := range v {
+            c[key] = deepCopy(child)
+        }
+        return c
+    case []interface{}:
+        c := make([]interface{}, len(v))
+        for i, child := range v {
+            c[i] = deepCopy(child)
+        }
+        return c
+    default:
+        return v
+    }
+}
@@ -0,0 +1,103 @@
+package patch
+
+import (
+    "encoding/json"
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+    t.Helper()
+    var g, w interface{}
+    if err := json.Unmarshal(got, &g); err != nil {
+        t.Fatalf("Invalid JSON %s: %v", got, err)
+    }
+    json.Unmarshal([]byte(want), &w)
+    if !reflect.DeepEqual(g, w) {
+        t.Errorf("Got %s, want %s", got, want)
+    }
+}
+
+// Examples from RFC 7396 appendix A
+func TestMergePatch(t *testing.T) {
+    tests := []struct {
+        doc, patch, want string
+    }{
+        {`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+        {`{"a":"b"}`, `{"a":null}`, `{}`},
+        {`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+        {`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+        {`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+        {`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+        {`["a","b"]`, `["c","d"]`, `["c","d"]`},
+        {`{"a":"foo"}`, `"bar"`, `"bar"`},
+        {`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+        {`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+    }
+    for _, tt := range tests {
+        got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+        if err != nil {
+            t.Fatalf("MergePatch(%s, %s) error: %v", tt.doc, tt.patch, err)
+        }
+        assertJSONEqual(t, got, tt.want)
+    }
+}
+
+// Mostly examples from RFC 6902 appendix A
+func TestJSONPatch(t *testing.T) {
+    tests := []struct {
+        name, doc, patch, want string
+        wantErr                error
+    }{
+        {"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+        {"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+        {"append", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`, nil},
+        {"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+        {"remove element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+        {"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, nil},
+        {"move", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+        {"move element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+        {"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, nil},
+        {"test success", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, nil},
+        {"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`, nil},
+        {"add null value", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":null}]`, `{"foo":"bar","child":null}`, nil},
+        {"replace root", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`, nil},
+        {"test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", ErrTestFailed},
+        {"missing target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", ErrUnprocessable},
+        {"remove missing", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, "", ErrUnprocessable},
+        {"index out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/5","value":1}]`, "", ErrUnprocessable},
+        {"move into child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, "", ErrUnprocessable},
+        {"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, "", ErrInvalidPatch},
+        {"missing value", `{}`, `[{"op":"add","path":"/a"}]`, "", ErrInvalidPatch},
+        {"not an array", `{}`, `{"op":"add","path":"/a","value":1}`, "", ErrInvalidPatch},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+            if tt.wantErr != nil {
+                if !errors.Is(err, tt.wantErr) {
+                    t.Fatalf("JSONPatch() error = %v, want %v", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("JSONPatch() error: %v", err)
+            }
+            assertJSONEqual(t, got, tt.want)
+        })
+    }
+}
+
+func TestApply(t *testing.T) {
+    doc := []byte(`{"name":"a"}`)
+    if got, err := Apply("application/merge-patch+json; charset=utf-8", doc, []byte(`{"name":"b"}`)); err != nil {
+        t.Errorf("Apply(merge patch) error: %v", err)
+    } else {
+        assertJSONEqual(t, got, `{"name":"b"}`)
+    }
+    if _, err := Apply("application/json", doc, []byte(`{}`)); !errors.Is(err, ErrUnsupportedMediaType) {
+        t.Errorf("Apply(application/json) error = %v, want ErrUnsupportedMediaType", err)
+    }
+}
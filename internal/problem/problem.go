@@ -0,0 +1,100 @@
+// Package problem writes RFC 7807 "problem details" error responses:
+// application/problem+json bodies with a type, title, status, detail,
+// instance, the request ID, and per-field errors for invalid input.
+package problem
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "go-crud-api/internal/requestid"
+)
+
+// ContentType is the media type of every error response
+const ContentType = "application/problem+json"
+
+// Problem types. Errors that are fully described by their HTTP status use
+// TypeBlank, whose title is the status text (RFC 7807 section 4.2).
+const (
+    TypeBlank        = "about:blank"
+    TypeValidation   = "/problems/validation-error"
+    TypeConflict     = "/problems/conflict"
+    TypeUnavailable  = "/problems/service-unavailable"
+    TypeInvalidToken = "/problems/invalid-token"
+)
+
+// FieldError describes why a single request field or query parameter was
+// rejected
+type FieldError struct {
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object. RequestID and Errors are
+// extension members.
+type Problem struct {
+    Type      string       `json:"type"`
+    Title     string       `json:"title"`
+    Status    int          `json:"status"`
+    Detail    string       `json:"detail,omitempty"`
+    Instance  string       `json:"instance,omitempty"`
+    RequestID string       `json:"request_id,omitempty"`
+    Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// New returns an about:blank problem for status
+func New(status int, detail string) *Problem {
+    return &Problem{
+        Type:   TypeBlank,
+        Title:  http.StatusText(status),
+        Status: status,
+        Detail: detail,
+    }
+}
+
+// Typed returns a problem with its own type URI and title
+func Typed(typ, title string, status int, detail string) *Problem {
+    return &Problem{
+        Type:   typ,
+        Title:  title,
+        Status: status,
+        Detail: detail,
+    }
+}
+
+// Validation returns a 400 problem listing the rejected fields
+func Validation(errs ...FieldError) *Problem {
+    p := Typed(TypeValidation, "Validation failed", http.StatusBadRequest,
+        "One or more fields are invalid")
+    p.Errors = errs
+    return p
+}
+
+// Write sends p as the response. Instance defaults to the request path and
+// RequestID to the ID stored in the request context.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+    if p.Instance == "" {
+        p.Instance = r.URL.Path
+    }
+    if p.RequestID == "" {
+        p.RequestID = requestid.FromContext(r.Context())
+    }
+
+    w.Header().Set("Content-Type", ContentType)
+    w.Header().Set("X-Content-Type-Options", "nosniff")
+    w.WriteHeader(p.Status)
+    json.NewEncoder(w).Encode(p)
+}
+
+// Error is the problem+json counterpart of http.Error
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+    Write(w, r, New(status, detail))
+}
+
+// StatusHandler answers every request with an about:blank problem for
+// status, e.g. for the router's not found and method not allowed cases
+func StatusHandler(status int) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        Error(w, r, status, "")
+    })
+}
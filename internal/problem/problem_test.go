@@ -0,0 +1,64 @@
+package problem
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "go-crud-api/internal/requestid"
+)
+
+func TestWrite(t *testing.T) {
+    req := httptest.NewRequest(http.MethodGet, "/users/u-1?x=1", nil)
+    req = req.WithContext(requestid.WithRequestID(req.Context(), "req-123"))
+    rr := httptest.NewRecorder()
+
+    Error(rr, req, http.StatusNotFound, "User not found")
+
+    if rr.Code != http.StatusNotFound {
+        t.Errorf("Expected status %d, got %d", http.StatusNotFound, rr.Code)
+    }
+    if ct := rr.Header().Get("Content-Type"); ct != ContentType {
+        t.Errorf("Expected Content-Type %q, got %q", ContentType, ct)
+    }
+
+    var got Problem
+    if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+        t.Fatalf("Failed to decode problem: %v", err)
+    }
+    want := Problem{
+        Type:      TypeBlank,
+        Title:     "Not Found",
+        Status:    http.StatusNotFound,
+        Detail:    "User not found",
+        Instance:  "/users/u-1",
+        RequestID: "req-123",
+    }
+    if got.Type != want.Type || got.Title != want.Title || got.Status != want.Status ||
+        got.Detail != want.Detail || got.Instance != want.Instance || got.RequestID != want.RequestID {
+        t.Errorf("Problem = %+v, want %+v", got, want)
+    }
+}
+
+func TestValidation(t *testing.T) {
+    req := httptest.NewRequest(http.MethodGet, "/users", nil)
+    rr := httptest.NewRecorder()
+
+    Write(rr, req, Validation(FieldError{Field: "limit", Message: "must be between 1 and 100"}))
+
+    var body map[string]interface{}
+    if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+        t.Fatalf("Failed to decode problem: %v", err)
+    }
+    if body["type"] != TypeValidation || body["status"] != float64(http.StatusBadRequest) {
+        t.Errorf("Unexpected problem %v", body)
+    }
+    errs, ok := body["errors"].([]interface{})
+    if !ok || len(errs) != 1 || errs[0].(map[string]interface{})["field"] != "limit" {
+        t.Errorf("Expected one field error for limit, got %v", body["errors"])
+    }
+    if _, ok := body["request_id"]; ok {
+        t.Error("request_id should be omitted without a request ID")
+    }
+}
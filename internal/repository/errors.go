@@ -0,0 +1,224 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "net"
+    "strings"
+
+    "github.com/go-sql-driver/mysql"
+    "github.com/jackc/pgx/v5/pgconn"
+    "modernc.org/sqlite"
+    sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Repository error taxonomy. Implementations return errors that match one
+// of these with errors.Is, so callers never need to know which database
+// sits behind the interface.
+var (
+    // ErrNotFound means the requested record does not exist
+    ErrNotFound = errors.New("not found")
+    // ErrConflict means the write violates a uniqueness or integrity constraint
+    ErrConflict = errors.New("conflict")
+    // ErrStale means a conditional write expected a version the record no
+    // longer has, because it changed since it was read
+    ErrStale = errors.New("stale version")
+    // ErrUnavailable means the database could not be reached, timed out or
+    // the caller's context was cancelled; the operation may succeed if retried
+    ErrUnavailable = errors.New("database unavailable")
+)
+
+// MySQL server error codes mapped by mapMySQLError
+const (
+    mysqlErrDuplicateEntry   = 1062
+    mysqlErrNoReferencedRow  = 1452
+    mysqlErrTooManyConns     = 1040
+    mysqlErrLockWaitTimeout  = 1205
+    mysqlErrDeadlock         = 1213
+    mysqlErrQueryInterrupted = 1317
+    mysqlErrMaxExecutionTime = 3024
+)
+
+// PostgreSQL SQLSTATE codes mapped by mapPostgresError
+const (
+    pgUniqueViolation      = "23505"
+    pgForeignKeyViolation  = "23503"
+    pgSerializationFailure = "40001"
+    pgDeadlockDetected     = "40P01"
+    pgTooManyConnections   = "53300"
+    pgLockNotAvailable     = "55P03"
+    pgQueryCanceled        = "57014"
+)
+
+// Error is returned by the repositories. Kind is one of the taxonomy
+// errors (or nil when the failure is not classified) and Err is the
+// underlying driver error; errors.Is and errors.As see both. Field names
+// the conflicting field of an ErrConflict when it is known.
+type Error struct {
+    Op    string
+    Kind  error
+    Field string
+    Err   error
+}
+
+func (e *Error) Error() string {
+    msg := "repository: " + e.Op
+    if e.Kind != nil {
+        msg += ": " + e.Kind.Error()
+    }
+    if e.Field != "" {
+        msg += " on " + e.Field
+    }
+    if e.Err != nil {
+        msg += ": " + e.Err.Error()
+    }
+    return msg
+}
+
+func (e *Error) Unwrap() []error {
+    var errs []error
+    if e.Kind != nil {
+        errs = append(errs, e.Kind)
+    }
+    if e.Err != nil {
+        errs = append(errs, e.Err)
+    }
+    return errs
+}
+
+// ConflictField returns the field an ErrConflict was reported for, or ""
+func ConflictField(err error) string {
+    var repoErr *Error
+    if errors.As(err, &repoErr) && errors.Is(repoErr.Kind, ErrConflict) {
+        return repoErr.Field
+    }
+    return ""
+}
+
+// wrapError classifies a database/sql error returned by operation op
+func wrapError(op string, err error) error {
+    if err == nil {
+        return nil
+    }
+    return &Error{Op: op, Kind: classify(err), Err: err}
+}
+
+func classify(err error) error {
+    var mysqlErr *mysql.MySQLError
+    var sqliteErr *sqlite.Error
+    var pgErr *pgconn.PgError
+    var netErr net.Error
+
+    switch {
+    case errors.Is(err, sql.ErrNoRows):
+        return ErrNotFound
+    case errors.As(err, &mysqlErr):
+        return mapMySQLError(mysqlErr)
+    case errors.As(err, &sqliteErr):
+        return mapSQLiteError(sqliteErr)
+    case errors.As(err, &pgErr):
+        return mapPostgresError(pgErr)
+    case errors.Is(err, context.DeadlineExceeded),
+        errors.Is(err, context.Canceled),
+        errors.Is(err, driver.ErrBadConn),
+        errors.Is(err, mysql.ErrInvalidConn),
+        errors.Is(err, sql.ErrConnDone),
+        errors.As(err, &netErr):
+        return ErrUnavailable
+    default:
+        return nil
+    }
+}
+
+func mapMySQLError(err *mysql.MySQLError) error {
+    switch err.Number {
+    case mysqlErrDuplicateEntry, mysqlErrNoReferencedRow:
+        return ErrConflict
+    case mysqlErrTooManyConns, mysqlErrLockWaitTimeout, mysqlErrDeadlock,
+        mysqlErrQueryInterrupted, mysqlErrMaxExecutionTime:
+        return ErrUnavailable
+    default:
+        return nil
+    }
+}
+
+// mapSQLiteError classifies by the primary result code, the low byte of
+// the extended code the driver reports
+func mapSQLiteError(err *sqlite.Error) error {
+    switch err.Code() & 0xff {
+    case sqlite3.SQLITE_CONSTRAINT:
+        return ErrConflict
+    case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+        return ErrUnavailable
+    default:
+        return nil
+    }
+}
+
+func mapPostgresError(err *pgconn.PgError) error {
+    switch err.Code {
+    case pgUniqueViolation, pgForeignKeyViolation:
+        return ErrConflict
+    case pgSerializationFailure, pgDeadlockDetected, pgTooManyConnections,
+        pgLockNotAvailable, pgQueryCanceled:
+        return ErrUnavailable
+    default:
+        return nil
+    }
+}
+
+// duplicateKeyName extracts the index name from a duplicate entry error,
+// e.g. "uq_users_email" from "Duplicate entry 'a@b.c' for key
+// 'users.uq_users_email'". MySQL before 8.0 omits the table prefix.
+// SQLite names the constrained column instead, e.g. "users.email" from
+// "UNIQUE constraint failed: users.email". PostgreSQL reports the
+// constraint name, e.g. "users_pkey".
+func duplicateKeyName(err error) string {
+    var sqliteErr *sqlite.Error
+    if errors.As(err, &sqliteErr) {
+        return sqliteUniqueColumn(sqliteErr)
+    }
+    var pgErr *pgconn.PgError
+    if errors.As(err, &pgErr) {
+        if pgErr.Code != pgUniqueViolation {
+            return ""
+        }
+        return pgErr.ConstraintName
+    }
+
+    var mysqlErr *mysql.MySQLError
+    if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlErrDuplicateEntry {
+        return ""
+    }
+    _, key, found := strings.Cut(mysqlErr.Message, "for key '")
+    if !found {
+        return ""
+    }
+    key = strings.TrimSuffix(key, "'")
+    if i := strings.LastIndex(key, "."); i >= 0 {
+        key = key[i+1:]
+    }
+    return key
+}
+
+func sqliteUniqueColumn(err *sqlite.Error) string {
+    code := err.Code()
+    if code != sqlite3.SQLITE_CONSTRAINT_UNIQUE && code != sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+        return ""
+    }
+    // The driver may prefix SQLite's message with its own description
+    const marker = "constraint failed: "
+    msg := err.Error()
+    i := strings.LastIndex(msg, marker)
+    if i < 0 {
+        return ""
+    }
+    // Composite keys list every column; the first one names the key
+    column := msg[i+len(marker):]
+    if j := strings.IndexAny(column, ", "); j >= 0 {
+        column = column[:j]
+    }
+    return column
+}
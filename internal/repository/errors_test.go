@@ -0,0 +1,93 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "fmt"
+    "testing"
+
+    "github.com/go-sql-driver/mysql"
+    "github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWrapError(t *testing.T) {
+    tests := []struct {
+        name     string
+        err      error
+        wantKind error
+    }{
+        {name: "no rows", err: sql.ErrNoRows, wantKind: ErrNotFound},
+        {name: "duplicate entry", err: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, wantKind: ErrConflict},
+        {name: "foreign key", err: &mysql.MySQLError{Number: 1452}, wantKind: ErrConflict},
+        {name: "deadlock", err: &mysql.MySQLError{Number: 1213}, wantKind: ErrUnavailable},
+        {name: "lock wait timeout", err: &mysql.MySQLError{Number: 1205}, wantKind: ErrUnavailable},
+        {name: "query timeout", err: context.DeadlineExceeded, wantKind: ErrUnavailable},
+        {name: "cancelled", err: context.Canceled, wantKind: ErrUnavailable},
+        {name: "bad connection", err: fmt.Errorf("exec: %w", driver.ErrBadConn), wantKind: ErrUnavailable},
+        {name: "unclassified mysql error", err: &mysql.MySQLError{Number: 1064}, wantKind: nil},
+        {name: "postgres unique violation", err: &pgconn.PgError{Code: "23505"}, wantKind: ErrConflict},
+        {name: "postgres serialization failure", err: &pgconn.PgError{Code: "40001"}, wantKind: ErrUnavailable},
+        {name: "unclassified postgres error", err: &pgconn.PgError{Code: "42601"}, wantKind: nil},
+    }
+
+    kinds := []error{ErrNotFound, ErrConflict, ErrUnavailable}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := wrapError("test op", tt.err)
+            if !errors.Is(err, tt.err) {
+                t.Errorf("wrapError() = %v, lost the underlying error", err)
+            }
+            for _, kind := range kinds {
+                if got, want := errors.Is(err, kind), kind == tt.wantKind; got != want {
+                    t.Errorf("errors.Is(%v, %v) = %v, want %v", err, kind, got, want)
+                }
+            }
+        })
+    }
+
+    if err := wrapError("test op", nil); err != nil {
+        t.Errorf("wrapError(nil) = %v, want nil", err)
+    }
+}
+
+func TestErrorAs(t *testing.T) {
+    err := wrapError("save user", &mysql.MySQLError{Number: 1062})
+
+    var repoErr *Error
+    if !errors.As(err, &repoErr) || repoErr.Op != "save user" {
+        t.Fatalf("errors.As(*Error) failed for %v", err)
+    }
+    var mysqlErr *mysql.MySQLError
+    if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+        t.Errorf("errors.As(*mysql.MySQLError) failed for %v", err)
+    }
+}
+
+func TestDuplicateKeyName(t *testing.T) {
+    tests := []struct {
+        message string
+        want    string
+    }{
+        {"Duplicate entry 'a@example.com' for key 'users.uq_users_email'", "uq_users_email"},
+        {"Duplicate entry 'a@example.com' for key 'uq_users_email'", "uq_users_email"},
+        {"Duplicate entry 'abc' for key 'PRIMARY'", "PRIMARY"},
+    }
+    for _, tt := range tests {
+        err := &mysql.MySQLError{Number: 1062, Message: tt.message}
+        if got := duplicateKeyName(err); got != tt.want {
+            t.Errorf("duplicateKeyName(%q) = %q, want %q", tt.message, got, tt.want)
+        }
+    }
+
+    err := wrapUserWriteError("save user", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.uq_users_email'"})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "email" {
+        t.Errorf("wrapUserWriteError() = %v, want ErrConflict on email", err)
+    }
+
+    err = wrapUserWriteError("save user", &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "id" {
+        t.Errorf("wrapUserWriteError() = %v, want ErrConflict on id", err)
+    }
+}
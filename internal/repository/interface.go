@@ -1,12 +1,102 @@
 package repository
 
-import "go-crud-api/internal/model"
+import (
+    "context"
+    "time"
 
-// UserRepositoryInterface defines the methods for user repository
+    "go-crud-api/internal/model"
+)
+
+// UserRepositoryInterface defines the methods for user repository. Every
+// method takes the request context so queries are cancelled when the
+// client goes away or the deadline passes. Errors match ErrNotFound,
+// ErrConflict, ErrStale or ErrUnavailable where applicable.
+//
+// Users carry a version for optimistic concurrency. Save stores version 1
+// and every write increments it. Update, Patch and Delete take the
+// version the caller last read (user.Version, changes.Version, version);
+// when it is non-zero the write only happens if the stored version still
+// matches, and fails with ErrStale otherwise.
+//
+// Delete is a soft delete: it sets the user's DeletedAt, after which the
+// user is invisible to every read except FindByIdIncludingDeleted and
+// List with IncludeDeleted, and cannot be written until restored. A
+// deleted user keeps their email reserved until Purge removes the row.
+//
+// SetPassword is the exception to both: it replaces the stored hash of a
+// deleted user too, and leaves the version alone, so maintenance such as
+// rehashing does not invalidate the ETags clients hold.
 type UserRepositoryInterface interface {
-    GetAll() ([]model.User, error)
-    Save(user model.User) error
-    FindById(id string) (model.User, bool)
-    Update(user model.User) bool
-    Delete(id string) bool
+    GetAll(ctx context.Context) ([]model.User, error)
+    List(ctx context.Context, opts ListOptions) (UserPage, error)
+    Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error)
+    Save(ctx context.Context, user model.User) error
+    FindById(ctx context.Context, id string) (model.User, error)
+    FindByIdIncludingDeleted(ctx context.Context, id string) (model.User, error)
+    FindByEmail(ctx context.Context, email string) (model.User, error)
+    Update(ctx context.Context, user model.User) error
+    // Patch sets only the fields present in changes
+    Patch(ctx context.Context, id string, changes UserChanges) error
+    // SetPassword stores a new password hash for the user, deleted or
+    // not, without checking or incrementing the version
+    SetPassword(ctx context.Context, id string, hash string) error
+    Delete(ctx context.Context, id string, version int64) error
+    // Restore undeletes a soft-deleted user, returning ErrNotFound if the
+    // user does not exist or is not deleted
+    Restore(ctx context.Context, id string) error
+    // Purge permanently removes users deleted before deletedBefore and
+    // returns how many were removed
+    Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// UserChanges lists the fields a partial update sets; nil fields are left
+// unchanged. Password must already be hashed.
+type UserChanges struct {
+    // Version is the expected stored version, or 0 for an unconditional write
+    Version  int64
+    Name     *string
+    Email    *string
+    Password *string
+    Role     *model.Role
+}
+
+// Empty reports whether c changes nothing
+func (c UserChanges) Empty() bool {
+    return c.Name == nil && c.Email == nil && c.Password == nil && c.Role == nil
+}
+
+// Apply returns u with the changes applied and, if anything changed, its
+// version incremented like the repositories do
+func (c UserChanges) Apply(u model.User) model.User {
+    if c.Name != nil {
+        u.Name = *c.Name
+    }
+    if c.Email != nil {
+        u.Email = *c.Email
+    }
+    if c.Password != nil {
+        u.Password = *c.Password
+    }
+    if c.Role != nil {
+        u.Role = *c.Role
+    }
+    if !c.Empty() {
+        u.Version++
+    }
+    return u
+}
+
+// RefreshTokenRepositoryInterface defines the methods for refresh token storage
+type RefreshTokenRepositoryInterface interface {
+    Save(ctx context.Context, token model.RefreshToken) error
+    FindByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+    // Revoke marks an active token as revoked and records its successor.
+    // It returns ErrNotFound if the token does not exist or was already
+    // revoked, so two concurrent refreshes cannot both rotate the same token.
+    Revoke(ctx context.Context, id string, replacedBy string) error
+    RevokeFamily(ctx context.Context, familyID string) error
+    // DeleteExpired removes the tokens that expired before, revoked or
+    // not, and returns how many it removed. Revoked tokens are kept until
+    // they expire so that presenting one again is still seen as reuse.
+    DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
\ No newline at end of file
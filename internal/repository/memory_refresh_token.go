@@ -0,0 +1,95 @@
+package repository
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+var _ RefreshTokenRepositoryInterface = (*MemoryRefreshTokenRepository)(nil)
+
+// MemoryRefreshTokenRepository stores refresh tokens in memory and is safe
+// for concurrent use. Tokens are not persisted, so sessions end when the
+// process exits.
+type MemoryRefreshTokenRepository struct {
+    mu     sync.Mutex
+    tokens map[string]model.RefreshToken
+    // byHash maps each token hash to the token's ID
+    byHash map[string]string
+}
+
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+    return &MemoryRefreshTokenRepository{
+        tokens: make(map[string]model.RefreshToken),
+        byHash: make(map[string]string),
+    }
+}
+
+func (r *MemoryRefreshTokenRepository) Save(ctx context.Context, token model.RefreshToken) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if old, exists := r.tokens[token.ID]; exists {
+        delete(r.byHash, old.TokenHash)
+    }
+    r.tokens[token.ID] = token
+    r.byHash[token.TokenHash] = token.ID
+    return nil
+}
+
+func (r *MemoryRefreshTokenRepository) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    token, exists := r.tokens[r.byHash[hash]]
+    if !exists {
+        return model.RefreshToken{}, ErrNotFound
+    }
+    return token, nil
+}
+
+func (r *MemoryRefreshTokenRepository) Revoke(ctx context.Context, id string, replacedBy string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    token, exists := r.tokens[id]
+    if !exists || token.Revoked() {
+        return ErrNotFound
+    }
+    now := time.Now()
+    token.RevokedAt = &now
+    token.ReplacedBy = replacedBy
+    r.tokens[id] = token
+    return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    now := time.Now()
+    for id, token := range r.tokens {
+        if token.FamilyID == familyID && !token.Revoked() {
+            token.RevokedAt = &now
+            r.tokens[id] = token
+        }
+    }
+    return nil
+}
+
+func (r *MemoryRefreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var deleted int64
+    for id, token := range r.tokens {
+        if token.ExpiresAt.Before(before) {
+            delete(r.tokens, id)
+            delete(r.byHash, token.TokenHash)
+            deleted++
+        }
+    }
+    return deleted, nil
+}
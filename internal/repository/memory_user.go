@@ -0,0 +1,475 @@
+package repository
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+var _ UserRepositoryInterface = (*MemoryUserRepository)(nil)
+
+// MemoryUserRepository stores users in memory. It is safe for concurrent
+// use: reads share a lock and writes hold it exclusively, so every method
+// sees and leaves a consistent state. Emails are indexed case-insensitively,
+// like the unique index of the SQL backends, and the index keeps deleted
+// users' emails reserved until they are purged.
+//
+// SaveSnapshot and LoadSnapshot persist the users to a file, so a process
+// can keep its data across restarts.
+type MemoryUserRepository struct {
+    mu    sync.RWMutex
+    users map[string]model.User
+    // emails maps the lower-cased email of every stored user to their id
+    emails map[string]string
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+    return &MemoryUserRepository{
+        users:  make(map[string]model.User),
+        emails: make(map[string]string),
+    }
+}
+
+// checkContext reports a cancelled or expired ctx as ErrUnavailable, as
+// the database backends do, so callers see the same error from all of them
+func checkContext(ctx context.Context, op string) error {
+    if err := ctx.Err(); err != nil {
+        return &Error{Op: op, Kind: ErrUnavailable, Err: err}
+    }
+    return nil
+}
+
+func emailKey(email string) string {
+    return strings.ToLower(email)
+}
+
+func (r *MemoryUserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+    if err := checkContext(ctx, "get all users"); err != nil {
+        return nil, err
+    }
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    users := make([]model.User, 0, len(r.users))
+    for _, user := range r.users {
+        if !user.Deleted() {
+            users = append(users, user)
+        }
+    }
+    return users, nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, opts ListOptions) (UserPage, error) {
+    if err := checkContext(ctx, "list users"); err != nil {
+        return UserPage{}, err
+    }
+    opts = opts.normalize()
+
+    var after []string
+    if opts.Cursor != "" {
+        values, err := decodeCursor(opts.Cursor, opts.Sort)
+        if err != nil {
+            return UserPage{}, err
+        }
+        for i := range values {
+            values[i] = strings.ToLower(values[i])
+        }
+        after = values
+    }
+
+    namePrefix := strings.ToLower(opts.Filter.NamePrefix)
+    emailPrefix := strings.ToLower(opts.Filter.EmailPrefix)
+
+    r.mu.RLock()
+    var matched []model.User
+    for _, user := range r.users {
+        if user.Deleted() && !opts.IncludeDeleted {
+            continue
+        }
+        if strings.HasPrefix(strings.ToLower(user.Name), namePrefix) &&
+            strings.HasPrefix(strings.ToLower(user.Email), emailPrefix) {
+            matched = append(matched, user)
+        }
+    }
+    r.mu.RUnlock()
+
+    sort.Slice(matched, func(i, j int) bool {
+        return compareSortValues(opts.Sort, memorySortValues(opts.Sort, matched[i]), memorySortValues(opts.Sort, matched[j])) < 0
+    })
+
+    page := UserPage{Users: []model.User{}}
+    if opts.IncludeTotal {
+        total := len(matched)
+        page.Total = &total
+    }
+
+    for _, user := range matched {
+        if after != nil && compareSortValues(opts.Sort, memorySortValues(opts.Sort, user), after) <= 0 {
+            continue
+        }
+        if len(page.Users) == opts.Limit {
+            page.NextCursor = encodeCursor(opts.Sort, page.Users[len(page.Users)-1])
+            break
+        }
+        page.Users = append(page.Users, user)
+    }
+
+    return page, nil
+}
+
+// memorySortValues mirrors the case-insensitive collation MySQL uses for
+// text columns
+func memorySortValues(sort []SortField, u model.User) []string {
+    values := make([]string, len(sort))
+    for i, f := range sort {
+        values[i] = strings.ToLower(sortValue(u, f.Field))
+    }
+    return values
+}
+
+func compareSortValues(sort []SortField, a, b []string) int {
+    for i, f := range sort {
+        c := strings.Compare(a[i], b[i])
+        if c == 0 {
+            continue
+        }
+        if f.Desc {
+            return -c
+        }
+        return c
+    }
+    return 0
+}
+
+// Search matches every query term against the name and email tokens by
+// prefix, like the MySQL FULLTEXT search in boolean mode
+func (r *MemoryUserRepository) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+    if err := checkContext(ctx, "search users"); err != nil {
+        return nil, err
+    }
+    opts, terms, err := opts.normalize()
+    if err != nil {
+        return nil, err
+    }
+
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    results := []SearchResult{}
+    for _, user := range r.users {
+        if user.Deleted() {
+            continue
+        }
+        if score := scoreUser(user, terms, true); score > 0 {
+            results = append(results, newSearchResult(user, terms, score))
+        }
+    }
+    return rankResults(results, opts.Limit), nil
+}
+
+func (r *MemoryUserRepository) Save(ctx context.Context, user model.User) error {
+    if err := checkContext(ctx, "save user"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if _, exists := r.users[user.ID]; exists {
+        return &Error{Op: "save user", Kind: ErrConflict, Field: "id"}
+    }
+    if r.emailTaken(user.Email, user.ID) {
+        return &Error{Op: "save user", Kind: ErrConflict, Field: "email"}
+    }
+    user.Version = 1
+    r.put(user)
+    return nil
+}
+
+// emailTaken reports whether a user other than id, deleted or not, has
+// email. The caller must hold the lock.
+func (r *MemoryUserRepository) emailTaken(email, id string) bool {
+    owner, exists := r.emails[emailKey(email)]
+    return exists && owner != id
+}
+
+// put stores user and moves their email in the index. The caller must
+// hold the write lock.
+func (r *MemoryUserRepository) put(user model.User) {
+    if old, exists := r.users[user.ID]; exists {
+        delete(r.emails, emailKey(old.Email))
+    }
+    r.users[user.ID] = user
+    r.emails[emailKey(user.Email)] = user.ID
+}
+
+func (r *MemoryUserRepository) FindById(ctx context.Context, id string) (model.User, error) {
+    if err := checkContext(ctx, "find user by id"); err != nil {
+        return model.User{}, err
+    }
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user, exists := r.users[id]
+    if !exists || user.Deleted() {
+        return model.User{}, ErrNotFound
+    }
+    return user, nil
+}
+
+func (r *MemoryUserRepository) FindByIdIncludingDeleted(ctx context.Context, id string) (model.User, error) {
+    if err := checkContext(ctx, "find user by id"); err != nil {
+        return model.User{}, err
+    }
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user, exists := r.users[id]
+    if !exists {
+        return model.User{}, ErrNotFound
+    }
+    return user, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+    if err := checkContext(ctx, "find user by email"); err != nil {
+        return model.User{}, err
+    }
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    user, exists := r.users[r.emails[emailKey(email)]]
+    if !exists || user.Deleted() {
+        return model.User{}, ErrNotFound
+    }
+    return user, nil
+}
+
+func (r *MemoryUserRepository) Update(ctx context.Context, user model.User) error {
+    if err := checkContext(ctx, "update user"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    stored, err := r.checkVersion("update user", user.ID, user.Version)
+    if err != nil {
+        return err
+    }
+    if r.emailTaken(user.Email, user.ID) {
+        return &Error{Op: "update user", Kind: ErrConflict, Field: "email"}
+    }
+    // Like the SQL backends, Update leaves the creation time alone
+    user.CreatedAt = stored.CreatedAt
+    user.Version = stored.Version + 1
+    r.put(user)
+    return nil
+}
+
+// checkVersion returns the stored user, or ErrNotFound, or ErrStale when
+// version is non-zero and differs from the stored one. The caller must
+// hold the lock.
+func (r *MemoryUserRepository) checkVersion(op, id string, version int64) (model.User, error) {
+    user, exists := r.users[id]
+    if !exists || user.Deleted() {
+        return user, &Error{Op: op, Kind: ErrNotFound}
+    }
+    if version != 0 && version != user.Version {
+        return user, &Error{Op: op, Kind: ErrStale}
+    }
+    return user, nil
+}
+
+func (r *MemoryUserRepository) Patch(ctx context.Context, id string, changes UserChanges) error {
+    if err := checkContext(ctx, "patch user"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    user, err := r.checkVersion("patch user", id, changes.Version)
+    if err != nil {
+        return err
+    }
+    if changes.Email != nil && r.emailTaken(*changes.Email, id) {
+        return &Error{Op: "patch user", Kind: ErrConflict, Field: "email"}
+    }
+    r.put(changes.Apply(user))
+    return nil
+}
+
+func (r *MemoryUserRepository) SetPassword(ctx context.Context, id string, hash string) error {
+    if err := checkContext(ctx, "set user password"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    user, exists := r.users[id]
+    if !exists {
+        return &Error{Op: "set user password", Kind: ErrNotFound}
+    }
+    user.Password = hash
+    r.users[id] = user
+    return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string, version int64) error {
+    if err := checkContext(ctx, "delete user"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    user, err := r.checkVersion("delete user", id, version)
+    if err != nil {
+        return err
+    }
+    now := time.Now().UTC()
+    user.DeletedAt = &now
+    user.Version++
+    r.users[id] = user
+    return nil
+}
+
+func (r *MemoryUserRepository) Restore(ctx context.Context, id string) error {
+    if err := checkContext(ctx, "restore user"); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    user, exists := r.users[id]
+    if !exists || !user.Deleted() {
+        return &Error{Op: "restore user", Kind: ErrNotFound}
+    }
+    user.DeletedAt = nil
+    user.Version++
+    r.users[id] = user
+    return nil
+}
+
+func (r *MemoryUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+    if err := checkContext(ctx, "purge users"); err != nil {
+        return 0, err
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    var purged int64
+    for id, user := range r.users {
+        if user.Deleted() && user.DeletedAt.Before(deletedBefore) {
+            delete(r.users, id)
+            delete(r.emails, emailKey(user.Email))
+            purged++
+        }
+    }
+    return purged, nil
+}
+
+// snapshotFormat versions the snapshot file layout
+const snapshotFormat = 1
+
+type memorySnapshot struct {
+    Format int            `json:"format"`
+    Users  []snapshotUser `json:"users"`
+}
+
+// snapshotUser is the stored form of a model.User, whose JSON encoding
+// leaves out the password, version and deletion time
+type snapshotUser struct {
+    ID        string     `json:"id"`
+    Name      string     `json:"name"`
+    Email     string     `json:"email"`
+    Password  string     `json:"password"`
+    Role      model.Role `json:"role"`
+    CreatedAt time.Time  `json:"created_at"`
+    Version   int64      `json:"version"`
+    DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// SaveSnapshot writes every user, including deleted ones, to path. The
+// file is written next to path and renamed over it, so a crash while
+// saving leaves the previous snapshot intact.
+func (r *MemoryUserRepository) SaveSnapshot(path string) error {
+    r.mu.RLock()
+    snapshot := memorySnapshot{Format: snapshotFormat, Users: make([]snapshotUser, 0, len(r.users))}
+    for _, u := range r.users {
+        snapshot.Users = append(snapshot.Users, snapshotUser(u))
+    }
+    r.mu.RUnlock()
+    sort.Slice(snapshot.Users, func(i, j int) bool {
+        return snapshot.Users[i].ID < snapshot.Users[j].ID
+    })
+
+    data, err := json.MarshalIndent(snapshot, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+    if err != nil {
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    defer os.Remove(tmp.Name())
+    // The file holds password hashes
+    if err := tmp.Chmod(0o600); err != nil {
+        tmp.Close()
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    if err := tmp.Close(); err != nil {
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    if err := os.Rename(tmp.Name(), path); err != nil {
+        return fmt.Errorf("failed to save snapshot: %w", err)
+    }
+    return nil
+}
+
+// LoadSnapshot replaces the stored users with those saved in path. It
+// returns an error matching fs.ErrNotExist if there is no snapshot yet.
+func (r *MemoryUserRepository) LoadSnapshot(path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return err
+    }
+    var snapshot memorySnapshot
+    if err := json.Unmarshal(data, &snapshot); err != nil {
+        return fmt.Errorf("invalid snapshot %s: %w", path, err)
+    }
+    if snapshot.Format != snapshotFormat {
+        return fmt.Errorf("snapshot %s has unsupported format %d", path, snapshot.Format)
+    }
+
+    users := make(map[string]model.User, len(snapshot.Users))
+    emails := make(map[string]string, len(snapshot.Users))
+    for _, u := range snapshot.Users {
+        if _, exists := users[u.ID]; exists {
+            return fmt.Errorf("snapshot %s: %w: duplicate id %q", path, ErrConflict, u.ID)
+        }
+        if _, exists := emails[emailKey(u.Email)]; exists {
+            return fmt.Errorf("snapshot %s: %w: duplicate email %q", path, ErrConflict, u.Email)
+        }
+        users[u.ID] = model.User(u)
+        emails[emailKey(u.Email)] = u.ID
+    }
+
+    r.mu.Lock()
+    r.users, r.emails = users, emails
+    r.mu.Unlock()
+    return nil
+}
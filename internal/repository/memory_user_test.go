@@ -0,0 +1,127 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+func TestMemoryUserRepository_Concurrent(t *testing.T) {
+    ctx := context.Background()
+    repo := NewMemoryUserRepository()
+    repo.Save(ctx, model.User{ID: "shared", Name: "Shared", Email: "shared@example.com", Role: model.RoleUser})
+
+    const workers = 20
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    var applied, stale int
+    for i := 0; i < workers; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            id := fmt.Sprintf("user-%d", i)
+            // Every worker claims the same email; exactly one may win
+            repo.Save(ctx, model.User{ID: id, Name: id, Email: "SAME@example.com", Role: model.RoleUser})
+            repo.List(ctx, ListOptions{})
+            repo.Search(ctx, SearchOptions{Query: "user"})
+
+            name := id
+            err := repo.Patch(ctx, "shared", UserChanges{Version: 1, Name: &name})
+            mu.Lock()
+            defer mu.Unlock()
+            switch {
+            case err == nil:
+                applied++
+            case errors.Is(err, ErrStale):
+                stale++
+            default:
+                t.Errorf("Patch() error = %v", err)
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    if applied != 1 || stale != workers-1 {
+        t.Errorf("Concurrent patches at version 1: %d applied, %d stale, want 1 and %d", applied, stale, workers-1)
+    }
+    page, _ := repo.List(ctx, ListOptions{Filter: UserFilter{EmailPrefix: "same@"}})
+    if len(page.Users) != 1 {
+        t.Errorf("Expected one user to win the shared email, got %d", len(page.Users))
+    }
+}
+
+func TestMemoryUserRepository_EmailIndex(t *testing.T) {
+    ctx := context.Background()
+    repo := NewMemoryUserRepository()
+    repo.Save(ctx, model.User{ID: "1", Name: "Alice", Email: "alice@example.com"})
+
+    // Changing an email frees the old one
+    email := "alice@example.org"
+    if err := repo.Patch(ctx, "1", UserChanges{Email: &email}); err != nil {
+        t.Fatalf("Patch() error = %v", err)
+    }
+    if _, err := repo.FindByEmail(ctx, "alice@example.com"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("FindByEmail() of the old email = %v, want ErrNotFound", err)
+    }
+    if err := repo.Save(ctx, model.User{ID: "2", Name: "Other", Email: "Alice@Example.com"}); err != nil {
+        t.Errorf("Save() with the freed email error = %v", err)
+    }
+
+    // Purging frees a deleted user's email
+    repo.Delete(ctx, "1", 0)
+    repo.Purge(ctx, time.Now().Add(time.Minute))
+    if err := repo.Save(ctx, model.User{ID: "3", Name: "Third", Email: "ALICE@example.org"}); err != nil {
+        t.Errorf("Save() with a purged user's email error = %v", err)
+    }
+}
+
+func TestMemoryUserRepository_Snapshot(t *testing.T) {
+    ctx := context.Background()
+    path := filepath.Join(t.TempDir(), "users.json")
+
+    empty := NewMemoryUserRepository()
+    if err := empty.LoadSnapshot(path); !errors.Is(err, fs.ErrNotExist) {
+        t.Errorf("LoadSnapshot() of a missing file = %v, want fs.ErrNotExist", err)
+    }
+
+    repo := NewMemoryUserRepository()
+    created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    repo.Save(ctx, model.User{ID: "1", Name: "Alice", Email: "alice@example.com", Password: "hash", Role: model.RoleAdmin, CreatedAt: created})
+    repo.Save(ctx, model.User{ID: "2", Name: "Bob", Email: "bob@example.com", Password: "hash", Role: model.RoleUser, CreatedAt: created})
+    repo.Delete(ctx, "2", 1)
+    if err := repo.SaveSnapshot(path); err != nil {
+        t.Fatalf("SaveSnapshot() error = %v", err)
+    }
+    if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+        t.Errorf("Snapshot file = %v, %v, want mode 0600", info, err)
+    }
+
+    loaded := NewMemoryUserRepository()
+    if err := loaded.LoadSnapshot(path); err != nil {
+        t.Fatalf("LoadSnapshot() error = %v", err)
+    }
+    alice, err := loaded.FindByEmail(ctx, "ALICE@example.com")
+    if err != nil || alice.Password != "hash" || alice.Role != model.RoleAdmin || alice.Version != 1 || !alice.CreatedAt.Equal(created) {
+        t.Errorf("FindByEmail() after LoadSnapshot() = %+v, %v", alice, err)
+    }
+    bob, err := loaded.FindByIdIncludingDeleted(ctx, "2")
+    if err != nil || !bob.Deleted() || bob.Version != 2 {
+        t.Errorf("Deleted user after LoadSnapshot() = %+v, %v", bob, err)
+    }
+    if err := loaded.Save(ctx, model.User{ID: "3", Email: "bob@example.com"}); !errors.Is(err, ErrConflict) {
+        t.Errorf("Save() with a loaded deleted user's email = %v, want ErrConflict", err)
+    }
+
+    os.WriteFile(path, []byte(`{"format": 99, "users": []}`), 0o600)
+    if err := loaded.LoadSnapshot(path); err == nil {
+        t.Error("LoadSnapshot() of an unknown format succeeded")
+    }
+}
@@ -1,48 +1,28 @@
 package repository
 
-import "go-crud-api/internal/model"
+var (
+    _ UserRepositoryInterface         = (*MockUserRepository)(nil)
+    _ RefreshTokenRepositoryInterface = (*MockRefreshTokenRepository)(nil)
+)
 
-// MockUserRepository implements an in-memory version for testing
+// MockUserRepository is the in-memory repository the tests use
 type MockUserRepository struct {
-    users map[string]model.User
+    *MemoryUserRepository
 }
 
 func NewMockUserRepository() *MockUserRepository {
     return &MockUserRepository{
-        users: make(map[string]model.User),
+        MemoryUserRepository: NewMemoryUserRepository(),
     }
 }
 
-func (r *MockUserRepository) GetAll() ([]model.User, error) {
-    users := make([]model.User, 0, len(r.users))
-    for _, user := range r.users {
-        users = append(users, user)
-    }
-    return users, nil
-}
-
-func (r *MockUserRepository) Save(user model.User) error {
-    r.users[user.ID] = user
-    return nil
+// MockRefreshTokenRepository is the in-memory token store the tests use
+type MockRefreshTokenRepository struct {
+    *MemoryRefreshTokenRepository
 }
 
-func (r *MockUserRepository) FindById(id string) (model.User, bool) {
-    user, exists := r.users[id]
-    return user, exists
-}
-
-func (r *MockUserRepository) Update(user model.User) bool {
-    _, exists := r.users[user.ID]
-    if exists {
-        r.users[user.ID] = user
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+    return &MockRefreshTokenRepository{
+        MemoryRefreshTokenRepository: NewMemoryRefreshTokenRepository(),
     }
-    return exists
 }
-
-func (r *MockUserRepository) Delete(id string) bool {
-    _, exists := r.users[id]
-    if exists {
-        delete(r.users, id)
-    }
-    return exists
-}
\ No newline at end of file
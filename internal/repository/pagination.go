@@ -0,0 +1,182 @@
+package repository
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+const (
+    DefaultPageSize = 20
+    MaxPageSize     = 100
+)
+
+var (
+    // ErrInvalidSort is returned for sort expressions naming unknown fields
+    ErrInvalidSort = errors.New("invalid sort")
+    // ErrInvalidCursor is returned for cursors that are malformed or were
+    // issued for a different sort order
+    ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// sortableFields lists the user fields that can be sorted on
+var sortableFields = map[string]bool{
+    "id":         true,
+    "name":       true,
+    "email":      true,
+    "created_at": true,
+}
+
+// cursorTimeFormat is a fixed width RFC 3339 layout so encoded timestamps
+// compare lexicographically in the same order as the times themselves
+const cursorTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// SortField orders a listing by one field
+type SortField struct {
+    Field string
+    Desc  bool
+}
+
+// ParseSort parses a comma separated sort expression such as
+// "name,-created_at" where a leading "-" means descending
+func ParseSort(s string) ([]SortField, error) {
+    if s == "" {
+        return nil, nil
+    }
+
+    var fields []SortField
+    seen := make(map[string]bool)
+    for _, part := range strings.Split(s, ",") {
+        part = strings.TrimSpace(part)
+        desc := strings.HasPrefix(part, "-")
+        name := strings.TrimPrefix(part, "-")
+        if !sortableFields[name] {
+            return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidSort, name)
+        }
+        if seen[name] {
+            return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, name)
+        }
+        seen[name] = true
+        fields = append(fields, SortField{Field: name, Desc: desc})
+    }
+    return fields, nil
+}
+
+// UserFilter restricts a listing. Prefix filters are case-insensitive.
+type UserFilter struct {
+    NamePrefix  string
+    EmailPrefix string
+}
+
+// ListOptions controls a paginated user listing
+type ListOptions struct {
+    Limit        int
+    Cursor       string
+    Sort         []SortField
+    Filter       UserFilter
+    IncludeTotal bool
+    // IncludeDeleted also lists soft-deleted users
+    IncludeDeleted bool
+}
+
+// UserPage is one page of a user listing. NextCursor is empty on the last
+// page; Total is only set when requested.
+type UserPage struct {
+    Users      []model.User
+    NextCursor string
+    Total      *int
+}
+
+// normalize applies defaults and appends the id as a final tie-breaker so
+// the sort order is total, which keyset pagination relies on
+func (o ListOptions) normalize() ListOptions {
+    if o.Limit <= 0 {
+        o.Limit = DefaultPageSize
+    }
+    if o.Limit > MaxPageSize {
+        o.Limit = MaxPageSize
+    }
+
+    sort := append([]SortField(nil), o.Sort...)
+    if len(sort) == 0 {
+        sort = []SortField{{Field: "created_at"}}
+    }
+    hasID := false
+    for _, f := range sort {
+        if f.Field == "id" {
+            hasID = true
+        }
+    }
+    if !hasID {
+        sort = append(sort, SortField{Field: "id"})
+    }
+    o.Sort = sort
+    return o
+}
+
+type cursor struct {
+    Sort   string   `json:"s"`
+    Values []string `json:"v"`
+}
+
+func sortSignature(sort []SortField) string {
+    parts := make([]string, len(sort))
+    for i, f := range sort {
+        parts[i] = f.Field
+        if f.Desc {
+            parts[i] = "-" + f.Field
+        }
+    }
+    return strings.Join(parts, ",")
+}
+
+// sortValue returns the value of field on u as it is stored in a cursor
+func sortValue(u model.User, field string) string {
+    switch field {
+    case "name":
+        return u.Name
+    case "email":
+        return u.Email
+    case "created_at":
+        return u.CreatedAt.UTC().Format(cursorTimeFormat)
+    default:
+        return u.ID
+    }
+}
+
+func encodeCursor(sort []SortField, last model.User) string {
+    c := cursor{Sort: sortSignature(sort)}
+    for _, f := range sort {
+        c.Values = append(c.Values, sortValue(last, f.Field))
+    }
+    data, _ := json.Marshal(c)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort []SortField) ([]string, error) {
+    data, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+
+    var c cursor
+    if err := json.Unmarshal(data, &c); err != nil {
+        return nil, ErrInvalidCursor
+    }
+    if c.Sort != sortSignature(sort) || len(c.Values) != len(sort) {
+        return nil, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidCursor)
+    }
+    for i, f := range sort {
+        if f.Field == "created_at" {
+            if _, err := time.Parse(cursorTimeFormat, c.Values[i]); err != nil {
+                return nil, ErrInvalidCursor
+            }
+        }
+    }
+    return c.Values, nil
+}
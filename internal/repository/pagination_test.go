@@ -0,0 +1,103 @@
+package repository
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+func TestParseSort(t *testing.T) {
+    tests := []struct {
+        input   string
+        want    []SortField
+        wantErr bool
+    }{
+        {input: "", want: nil},
+        {input: "name", want: []SortField{{Field: "name"}}},
+        {input: "name,-created_at", want: []SortField{{Field: "name"}, {Field: "created_at", Desc: true}}},
+        {input: " -email , id", want: []SortField{{Field: "email", Desc: true}, {Field: "id"}}},
+        {input: "password", wantErr: true},
+        {input: "name,-name", wantErr: true},
+        {input: "name,", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.input, func(t *testing.T) {
+            got, err := ParseSort(tt.input)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParseSort() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if err != nil && !errors.Is(err, ErrInvalidSort) {
+                t.Errorf("Expected ErrInvalidSort, got %v", err)
+            }
+            if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ParseSort() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestListOptionsNormalize(t *testing.T) {
+    opts := ListOptions{}.normalize()
+    if opts.Limit != DefaultPageSize {
+        t.Errorf("Expected default limit %d, got %d", DefaultPageSize, opts.Limit)
+    }
+    if want := []SortField{{Field: "created_at"}, {Field: "id"}}; !reflect.DeepEqual(opts.Sort, want) {
+        t.Errorf("Expected default sort %+v, got %+v", want, opts.Sort)
+    }
+
+    opts = ListOptions{Limit: 1000, Sort: []SortField{{Field: "id", Desc: true}}}.normalize()
+    if opts.Limit != MaxPageSize {
+        t.Errorf("Expected limit capped at %d, got %d", MaxPageSize, opts.Limit)
+    }
+    if len(opts.Sort) != 1 {
+        t.Errorf("Expected no extra tie-breaker when sorting by id, got %+v", opts.Sort)
+    }
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+    sort := []SortField{{Field: "name"}, {Field: "created_at", Desc: true}, {Field: "id"}}
+    user := model.User{ID: "42", Name: "Bob", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)}
+
+    values, err := decodeCursor(encodeCursor(sort, user), sort)
+    if err != nil {
+        t.Fatalf("decodeCursor returned error: %v", err)
+    }
+    if want := []string{"Bob", "2024-01-02T03:04:05.000000006Z", "42"}; !reflect.DeepEqual(values, want) {
+        t.Errorf("Got values %v, want %v", values, want)
+    }
+
+    other := []SortField{{Field: "name"}, {Field: "id"}}
+    for _, c := range []string{encodeCursor(other, user), "!!!", "bm90IGpzb24"} {
+        if _, err := decodeCursor(c, sort); !errors.Is(err, ErrInvalidCursor) {
+            t.Errorf("decodeCursor(%q) error = %v, want ErrInvalidCursor", c, err)
+        }
+    }
+}
+
+func TestKeysetCondition(t *testing.T) {
+    sort := []SortField{{Field: "name"}, {Field: "created_at", Desc: true}, {Field: "id"}}
+    values := []string{"Bob", "2024-01-02T03:04:05.000000000Z", "42"}
+
+    condition, args := keysetCondition(sort, values)
+
+    want := "((name > ?) OR (name = ? AND created_at < ?) OR (name = ? AND created_at = ? AND id > ?))"
+    if condition != want {
+        t.Errorf("Got condition %s, want %s", condition, want)
+    }
+    if len(args) != 6 {
+        t.Fatalf("Expected 6 args, got %d", len(args))
+    }
+    if _, ok := args[2].(time.Time); !ok {
+        t.Errorf("Expected created_at argument to be a time.Time, got %T", args[2])
+    }
+}
+
+func TestEscapeLike(t *testing.T) {
+    if got := escapeLike(`50%_off!\`); got != `50!%!_off!!\` {
+        t.Errorf("escapeLike() = %s", got)
+    }
+}
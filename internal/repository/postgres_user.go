@@ -0,0 +1,56 @@
+package repository
+
+import (
+    "context"
+    "fmt"
+
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/model"
+)
+
+var _ UserRepositoryInterface = (*PostgresUserRepository)(nil)
+
+// PostgresUserRepository stores users in PostgreSQL. It shares the queries
+// of UserRepository, whose ? placeholders database.DB numbers for
+// Postgres; name and email are CITEXT columns, so comparisons, ordering,
+// LIKE and the unique email constraint are case-insensitive as in MySQL.
+// There is no FULLTEXT index, so every search matches with LIKE.
+type PostgresUserRepository struct {
+    *UserRepository
+}
+
+func NewPostgresUserRepository(db *database.DB) *PostgresUserRepository {
+    return &PostgresUserRepository{
+        UserRepository: NewUserRepository(db),
+    }
+}
+
+// Save leaves the version to the column default and reads it back with
+// RETURNING, so a schema default that drifts from 1 is caught here
+// rather than surfacing later as ErrStale
+func (r *PostgresUserRepository) Save(ctx context.Context, user model.User) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    var version int64
+    query := `INSERT INTO users (id, name, email, password, role, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING version`
+    err := r.db.QueryRowContext(ctx, query, user.ID, user.Name, user.Email, user.Password, user.Role, user.CreatedAt.UTC()).Scan(&version)
+    if err != nil {
+        return wrapUserWriteError("save user", err)
+    }
+    if version != 1 {
+        return &Error{Op: "save user", Err: fmt.Errorf("new user stored at version %d, want 1", version)}
+    }
+    return nil
+}
+
+func (r *PostgresUserRepository) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    opts, terms, err := opts.normalize()
+    if err != nil {
+        return nil, err
+    }
+    return r.searchLike(ctx, terms, opts.Limit)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/model"
+)
+
+var _ RefreshTokenRepositoryInterface = (*RefreshTokenRepository)(nil)
+
+type RefreshTokenRepository struct {
+    db *database.DB
+}
+
+func NewRefreshTokenRepository(db *database.DB) *RefreshTokenRepository {
+    return &RefreshTokenRepository{
+        db: db,
+    }
+}
+
+func (r *RefreshTokenRepository) Save(ctx context.Context, token model.RefreshToken) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, created_at)
+              VALUES (?, ?, ?, ?, ?, ?)`
+    // UTC keeps SQLite's textual timestamps comparable in DeleteExpired
+    _, err := r.db.ExecContext(ctx, query, token.ID, token.UserID, token.FamilyID, token.TokenHash,
+        token.ExpiresAt.UTC(), token.CreatedAt.UTC())
+    return wrapError("save refresh token", err)
+}
+
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    var token model.RefreshToken
+    var revokedAt sql.NullTime
+    var replacedBy sql.NullString
+
+    query := `SELECT id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by
+              FROM refresh_tokens WHERE token_hash = ?`
+    err := r.db.QueryRowContext(ctx, query, hash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+        &token.ExpiresAt, &token.CreatedAt, &revokedAt, &replacedBy)
+    if err != nil {
+        return token, wrapError("find refresh token", err)
+    }
+
+    if revokedAt.Valid {
+        token.RevokedAt = &revokedAt.Time
+    }
+    token.ReplacedBy = replacedBy.String
+    return token, nil
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id string, replacedBy string) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, replaced_by = NULLIF(?, '')
+              WHERE id = ? AND revoked_at IS NULL`
+    result, err := r.db.ExecContext(ctx, query, replacedBy, id)
+    return requireRowsAffected("revoke refresh token", result, err)
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+              WHERE family_id = ? AND revoked_at IS NULL`
+    _, err := r.db.ExecContext(ctx, query, familyID)
+    return wrapError("revoke refresh token family", err)
+}
+
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `DELETE FROM refresh_tokens WHERE expires_at < ?`
+    result, err := r.db.ExecContext(ctx, query, before.UTC())
+    if err != nil {
+        return 0, wrapError("delete expired refresh tokens", err)
+    }
+
+    deleted, err := result.RowsAffected()
+    return deleted, wrapError("delete expired refresh tokens", err)
+}
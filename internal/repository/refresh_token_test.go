@@ -0,0 +1,53 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+)
+
+func TestMockRefreshTokenRepository_Revoke(t *testing.T) {
+    repo := NewMockRefreshTokenRepository()
+    repo.Save(context.Background(), model.RefreshToken{ID: "rt-1", FamilyID: "fam-1", TokenHash: "hash-1", ExpiresAt: time.Now().Add(time.Hour)})
+
+    token, err := repo.FindByHash(context.Background(), "hash-1")
+    if err != nil || token.ID != "rt-1" {
+        t.Fatalf("FindByHash() = %+v, %v", token, err)
+    }
+
+    if err := repo.Revoke(context.Background(), "rt-1", "rt-2"); err != nil {
+        t.Fatalf("Revoke() of an active token failed: %v", err)
+    }
+    if err := repo.Revoke(context.Background(), "rt-1", "rt-3"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Revoke() of an already revoked token = %v, want ErrNotFound", err)
+    }
+    if err := repo.Revoke(context.Background(), "missing", ""); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Revoke() of a missing token = %v, want ErrNotFound", err)
+    }
+
+    token, _ = repo.FindByHash(context.Background(), "hash-1")
+    if !token.Revoked() || token.ReplacedBy != "rt-2" {
+        t.Errorf("Expected token revoked and replaced by rt-2, got %+v", token)
+    }
+}
+
+func TestMockRefreshTokenRepository_RevokeFamily(t *testing.T) {
+    repo := NewMockRefreshTokenRepository()
+    repo.Save(context.Background(), model.RefreshToken{ID: "rt-1", FamilyID: "fam-1", TokenHash: "hash-1"})
+    repo.Save(context.Background(), model.RefreshToken{ID: "rt-2", FamilyID: "fam-1", TokenHash: "hash-2"})
+    repo.Save(context.Background(), model.RefreshToken{ID: "rt-3", FamilyID: "fam-2", TokenHash: "hash-3"})
+
+    if err := repo.RevokeFamily(context.Background(), "fam-1"); err != nil {
+        t.Fatalf("RevokeFamily returned error: %v", err)
+    }
+
+    for hash, wantRevoked := range map[string]bool{"hash-1": true, "hash-2": true, "hash-3": false} {
+        token, _ := repo.FindByHash(context.Background(), hash)
+        if token.Revoked() != wantRevoked {
+            t.Errorf("Token %s revoked = %v, want %v", hash, token.Revoked(), wantRevoked)
+        }
+    }
+}
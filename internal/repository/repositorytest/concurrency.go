@@ -0,0 +1,103 @@
+package repositorytest
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "testing"
+
+    "go-crud-api/internal/repository"
+)
+
+// concurrentWriters is how many goroutines race for the same write
+const concurrentWriters = 8
+
+// race runs write from concurrentWriters goroutines at once and returns
+// their errors
+func race(write func(i int) error) []error {
+    errs := make([]error, concurrentWriters)
+    start := make(chan struct{})
+    var wg sync.WaitGroup
+    for i := range errs {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            <-start
+            errs[i] = write(i)
+        }(i)
+    }
+    close(start)
+    wg.Wait()
+    return errs
+}
+
+// countWinners returns how many errs are nil, failing the test for any
+// error that does not match lost
+func countWinners(t *testing.T, op string, errs []error, lost error) int {
+    t.Helper()
+    winners := 0
+    for _, err := range errs {
+        switch {
+        case err == nil:
+            winners++
+        case !errors.Is(err, lost):
+            t.Errorf("Concurrent %s error = %v, want nil or %v", op, err, lost)
+        }
+    }
+    return winners
+}
+
+func testConcurrentWrites(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo, newUser("user-0", "Shared", "shared@example.com"))
+
+    // Exactly one of several sign-ups with the same email succeeds
+    errs := race(func(i int) error {
+        id := fmt.Sprintf("user-%d", i+1)
+        return repo.Save(ctx, newUser(id, id, "Racing@example.com"))
+    })
+    if winners := countWinners(t, "Save()", errs, repository.ErrConflict); winners != 1 {
+        t.Errorf("%d concurrent Save() with one email succeeded, want 1", winners)
+    }
+
+    // Exactly one of several writes expecting the same version succeeds
+    errs = race(func(i int) error {
+        name := fmt.Sprintf("Writer %d", i)
+        return repo.Patch(ctx, "user-0", repository.UserChanges{Version: 1, Name: &name})
+    })
+    if winners := countWinners(t, "Patch()", errs, repository.ErrStale); winners != 1 {
+        t.Errorf("%d concurrent Patch() at version 1 succeeded, want 1", winners)
+    }
+
+    // Unconditional writes all apply, and no increment is lost
+    errs = race(func(i int) error {
+        name := fmt.Sprintf("Writer %d", i)
+        return repo.Patch(ctx, "user-0", repository.UserChanges{Name: &name})
+    })
+    if winners := countWinners(t, "Patch()", errs, nil); winners != concurrentWriters {
+        t.Errorf("%d of %d unconditional Patch() succeeded", winners, concurrentWriters)
+    }
+    user, err := repo.FindById(ctx, "user-0")
+    if want := int64(2 + concurrentWriters); err != nil || user.Version != want {
+        t.Errorf("FindById() after concurrent writes = version %d, %v, want %d", user.Version, err, want)
+    }
+
+    // Exactly one of several deletes expecting the same version succeeds
+    errs = race(func(i int) error {
+        return repo.Delete(ctx, "user-0", user.Version)
+    })
+    winners := 0
+    for _, err := range errs {
+        switch {
+        case err == nil:
+            winners++
+        // A loser finds the user changed or already deleted
+        case !errors.Is(err, repository.ErrStale) && !errors.Is(err, repository.ErrNotFound):
+            t.Errorf("Concurrent Delete() error = %v, want nil, ErrStale or ErrNotFound", err)
+        }
+    }
+    if winners != 1 {
+        t.Errorf("%d concurrent Delete() succeeded, want 1", winners)
+    }
+}
@@ -0,0 +1,111 @@
+package repositorytest
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/repository"
+)
+
+// paginationUsers are listed in creation order. Pairs share a creation
+// time so the next sort field or the id has to break the tie, and names
+// mix case so the order must be case-insensitive.
+var paginationUsers = []struct {
+    id, name string
+    offset   time.Duration
+}{
+    {"user-1", "alice", 0},
+    {"user-2", "Bob", 0},
+    {"user-3", "carol", time.Second},
+    {"user-4", "Dave", time.Second},
+    {"user-5", "erin", 2 * time.Second},
+    {"user-6", "Frank", 2 * time.Second},
+    {"user-7", "grace", 3 * time.Second},
+}
+
+func testPagination(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    for _, u := range paginationUsers {
+        user := newUser(u.id, u.name, u.name+"@example.com")
+        user.CreatedAt = created.Add(u.offset)
+        save(t, repo, user)
+    }
+
+    orders := []struct {
+        sort string
+        want []string
+    }{
+        {"", []string{"alice", "Bob", "carol", "Dave", "erin", "Frank", "grace"}},
+        {"name", []string{"alice", "Bob", "carol", "Dave", "erin", "Frank", "grace"}},
+        {"-name", []string{"grace", "Frank", "erin", "Dave", "carol", "Bob", "alice"}},
+        {"-created_at,name", []string{"grace", "erin", "Frank", "carol", "Dave", "alice", "Bob"}},
+        {"created_at,-id", []string{"Bob", "alice", "Dave", "carol", "Frank", "erin", "grace"}},
+    }
+    for _, o := range orders {
+        sort, err := repository.ParseSort(o.sort)
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, limit := range []int{1, 2, 3, 7, 10} {
+            got := listAll(t, repo, repository.ListOptions{Limit: limit, Sort: sort, IncludeTotal: true}, len(o.want))
+            if !reflect.DeepEqual(got, o.want) {
+                t.Errorf("List(sort=%q, limit=%d) = %v, want %v", o.sort, limit, got, o.want)
+            }
+        }
+    }
+
+    // A cursor holds the position, not an offset: users inserted before
+    // it are not returned and none after it are skipped
+    sort, _ := repository.ParseSort("name")
+    first, err := repo.List(ctx, repository.ListOptions{Limit: 2, Sort: sort})
+    if err != nil || first.NextCursor == "" {
+        t.Fatalf("List() first page = %+v, %v", first, err)
+    }
+    save(t, repo, newUser("user-8", "aaron", "aaron@example.com"))
+    rest := listAll(t, repo, repository.ListOptions{Limit: 2, Sort: sort, Cursor: first.NextCursor}, 5)
+    if want := []string{"carol", "Dave", "erin", "Frank", "grace"}; !reflect.DeepEqual(rest, want) {
+        t.Errorf("List() after an insert = %v, want %v", rest, want)
+    }
+
+    // A cursor only continues the listing it came from
+    byCreation, _ := repository.ParseSort("created_at")
+    if _, err := repo.List(ctx, repository.ListOptions{Sort: byCreation, Cursor: first.NextCursor}); !errors.Is(err, repository.ErrInvalidCursor) {
+        t.Errorf("List() with another sort's cursor = %v, want ErrInvalidCursor", err)
+    }
+    if _, err := repo.List(ctx, repository.ListOptions{Cursor: "not-a-cursor"}); !errors.Is(err, repository.ErrInvalidCursor) {
+        t.Errorf("List() with a malformed cursor = %v, want ErrInvalidCursor", err)
+    }
+}
+
+// listAll follows the cursors of opts and returns the names of every user
+// listed. It fails the test if a page is larger than the limit, the total
+// is wrong, or the listing does not end after want users.
+func listAll(t *testing.T, repo repository.UserRepositoryInterface, opts repository.ListOptions, want int) []string {
+    t.Helper()
+    names := []string{}
+    for pages := 0; ; pages++ {
+        if pages > want {
+            t.Fatalf("List(%+v) did not end after %d pages", opts, pages)
+        }
+        page, err := repo.List(context.Background(), opts)
+        if err != nil {
+            t.Fatalf("List(%+v) error = %v", opts, err)
+        }
+        if len(page.Users) > opts.Limit {
+            t.Errorf("List(limit=%d) returned %d users", opts.Limit, len(page.Users))
+        }
+        if opts.IncludeTotal && (page.Total == nil || *page.Total != want) {
+            t.Errorf("List(%+v) Total = %v, want %d", opts, page.Total, want)
+        }
+        for _, u := range page.Users {
+            names = append(names, u.Name)
+        }
+        if page.NextCursor == "" {
+            return names
+        }
+        opts.Cursor = page.NextCursor
+    }
+}
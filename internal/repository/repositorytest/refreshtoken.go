@@ -0,0 +1,147 @@
+package repositorytest
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+// RefreshTokenFactory returns an empty refresh token repository for one
+// test, together with the user repository its tokens' users are saved in
+type RefreshTokenFactory func(t *testing.T) (repository.UserRepositoryInterface, repository.RefreshTokenRepositoryInterface)
+
+// RunRefreshTokens runs the conformance suite against the refresh token
+// repositories newRepo returns
+func RunRefreshTokens(t *testing.T, newRepo RefreshTokenFactory) {
+    tests := []struct {
+        name string
+        test func(t *testing.T, repo repository.RefreshTokenRepositoryInterface)
+    }{
+        {"SaveAndFind", testRefreshTokenSaveAndFind},
+        {"Revoke", testRefreshTokenRevoke},
+        {"RevokeFamily", testRefreshTokenRevokeFamily},
+        {"DeleteExpired", testRefreshTokenDeleteExpired},
+    }
+    t.Run("RefreshTokens", func(t *testing.T) {
+        for _, tt := range tests {
+            t.Run(tt.name, func(t *testing.T) {
+                users, repo := newRepo(t)
+                save(t, users, newUser("user-1", "Alice", "alice@example.com"))
+                tt.test(t, repo)
+            })
+        }
+    })
+}
+
+func newRefreshToken(id, family string, expires time.Time) model.RefreshToken {
+    return model.RefreshToken{
+        ID:        id,
+        UserID:    "user-1",
+        FamilyID:  family,
+        TokenHash: "hash-" + id,
+        ExpiresAt: expires,
+        CreatedAt: created,
+    }
+}
+
+func saveTokens(t *testing.T, repo repository.RefreshTokenRepositoryInterface, tokens ...model.RefreshToken) {
+    t.Helper()
+    for _, token := range tokens {
+        if err := repo.Save(context.Background(), token); err != nil {
+            t.Fatalf("Save(%s) error = %v", token.ID, err)
+        }
+    }
+}
+
+func testRefreshTokenSaveAndFind(t *testing.T, repo repository.RefreshTokenRepositoryInterface) {
+    ctx := context.Background()
+    expires := time.Now().Add(time.Hour).Truncate(time.Second)
+    saveTokens(t, repo, newRefreshToken("rt-1", "fam-1", expires))
+
+    token, err := repo.FindByHash(ctx, "hash-rt-1")
+    if err != nil {
+        t.Fatalf("FindByHash() error = %v", err)
+    }
+    if token.ID != "rt-1" || token.UserID != "user-1" || token.FamilyID != "fam-1" || token.Revoked() {
+        t.Errorf("FindByHash() = %+v, want active token rt-1", token)
+    }
+    if !token.ExpiresAt.Equal(expires) {
+        t.Errorf("FindByHash() ExpiresAt = %v, want %v", token.ExpiresAt, expires)
+    }
+
+    if _, err := repo.FindByHash(ctx, "hash-missing"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("FindByHash() of a missing token = %v, want ErrNotFound", err)
+    }
+}
+
+func testRefreshTokenRevoke(t *testing.T, repo repository.RefreshTokenRepositoryInterface) {
+    ctx := context.Background()
+    saveTokens(t, repo, newRefreshToken("rt-1", "fam-1", time.Now().Add(time.Hour)))
+
+    if err := repo.Revoke(ctx, "rt-1", "rt-2"); err != nil {
+        t.Fatalf("Revoke() of an active token error = %v", err)
+    }
+    if err := repo.Revoke(ctx, "rt-1", "rt-3"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("Revoke() of a revoked token = %v, want ErrNotFound", err)
+    }
+    if err := repo.Revoke(ctx, "missing", ""); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("Revoke() of a missing token = %v, want ErrNotFound", err)
+    }
+
+    token, err := repo.FindByHash(ctx, "hash-rt-1")
+    if err != nil || !token.Revoked() || token.ReplacedBy != "rt-2" {
+        t.Errorf("FindByHash() after Revoke() = %+v, %v, want revoked and replaced by rt-2", token, err)
+    }
+}
+
+func testRefreshTokenRevokeFamily(t *testing.T, repo repository.RefreshTokenRepositoryInterface) {
+    expires := time.Now().Add(time.Hour)
+    saveTokens(t, repo,
+        newRefreshToken("rt-1", "fam-1", expires),
+        newRefreshToken("rt-2", "fam-1", expires),
+        newRefreshToken("rt-3", "fam-2", expires),
+    )
+
+    if err := repo.RevokeFamily(context.Background(), "fam-1"); err != nil {
+        t.Fatalf("RevokeFamily() error = %v", err)
+    }
+    for id, wantRevoked := range map[string]bool{"rt-1": true, "rt-2": true, "rt-3": false} {
+        token, err := repo.FindByHash(context.Background(), "hash-"+id)
+        if err != nil || token.Revoked() != wantRevoked {
+            t.Errorf("FindByHash(%s) after RevokeFamily() = %+v, %v, want revoked %v", id, token, err, wantRevoked)
+        }
+    }
+}
+
+func testRefreshTokenDeleteExpired(t *testing.T, repo repository.RefreshTokenRepositoryInterface) {
+    ctx := context.Background()
+    now := time.Now()
+    saveTokens(t, repo,
+        newRefreshToken("expired", "fam-1", now.Add(-time.Hour)),
+        newRefreshToken("expired-revoked", "fam-1", now.Add(-time.Hour)),
+        newRefreshToken("revoked", "fam-2", now.Add(time.Hour)),
+        newRefreshToken("active", "fam-2", now.Add(time.Hour)),
+    )
+    for _, id := range []string{"expired-revoked", "revoked"} {
+        if err := repo.Revoke(ctx, id, ""); err != nil {
+            t.Fatalf("Revoke(%s) error = %v", id, err)
+        }
+    }
+
+    deleted, err := repo.DeleteExpired(ctx, now)
+    if err != nil || deleted != 2 {
+        t.Fatalf("DeleteExpired() = %d, %v, want 2", deleted, err)
+    }
+    // Revoked tokens stay until they expire, so replaying one is still
+    // recognised as reuse
+    for id, wantKept := range map[string]bool{"expired": false, "expired-revoked": false, "revoked": true, "active": true} {
+        _, err := repo.FindByHash(ctx, "hash-"+id)
+        if kept := err == nil; kept != wantKept {
+            t.Errorf("FindByHash(%s) after DeleteExpired() = %v, want kept %v", id, err, wantKept)
+        }
+    }
+}
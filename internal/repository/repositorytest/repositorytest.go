@@ -0,0 +1,338 @@
+// Package repositorytest is a conformance suite for implementations of
+// repository.UserRepositoryInterface and RefreshTokenRepositoryInterface.
+// Every backend runs it, so they all behave the same way behind the
+// interfaces the handlers use.
+package repositorytest
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+// Factory returns an empty repository for one test. It registers any
+// cleanup with t.
+type Factory func(t *testing.T) repository.UserRepositoryInterface
+
+// Run runs the conformance suite against the repositories newRepo returns
+func Run(t *testing.T, newRepo Factory) {
+    tests := []struct {
+        name string
+        test func(t *testing.T, repo repository.UserRepositoryInterface)
+    }{
+        {"SaveAndFind", testSaveAndFind},
+        {"Uniqueness", testUniqueness},
+        {"NotFound", testNotFound},
+        {"Versions", testVersions},
+        {"SoftDelete", testSoftDelete},
+        {"SetPassword", testSetPassword},
+        {"Pagination", testPagination},
+        {"FilterAndSearch", testFilterAndSearch},
+        {"ConcurrentWrites", testConcurrentWrites},
+        {"CancelledContext", testCancelledContext},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            tt.test(t, newRepo(t))
+        })
+    }
+}
+
+// created is the creation time of the users the suite saves. Some
+// databases store seconds only.
+var created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newUser(id, name, email string) model.User {
+    return model.User{ID: id, Name: name, Email: email, Password: "hash", Role: model.RoleUser, CreatedAt: created}
+}
+
+func save(t *testing.T, repo repository.UserRepositoryInterface, users ...model.User) {
+    t.Helper()
+    for _, user := range users {
+        if err := repo.Save(context.Background(), user); err != nil {
+            t.Fatalf("Save(%s) error = %v", user.ID, err)
+        }
+    }
+}
+
+func testSaveAndFind(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    user := newUser("user-1", "Alice Smith", "alice@example.com")
+    user.Role = model.RoleAdmin
+    save(t, repo, user)
+
+    found, err := repo.FindById(ctx, "user-1")
+    if err != nil {
+        t.Fatalf("FindById() error = %v", err)
+    }
+    if found.Name != user.Name || found.Email != user.Email || found.Password != user.Password ||
+        found.Role != user.Role || found.Version != 1 || found.Deleted() {
+        t.Errorf("FindById() = %+v, want %+v at version 1", found, user)
+    }
+    if !found.CreatedAt.Equal(created) {
+        t.Errorf("FindById() CreatedAt = %v, want %v", found.CreatedAt, created)
+    }
+
+    found, err = repo.FindByEmail(ctx, "ALICE@Example.com")
+    if err != nil || found.ID != "user-1" {
+        t.Errorf("FindByEmail() with different case = %+v, %v, want user-1", found, err)
+    }
+
+    users, err := repo.GetAll(ctx)
+    if err != nil || len(users) != 1 {
+        t.Errorf("GetAll() = %d users, %v, want 1", len(users), err)
+    }
+}
+
+func testUniqueness(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo, newUser("user-1", "Alice", "alice@example.com"), newUser("user-2", "Bob", "bob@example.com"))
+
+    err := repo.Save(ctx, newUser("user-3", "Other", "Alice@Example.COM"))
+    if !errors.Is(err, repository.ErrConflict) || repository.ConflictField(err) != "email" {
+        t.Errorf("Save() with a taken email = %v, want ErrConflict on email", err)
+    }
+    err = repo.Save(ctx, newUser("user-1", "Other", "other@example.com"))
+    if !errors.Is(err, repository.ErrConflict) || repository.ConflictField(err) != "id" {
+        t.Errorf("Save() with a taken id = %v, want ErrConflict on id", err)
+    }
+
+    bob := newUser("user-2", "Bob", "ALICE@example.com")
+    if err := repo.Update(ctx, bob); !errors.Is(err, repository.ErrConflict) || repository.ConflictField(err) != "email" {
+        t.Errorf("Update() to a taken email = %v, want ErrConflict on email", err)
+    }
+    email := "alice@example.com"
+    if err := repo.Patch(ctx, "user-2", repository.UserChanges{Email: &email}); !errors.Is(err, repository.ErrConflict) {
+        t.Errorf("Patch() to a taken email = %v, want ErrConflict", err)
+    }
+
+    // A deleted user keeps their email reserved
+    if err := repo.Delete(ctx, "user-1", 0); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if err := repo.Save(ctx, newUser("user-3", "Other", "alice@example.com")); !errors.Is(err, repository.ErrConflict) {
+        t.Errorf("Save() with a deleted user's email = %v, want ErrConflict", err)
+    }
+}
+
+func testNotFound(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    name := "Nobody"
+
+    checks := map[string]error{
+        "Update":  repo.Update(ctx, newUser("missing", "Nobody", "nobody@example.com")),
+        "Patch":   repo.Patch(ctx, "missing", repository.UserChanges{Name: &name}),
+        "Delete":  repo.Delete(ctx, "missing", 0),
+        "Restore": repo.Restore(ctx, "missing"),
+    }
+    _, checks["FindById"] = repo.FindById(ctx, "missing")
+    _, checks["FindByIdIncludingDeleted"] = repo.FindByIdIncludingDeleted(ctx, "missing")
+    _, checks["FindByEmail"] = repo.FindByEmail(ctx, "nobody@example.com")
+
+    for method, err := range checks {
+        if !errors.Is(err, repository.ErrNotFound) {
+            t.Errorf("%s() of a missing user = %v, want ErrNotFound", method, err)
+        }
+    }
+}
+
+func testVersions(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo, newUser("user-1", "Alice", "alice@example.com"))
+
+    user := newUser("user-1", "Alice Jones", "alice@example.com")
+    user.Version = 2
+    if err := repo.Update(ctx, user); !errors.Is(err, repository.ErrStale) {
+        t.Errorf("Update() at a wrong version = %v, want ErrStale", err)
+    }
+    user.Version = 1
+    if err := repo.Update(ctx, user); err != nil {
+        t.Fatalf("Update() at the current version error = %v", err)
+    }
+
+    name := "Alice Brown"
+    if err := repo.Patch(ctx, "user-1", repository.UserChanges{Version: 1, Name: &name}); !errors.Is(err, repository.ErrStale) {
+        t.Errorf("Patch() at a wrong version = %v, want ErrStale", err)
+    }
+    if err := repo.Patch(ctx, "user-1", repository.UserChanges{Version: 2, Name: &name}); err != nil {
+        t.Fatalf("Patch() at the current version error = %v", err)
+    }
+    // Version 0 writes unconditionally
+    role := model.RoleAdmin
+    if err := repo.Patch(ctx, "user-1", repository.UserChanges{Role: &role}); err != nil {
+        t.Fatalf("Patch() without a version error = %v", err)
+    }
+
+    found, err := repo.FindById(ctx, "user-1")
+    if err != nil || found.Version != 4 || found.Name != name || found.Role != role {
+        t.Errorf("FindById() = %+v, %v, want version 4 with every change", found, err)
+    }
+    if err := repo.Delete(ctx, "user-1", 3); !errors.Is(err, repository.ErrStale) {
+        t.Errorf("Delete() at a wrong version = %v, want ErrStale", err)
+    }
+}
+
+func testSoftDelete(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo, newUser("user-1", "Alice", "alice@example.com"), newUser("user-2", "Bob", "bob@example.com"))
+
+    if err := repo.Delete(ctx, "user-1", 1); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if _, err := repo.FindById(ctx, "user-1"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("FindById() of a deleted user = %v, want ErrNotFound", err)
+    }
+    if _, err := repo.FindByEmail(ctx, "alice@example.com"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("FindByEmail() of a deleted user = %v, want ErrNotFound", err)
+    }
+    if err := repo.Delete(ctx, "user-1", 0); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("Second Delete() = %v, want ErrNotFound", err)
+    }
+    deleted, err := repo.FindByIdIncludingDeleted(ctx, "user-1")
+    if err != nil || !deleted.Deleted() || deleted.Version != 2 {
+        t.Errorf("FindByIdIncludingDeleted() = %+v, %v, want a deleted user at version 2", deleted, err)
+    }
+
+    page, err := repo.List(ctx, repository.ListOptions{})
+    if err != nil || len(page.Users) != 1 {
+        t.Errorf("List() = %d users, %v, want the one not deleted", len(page.Users), err)
+    }
+    page, err = repo.List(ctx, repository.ListOptions{IncludeDeleted: true})
+    if err != nil || len(page.Users) != 2 {
+        t.Errorf("List() including deleted = %d users, %v, want 2", len(page.Users), err)
+    }
+
+    if err := repo.Restore(ctx, "user-1"); err != nil {
+        t.Fatalf("Restore() error = %v", err)
+    }
+    if err := repo.Restore(ctx, "user-1"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("Restore() of a user that is not deleted = %v, want ErrNotFound", err)
+    }
+    restored, err := repo.FindById(ctx, "user-1")
+    if err != nil || restored.Deleted() || restored.Version != 3 {
+        t.Errorf("FindById() after Restore() = %+v, %v, want version 3", restored, err)
+    }
+
+    // Only users deleted before the cutoff are purged
+    repo.Delete(ctx, "user-1", 0)
+    if purged, err := repo.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+        t.Errorf("Purge() before the deletion = %d, %v, want 0", purged, err)
+    }
+    if purged, err := repo.Purge(ctx, time.Now().Add(time.Hour)); err != nil || purged != 1 {
+        t.Errorf("Purge() after the deletion = %d, %v, want 1", purged, err)
+    }
+    if _, err := repo.FindByIdIncludingDeleted(ctx, "user-1"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("FindByIdIncludingDeleted() of a purged user = %v, want ErrNotFound", err)
+    }
+    if _, err := repo.FindById(ctx, "user-2"); err != nil {
+        t.Errorf("FindById() of a user not deleted after Purge() = %v", err)
+    }
+}
+
+func testSetPassword(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo, newUser("user-1", "Alice", "alice@example.com"))
+
+    // Deleted users are included and the version does not change
+    if err := repo.Delete(ctx, "user-1", 1); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if err := repo.SetPassword(ctx, "user-1", "new-hash"); err != nil {
+        t.Fatalf("SetPassword() of a deleted user error = %v", err)
+    }
+    user, err := repo.FindByIdIncludingDeleted(ctx, "user-1")
+    if err != nil || user.Password != "new-hash" || user.Version != 2 {
+        t.Errorf("FindByIdIncludingDeleted() after SetPassword() = %+v, %v, want the new hash at version 2", user, err)
+    }
+
+    if err := repo.SetPassword(ctx, "missing", "new-hash"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("SetPassword() of a missing user = %v, want ErrNotFound", err)
+    }
+}
+
+// testCancelledContext checks that every method reports a cancelled
+// request as ErrUnavailable, still matching context.Canceled, and that
+// nothing is written
+func testCancelledContext(t *testing.T, repo repository.UserRepositoryInterface) {
+    save(t, repo, newUser("user-1", "Alice", "alice@example.com"))
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    check := func(op string, err error) {
+        t.Helper()
+        if !errors.Is(err, repository.ErrUnavailable) || !errors.Is(err, context.Canceled) {
+            t.Errorf("%s with a cancelled context = %v, want ErrUnavailable wrapping context.Canceled", op, err)
+        }
+    }
+    _, err := repo.GetAll(ctx)
+    check("GetAll()", err)
+    _, err = repo.List(ctx, repository.ListOptions{})
+    check("List()", err)
+    _, err = repo.Search(ctx, repository.SearchOptions{Query: "alice"})
+    check("Search()", err)
+    _, err = repo.Purge(ctx, time.Now())
+    check("Purge()", err)
+    check("Save()", repo.Save(ctx, newUser("user-2", "Bob", "bob@example.com")))
+    _, err = repo.FindById(ctx, "user-1")
+    check("FindById()", err)
+    _, err = repo.FindByIdIncludingDeleted(ctx, "user-1")
+    check("FindByIdIncludingDeleted()", err)
+    _, err = repo.FindByEmail(ctx, "alice@example.com")
+    check("FindByEmail()", err)
+    user := newUser("user-1", "Alice Smith", "alice@example.com")
+    user.Version = 1
+    check("Update()", repo.Update(ctx, user))
+    name := "Alice Jones"
+    check("Patch()", repo.Patch(ctx, "user-1", repository.UserChanges{Name: &name, Version: 1}))
+    check("SetPassword()", repo.SetPassword(ctx, "user-1", "new-hash"))
+    check("Delete()", repo.Delete(ctx, "user-1", 1))
+    check("Restore()", repo.Restore(ctx, "user-1"))
+
+    if _, err := repo.FindById(context.Background(), "user-2"); !errors.Is(err, repository.ErrNotFound) {
+        t.Errorf("FindById() after Save() with a cancelled context = %v, want ErrNotFound", err)
+    }
+    stored, err := repo.FindById(context.Background(), "user-1")
+    if err != nil || stored.Name != "Alice" || stored.Password != "hash" || stored.Version != 1 {
+        t.Errorf("FindById() after writes with a cancelled context = %+v, %v, want user-1 unchanged", stored, err)
+    }
+}
+
+func testFilterAndSearch(t *testing.T, repo repository.UserRepositoryInterface) {
+    ctx := context.Background()
+    save(t, repo,
+        newUser("user-1", "bob", "bob@example.com"),
+        newUser("user-2", "Carol", "carol@example.com"),
+        newUser("user-3", "alice", "alice@example.com"),
+        newUser("user-4", "Alice_2", "alice2@example.org"),
+    )
+
+    // Prefixes match case-insensitively and LIKE wildcards literally
+    filters := []struct {
+        filter repository.UserFilter
+        want   int
+    }{
+        {repository.UserFilter{NamePrefix: "ALI"}, 2},
+        {repository.UserFilter{NamePrefix: "Alice_"}, 1},
+        {repository.UserFilter{EmailPrefix: "alice2@"}, 1},
+        {repository.UserFilter{EmailPrefix: "%"}, 0},
+    }
+    for _, f := range filters {
+        page, err := repo.List(ctx, repository.ListOptions{Filter: f.filter})
+        if err != nil || len(page.Users) != f.want {
+            t.Errorf("List(%+v) = %d users, %v, want %d", f.filter, len(page.Users), err, f.want)
+        }
+    }
+
+    results, err := repo.Search(ctx, repository.SearchOptions{Query: "car"})
+    if err != nil || len(results) != 1 || results[0].User.ID != "user-2" {
+        t.Errorf("Search(car) = %+v, %v, want user-2", results, err)
+    }
+    results, err = repo.Search(ctx, repository.SearchOptions{Query: "nobody"})
+    if err != nil || len(results) != 0 {
+        t.Errorf("Search(nobody) = %+v, %v, want no results", results, err)
+    }
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+    "errors"
+    "html"
+    "sort"
+    "strings"
+    "unicode"
+
+    "go-crud-api/internal/model"
+)
+
+// ErrEmptySearch is returned when a search query contains no searchable terms
+var ErrEmptySearch = errors.New("search query has no terms")
+
+// SearchOptions controls a user search
+type SearchOptions struct {
+    Query string
+    Limit int
+}
+
+// SearchResult is a user matching a search together with its relevance
+// score and HTML highlighted fields, keyed by field name, for every field
+// that matched
+type SearchResult struct {
+    User       model.User
+    Score      float64
+    Highlights map[string]string
+}
+
+// Search field weights: a match in the name is worth more than one in the email
+const (
+    nameWeight  = 2.0
+    emailWeight = 1.0
+)
+
+// Tokenize lowercases s and splits it into runs of letters and digits,
+// so "John.Doe@example.com" becomes [john doe example com]
+func Tokenize(s string) []string {
+    return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+}
+
+func (o SearchOptions) normalize() (SearchOptions, []string, error) {
+    if o.Limit <= 0 {
+        o.Limit = DefaultPageSize
+    }
+    if o.Limit > MaxPageSize {
+        o.Limit = MaxPageSize
+    }
+
+    terms := Tokenize(o.Query)
+    if len(terms) == 0 {
+        return o, nil, ErrEmptySearch
+    }
+    return o, terms, nil
+}
+
+// scoreTokens rates how well terms match the tokens of one field: an
+// exact token match scores 3, a token prefix 2 and any other substring 1
+func scoreTokens(tokens []string, term string) float64 {
+    best := 0.0
+    for _, token := range tokens {
+        switch {
+        case token == term:
+            return 3
+        case strings.HasPrefix(token, term):
+            best = 2
+        case best < 1 && strings.Contains(token, term):
+            best = 1
+        }
+    }
+    return best
+}
+
+// scoreUser returns the relevance of u for terms, or 0 unless every term
+// matches the name or the email. With prefixOnly, substring matches
+// inside a token do not count as a match, mirroring a FULLTEXT prefix
+// search.
+func scoreUser(u model.User, terms []string, prefixOnly bool) float64 {
+    nameTokens := Tokenize(u.Name)
+    emailTokens := Tokenize(u.Email)
+
+    total := 0.0
+    for _, term := range terms {
+        name := scoreTokens(nameTokens, term)
+        email := scoreTokens(emailTokens, term)
+        if prefixOnly {
+            if name < 2 {
+                name = 0
+            }
+            if email < 2 {
+                email = 0
+            }
+        }
+        if name == 0 && email == 0 {
+            return 0
+        }
+        total += name*nameWeight + email*emailWeight
+    }
+    return total
+}
+
+// highlight HTML-escapes text and wraps every case-insensitive occurrence
+// of any term in <em> tags. ok is false when no term occurs in text.
+func highlight(text string, terms []string) (highlighted string, ok bool) {
+    var b strings.Builder
+    last := 0
+    for i := 0; i < len(text); {
+        matched := 0
+        for _, term := range terms {
+            if len(term) > matched && len(text)-i >= len(term) && strings.EqualFold(text[i:i+len(term)], term) {
+                matched = len(term)
+            }
+        }
+        if matched == 0 {
+            i++
+            continue
+        }
+        b.WriteString(html.EscapeString(text[last:i]))
+        b.WriteString("<em>")
+        b.WriteString(html.EscapeString(text[i : i+matched]))
+        b.WriteString("</em>")
+        i += matched
+        last = i
+        ok = true
+    }
+    b.WriteString(html.EscapeString(text[last:]))
+    return b.String(), ok
+}
+
+// newSearchResult builds the result for u with highlights for every
+// matching field
+func newSearchResult(u model.User, terms []string, score float64) SearchResult {
+    result := SearchResult{User: u, Score: score, Highlights: make(map[string]string)}
+    if h, ok := highlight(u.Name, terms); ok {
+        result.Highlights["name"] = h
+    }
+    if h, ok := highlight(u.Email, terms); ok {
+        result.Highlights["email"] = h
+    }
+    return result
+}
+
+// rankResults orders results by descending score, then id for stability,
+// and truncates them to limit
+func rankResults(results []SearchResult, limit int) []SearchResult {
+    sort.Slice(results, func(i, j int) bool {
+        if results[i].Score != results[j].Score {
+            return results[i].Score > results[j].Score
+        }
+        return results[i].User.ID < results[j].User.ID
+    })
+    if len(results) > limit {
+        results = results[:limit]
+    }
+    return results
+}
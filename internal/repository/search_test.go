@@ -0,0 +1,110 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+
+    "go-crud-api/internal/model"
+)
+
+func TestTokenize(t *testing.T) {
+    got := Tokenize("John O'Brien <John.Doe+1@Example.com>")
+    want := []string{"john", "o", "brien", "john", "doe", "1", "example", "com"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Tokenize() = %v, want %v", got, want)
+    }
+}
+
+func TestHighlight(t *testing.T) {
+    tests := []struct {
+        text   string
+        terms  []string
+        want   string
+        wantOK bool
+    }{
+        {text: "John Johnson", terms: []string{"john"}, want: "<em>John</em> <em>John</em>son", wantOK: true},
+        {text: "john@example.com", terms: []string{"jo", "john"}, want: "<em>john</em>@example.com", wantOK: true},
+        {text: "<b>Bob</b>", terms: []string{"bob"}, want: "&lt;b&gt;<em>Bob</em>&lt;/b&gt;", wantOK: true},
+        {text: "Alice", terms: []string{"bob"}, want: "Alice", wantOK: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.text, func(t *testing.T) {
+            got, ok := highlight(tt.text, tt.terms)
+            if got != tt.want || ok != tt.wantOK {
+                t.Errorf("highlight() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+            }
+        })
+    }
+}
+
+func TestScoreUser(t *testing.T) {
+    user := model.User{Name: "John Smith", Email: "jsmith@example.com"}
+
+    tests := []struct {
+        name       string
+        terms      []string
+        prefixOnly bool
+        want       float64
+    }{
+        {name: "exact name token", terms: []string{"john"}, want: 3 * nameWeight},
+        {name: "prefix in name and email", terms: []string{"jsm"}, want: 2 * emailWeight},
+        {name: "all terms must match", terms: []string{"john", "nobody"}, want: 0},
+        {name: "substring match", terms: []string{"mith"}, want: 1*nameWeight + 1*emailWeight},
+        {name: "substring ignored in prefix mode", terms: []string{"mith"}, prefixOnly: true, want: 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := scoreUser(user, tt.terms, tt.prefixOnly); got != tt.want {
+                t.Errorf("scoreUser() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestMockUserRepository_Search(t *testing.T) {
+    repo := NewMockUserRepository()
+    repo.Save(context.Background(), model.User{ID: "1", Name: "John Smith", Email: "jsmith@example.com"})
+    repo.Save(context.Background(), model.User{ID: "2", Name: "Johnny Appleseed", Email: "johnny@example.com"})
+    repo.Save(context.Background(), model.User{ID: "3", Name: "Alice Johnson", Email: "alice@example.com"})
+    repo.Save(context.Background(), model.User{ID: "4", Name: "Bob", Email: "bob.john@example.com"})
+
+    results, err := repo.Search(context.Background(), SearchOptions{Query: "john"})
+    if err != nil {
+        t.Fatalf("Search returned error: %v", err)
+    }
+
+    var ids []string
+    for _, r := range results {
+        ids = append(ids, r.User.ID)
+    }
+    // An exact name token ties with prefixes in both name and email (ties
+    // are broken by id), both beat a name prefix, which beats an email token
+    if want := []string{"1", "2", "3", "4"}; !reflect.DeepEqual(ids, want) {
+        t.Errorf("Got ranking %v, want %v", ids, want)
+    }
+
+    if got := results[0].Highlights["name"]; got != "<em>John</em> Smith" {
+        t.Errorf("Unexpected name highlight %q", got)
+    }
+    if _, ok := results[0].Highlights["email"]; ok {
+        t.Error("Email did not match and should not be highlighted")
+    }
+
+    results, _ = repo.Search(context.Background(), SearchOptions{Query: "john app", Limit: 10})
+    if len(results) != 1 || results[0].User.ID != "2" {
+        t.Errorf("Expected only Johnny Appleseed to match all terms, got %+v", results)
+    }
+
+    results, _ = repo.Search(context.Background(), SearchOptions{Query: "john", Limit: 1})
+    if len(results) != 1 {
+        t.Errorf("Expected limit to be applied, got %d results", len(results))
+    }
+
+    if _, err := repo.Search(context.Background(), SearchOptions{Query: " @. "}); !errors.Is(err, ErrEmptySearch) {
+        t.Errorf("Expected ErrEmptySearch, got %v", err)
+    }
+}
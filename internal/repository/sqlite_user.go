@@ -0,0 +1,33 @@
+package repository
+
+import (
+    "context"
+
+    "go-crud-api/internal/database"
+)
+
+var _ UserRepositoryInterface = (*SQLiteUserRepository)(nil)
+
+// SQLiteUserRepository stores users in SQLite. It shares the queries of
+// UserRepository, which stick to SQL both databases accept, except search:
+// SQLite has no FULLTEXT index, so every search matches with LIKE.
+type SQLiteUserRepository struct {
+    *UserRepository
+}
+
+func NewSQLiteUserRepository(db *database.DB) *SQLiteUserRepository {
+    return &SQLiteUserRepository{
+        UserRepository: NewUserRepository(db),
+    }
+}
+
+func (r *SQLiteUserRepository) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    opts, terms, err := opts.normalize()
+    if err != nil {
+        return nil, err
+    }
+    return r.searchLike(ctx, terms, opts.Limit)
+}
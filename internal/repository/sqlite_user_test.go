@@ -0,0 +1,128 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "go-crud-api/internal/database"
+    "go-crud-api/internal/migrate"
+    "go-crud-api/internal/model"
+)
+
+func newSQLiteUserRepository(t *testing.T) *SQLiteUserRepository {
+    t.Helper()
+    db, err := database.NewSQLiteConnection(":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    migrator, err := migrate.New(db.DB, migrate.SQLite{}, migrate.SQLiteMigrations())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err := migrator.Up(context.Background()); err != nil {
+        t.Fatalf("Failed to migrate: %v", err)
+    }
+    // Start without the sample users
+    if _, err := db.Exec(`DELETE FROM users`); err != nil {
+        t.Fatal(err)
+    }
+    return NewSQLiteUserRepository(db)
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+    ctx := context.Background()
+    repo := newSQLiteUserRepository(t)
+    created := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+    user := model.User{ID: "sqlite-1", Name: "Alice Smith", Email: "alice@example.com", Password: "hash", Role: model.RoleUser, CreatedAt: created}
+    if err := repo.Save(ctx, user); err != nil {
+        t.Fatalf("Save() error = %v", err)
+    }
+    found, err := repo.FindByEmail(ctx, "ALICE@example.com")
+    if err != nil || found.ID != "sqlite-1" || found.Version != 1 || !found.CreatedAt.Equal(created) {
+        t.Fatalf("FindByEmail() = %+v, %v", found, err)
+    }
+
+    // Uniqueness is case-insensitive and names the field
+    err = repo.Save(ctx, model.User{ID: "sqlite-2", Name: "Other", Email: "Alice@Example.com", Role: model.RoleUser})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "email" {
+        t.Errorf("Save() with a taken email = %v, want ErrConflict on email", err)
+    }
+    err = repo.Save(ctx, model.User{ID: "sqlite-1", Name: "Other", Email: "other@example.com", Role: model.RoleUser})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "id" {
+        t.Errorf("Save() with a taken id = %v, want ErrConflict on id", err)
+    }
+
+    if err := repo.Update(ctx, model.User{ID: "sqlite-1", Name: "Alice Jones", Email: "alice@example.com", Role: model.RoleUser, Version: 2}); !errors.Is(err, ErrStale) {
+        t.Errorf("Update() at a wrong version = %v, want ErrStale", err)
+    }
+    name := "Alice Jones"
+    if err := repo.Patch(ctx, "sqlite-1", UserChanges{Version: 1, Name: &name}); err != nil {
+        t.Errorf("Patch() error = %v", err)
+    }
+
+    results, err := repo.Search(ctx, SearchOptions{Query: "jon"})
+    if err != nil || len(results) != 1 || results[0].User.ID != "sqlite-1" {
+        t.Errorf("Search() = %+v, %v", results, err)
+    }
+    // LIKE wildcards in filters match literally
+    page, err := repo.List(ctx, ListOptions{Filter: UserFilter{NamePrefix: "Alice_"}})
+    if err != nil || len(page.Users) != 0 {
+        t.Errorf("List() with a wildcard prefix = %+v, %v, want no users", page.Users, err)
+    }
+
+    if err := repo.Delete(ctx, "sqlite-1", 2); err != nil {
+        t.Fatalf("Delete() error = %v", err)
+    }
+    if _, err := repo.FindById(ctx, "sqlite-1"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("FindById() of a deleted user = %v, want ErrNotFound", err)
+    }
+    purged, err := repo.Purge(ctx, time.Now().Add(time.Minute))
+    if err != nil || purged != 1 {
+        t.Errorf("Purge() = %d, %v, want 1", purged, err)
+    }
+}
+
+func TestSQLiteUserRepository_ListPagination(t *testing.T) {
+    ctx := context.Background()
+    repo := newSQLiteUserRepository(t)
+    base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+    names := []string{"bob", "Carol", "alice", "dave", "Eve"}
+    for i, name := range names {
+        // Two users share a creation time so the id breaks the tie
+        created := base.Add(time.Duration(i/2) * time.Millisecond)
+        repo.Save(ctx, model.User{ID: name, Name: name, Email: name + "@example.com", Role: model.RoleUser, CreatedAt: created})
+    }
+
+    for _, sort := range []string{"name", "-created_at,name"} {
+        fields, err := ParseSort(sort)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var got []string
+        opts := ListOptions{Limit: 2, Sort: fields}
+        for {
+            page, err := repo.List(ctx, opts)
+            if err != nil {
+                t.Fatalf("List(%s) error = %v", sort, err)
+            }
+            for _, u := range page.Users {
+                got = append(got, u.Name)
+            }
+            if page.NextCursor == "" {
+                break
+            }
+            opts.Cursor = page.NextCursor
+        }
+        if len(got) != len(names) {
+            t.Errorf("List(%s) returned %v, want every user once", sort, got)
+        }
+        if sort == "name" && (got[0] != "alice" || got[2] != "Carol") {
+            t.Errorf("List(name) = %v, want case-insensitive order", got)
+        }
+    }
+}
@@ -1,91 +1,528 @@
 package repository
 
 import (
+    "context"
     "database/sql"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/go-sql-driver/mysql"
     "go-crud-api/internal/model"
     "go-crud-api/internal/database"
 )
 
+// fullTextMinTokenSize is InnoDB's default innodb_ft_min_token_size;
+// shorter terms are not indexed, so FULLTEXT search cannot find them
+const fullTextMinTokenSize = 3
+
+// mysqlErrNoFullTextIndex is "Can't find FULLTEXT index matching the column list"
+const mysqlErrNoFullTextIndex = 1191
+
+var _ UserRepositoryInterface = (*UserRepository)(nil)
+
+// userColumns are the columns scanned by userFields, in order
+const userColumns = `id, name, email, password, role, created_at, version, deleted_at`
+
+// userFields returns the scan destinations for userColumns
+func userFields(u *model.User) []interface{} {
+    return []interface{}{&u.ID, &u.Name, &u.Email, &u.Password, &u.Role, &u.CreatedAt, &u.Version, &u.DeletedAt}
+}
+
+// userUniqueKeys maps the unique keys of the users table, as named by
+// duplicateKeyName, to the fields they constrain
+var userUniqueKeys = map[string]string{
+    "PRIMARY":        "id",
+    "uq_users_email": "email",
+    // SQLite reports columns rather than index names
+    "users.id":    "id",
+    "users.email": "email",
+    // PostgreSQL's default name for the primary key
+    "users_pkey": "id",
+}
+
 type UserRepository struct {
-    db *database.MySQLDB
+    db *database.DB
 }
 
-func NewUserRepository(db *database.MySQLDB) *UserRepository {
+func NewUserRepository(db *database.DB) *UserRepository {
     return &UserRepository{
         db: db,
     }
 }
 
-func (r *UserRepository) GetAll() ([]model.User, error) {
-    query := `SELECT id, name, email, password FROM users`
-    rows, err := r.db.Query(query)
+func (r *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `SELECT ` + userColumns + ` FROM users WHERE deleted_at IS NULL`
+    rows, err := r.db.QueryContext(ctx, query)
     if err != nil {
-        return nil, err
+        return nil, wrapError("get all users", err)
     }
     defer rows.Close()
     
     var users []model.User
     for rows.Next() {
         var user model.User
-        err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Password)
+        err := rows.Scan(userFields(&user)...)
         if err != nil {
-            return nil, err
+            return nil, wrapError("get all users", err)
         }
         users = append(users, user)
     }
     
-    return users, nil
+    return users, wrapError("get all users", rows.Err())
 }
 
-func (r *UserRepository) Save(user model.User) error {
-    query := `INSERT INTO users (id, name, email, password) VALUES (?, ?, ?, ?)`
-    _, err := r.db.Exec(query, user.ID, user.Name, user.Email, user.Password)
-    return err
+func (r *UserRepository) List(ctx context.Context, opts ListOptions) (UserPage, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    opts = opts.normalize()
+
+    var conditions []string
+    var args []interface{}
+    if !opts.IncludeDeleted {
+        conditions = append(conditions, `deleted_at IS NULL`)
+    }
+    if opts.Filter.NamePrefix != "" {
+        conditions = append(conditions, `name LIKE ? ESCAPE '!'`)
+        args = append(args, escapeLike(opts.Filter.NamePrefix)+"%")
+    }
+    if opts.Filter.EmailPrefix != "" {
+        conditions = append(conditions, `email LIKE ? ESCAPE '!'`)
+        args = append(args, escapeLike(opts.Filter.EmailPrefix)+"%")
+    }
+
+    page := UserPage{Users: []model.User{}}
+    if opts.IncludeTotal {
+        var total int
+        query := `SELECT COUNT(*) FROM users` + whereClause(conditions)
+        if err := r.db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+            return page, wrapError("count users", err)
+        }
+        page.Total = &total
+    }
+
+    if opts.Cursor != "" {
+        values, err := decodeCursor(opts.Cursor, opts.Sort)
+        if err != nil {
+            return page, err
+        }
+        condition, keysetArgs := keysetCondition(opts.Sort, values)
+        conditions = append(conditions, condition)
+        args = append(args, keysetArgs...)
+    }
+
+    orderBy := make([]string, len(opts.Sort))
+    for i, f := range opts.Sort {
+        orderBy[i] = f.Field + " ASC"
+        if f.Desc {
+            orderBy[i] = f.Field + " DESC"
+        }
+    }
+
+    // Fetch one extra row to find out whether there is a next page
+    query := `SELECT ` + userColumns + ` FROM users` + whereClause(conditions) +
+        ` ORDER BY ` + strings.Join(orderBy, ", ") + ` LIMIT ?`
+    args = append(args, opts.Limit+1)
+
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return page, wrapError("list users", err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var user model.User
+        err := rows.Scan(userFields(&user)...)
+        if err != nil {
+            return page, wrapError("list users", err)
+        }
+        page.Users = append(page.Users, user)
+    }
+    if err := rows.Err(); err != nil {
+        return page, wrapError("list users", err)
+    }
+
+    if len(page.Users) > opts.Limit {
+        page.Users = page.Users[:opts.Limit]
+        page.NextCursor = encodeCursor(opts.Sort, page.Users[opts.Limit-1])
+    }
+
+    return page, nil
+}
+
+// keysetCondition builds the predicate selecting rows after the cursor
+// values in the given sort order, e.g. for "name,-created_at,id":
+//
+//   (name > ?) OR (name = ? AND created_at < ?) OR (name = ? AND created_at = ? AND id > ?)
+func keysetCondition(sort []SortField, values []string) (string, []interface{}) {
+    var alternatives []string
+    var args []interface{}
+
+    for i, f := range sort {
+        var terms []string
+        for j := 0; j < i; j++ {
+            terms = append(terms, sort[j].Field+" = ?")
+            args = append(args, keysetArg(sort[j].Field, values[j]))
+        }
+        op := ">"
+        if f.Desc {
+            op = "<"
+        }
+        terms = append(terms, fmt.Sprintf("%s %s ?", f.Field, op))
+        args = append(args, keysetArg(f.Field, values[i]))
+        alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+    }
+
+    return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+func keysetArg(field, value string) interface{} {
+    if field == "created_at" {
+        // decodeCursor has already validated the format
+        t, _ := time.Parse(cursorTimeFormat, value)
+        return t
+    }
+    return value
+}
+
+func whereClause(conditions []string) string {
+    if len(conditions) == 0 {
+        return ""
+    }
+    return ` WHERE ` + strings.Join(conditions, " AND ")
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+// with ESCAPE '!'. A backslash is the default escape character in MySQL
+// only, and is written differently in each dialect's string literals.
+func escapeLike(s string) string {
+    return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
+
+// Search uses the FULLTEXT index on (name, email) in boolean mode, with
+// every term required and matched as a prefix. Terms too short for the
+// index, or a database without the index, fall back to LIKE matching
+// ranked in Go.
+func (r *UserRepository) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    opts, terms, err := opts.normalize()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, term := range terms {
+        if len(term) < fullTextMinTokenSize {
+            return r.searchLike(ctx, terms, opts.Limit)
+        }
+    }
+
+    results, err := r.searchFullText(ctx, terms, opts.Limit)
+    var mysqlErr *mysql.MySQLError
+    if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoFullTextIndex {
+        return r.searchLike(ctx, terms, opts.Limit)
+    }
+    return results, wrapError("search users", err)
 }
 
-func (r *UserRepository) FindById(id string) (model.User, bool) {
+func (r *UserRepository) searchFullText(ctx context.Context, terms []string, limit int) ([]SearchResult, error) {
+    boolean := make([]string, len(terms))
+    for i, term := range terms {
+        boolean[i] = "+" + term + "*"
+    }
+    against := strings.Join(boolean, " ")
+
+    query := `SELECT ` + userColumns + `,
+                     MATCH(name, email) AGAINST (? IN BOOLEAN MODE) AS score
+              FROM users
+              WHERE MATCH(name, email) AGAINST (? IN BOOLEAN MODE) AND deleted_at IS NULL
+              ORDER BY score DESC, id
+              LIMIT ?`
+    rows, err := r.db.QueryContext(ctx, query, against, against, limit)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    results := []SearchResult{}
+    for rows.Next() {
+        var user model.User
+        var score float64
+        err := rows.Scan(append(userFields(&user), &score)...)
+        if err != nil {
+            return nil, err
+        }
+        results = append(results, newSearchResult(user, terms, score))
+    }
+    return results, rows.Err()
+}
+
+func (r *UserRepository) searchLike(ctx context.Context, terms []string, limit int) ([]SearchResult, error) {
+    conditions := []string{`deleted_at IS NULL`}
+    args := make([]interface{}, 0, 2*len(terms))
+    for _, term := range terms {
+        conditions = append(conditions, `(name LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!')`)
+        pattern := "%" + escapeLike(term) + "%"
+        args = append(args, pattern, pattern)
+    }
+
+    // Rank in Go, so fetch a bounded candidate set larger than the page
+    query := `SELECT ` + userColumns + ` FROM users` +
+        whereClause(conditions) + ` LIMIT ?`
+    args = append(args, MaxPageSize*10)
+
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, wrapError("search users", err)
+    }
+    defer rows.Close()
+
+    results := []SearchResult{}
+    for rows.Next() {
+        var user model.User
+        err := rows.Scan(userFields(&user)...)
+        if err != nil {
+            return nil, wrapError("search users", err)
+        }
+        if score := scoreUser(user, terms, false); score > 0 {
+            results = append(results, newSearchResult(user, terms, score))
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return nil, wrapError("search users", err)
+    }
+    return rankResults(results, limit), nil
+}
+
+func (r *UserRepository) Save(ctx context.Context, user model.User) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    // SQLite compares times as text, so they are always stored in UTC
+    query := `INSERT INTO users (id, name, email, password, role, created_at, version) VALUES (?, ?, ?, ?, ?, ?, 1)`
+    _, err := r.db.ExecContext(ctx, query, user.ID, user.Name, user.Email, user.Password, user.Role, user.CreatedAt.UTC())
+    return wrapUserWriteError("save user", err)
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id string) (model.User, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    var user model.User
+    query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NULL`
+    err := r.db.QueryRowContext(ctx, query, id).Scan(userFields(&user)...)
+    
+    return user, wrapError("find user by id", err)
+}
+
+func (r *UserRepository) FindByIdIncludingDeleted(ctx context.Context, id string) (model.User, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    var user model.User
+    query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+    err := r.db.QueryRowContext(ctx, query, id).Scan(userFields(&user)...)
+    
+    return user, wrapError("find user by id", err)
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
     var user model.User
-    query := `SELECT id, name, email, password FROM users WHERE id = ?`
-    err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Password)
+    query := `SELECT ` + userColumns + ` FROM users WHERE email = ? AND deleted_at IS NULL`
+    err := r.db.QueryRowContext(ctx, query, email).Scan(userFields(&user)...)
     
+    return user, wrapError("find user by email", err)
+}
+
+// Update relies on the clientFoundRows DSN option so that an update that
+// changes nothing still counts the matched row and is not reported as
+// ErrNotFound
+func (r *UserRepository) Update(ctx context.Context, user model.User) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE users SET name = ?, email = ?, password = ?, role = ?, version = version + 1
+              WHERE id = ? AND deleted_at IS NULL` + versionCondition(user.Version)
+    args := []interface{}{user.Name, user.Email, user.Password, user.Role, user.ID}
+    result, err := r.db.ExecContext(ctx, query, append(args, versionArgs(user.Version)...)...)
     if err != nil {
-        if err == sql.ErrNoRows {
-            return user, false
-        }
-        return user, false
+        return wrapUserWriteError("update user", err)
     }
     
-    return user, true
+    return r.requireWritten(ctx, "update user", user.ID, result)
 }
 
-func (r *UserRepository) Update(user model.User) bool {
-    query := `UPDATE users SET name = ?, email = ?, password = ? WHERE id = ?`
-    result, err := r.db.Exec(query, user.Name, user.Email, user.Password, user.ID)
+// Patch updates only the columns named in changes
+func (r *UserRepository) Patch(ctx context.Context, id string, changes UserChanges) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    if changes.Empty() {
+        // Nothing to write, but a missing user or a stale version must
+        // still be reported
+        return r.checkVersion(ctx, "patch user", id, changes.Version)
+    }
+
+    sets := []string{"version = version + 1"}
+    var args []interface{}
+    if changes.Name != nil {
+        sets = append(sets, "name = ?")
+        args = append(args, *changes.Name)
+    }
+    if changes.Email != nil {
+        sets = append(sets, "email = ?")
+        args = append(args, *changes.Email)
+    }
+    if changes.Password != nil {
+        sets = append(sets, "password = ?")
+        args = append(args, *changes.Password)
+    }
+    if changes.Role != nil {
+        sets = append(sets, "role = ?")
+        args = append(args, *changes.Role)
+    }
+
+    query := `UPDATE users SET ` + strings.Join(sets, ", ") + ` WHERE id = ? AND deleted_at IS NULL` +
+        versionCondition(changes.Version)
+    args = append(args, id)
+    result, err := r.db.ExecContext(ctx, query, append(args, versionArgs(changes.Version)...)...)
+    if err != nil {
+        return wrapUserWriteError("patch user", err)
+    }
     
+    return r.requireWritten(ctx, "patch user", id, result)
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, id string, hash string) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE users SET password = ? WHERE id = ?`
+    result, err := r.db.ExecContext(ctx, query, hash, id)
+    return requireRowsAffected("set user password", result, err)
+}
+
+// Delete soft-deletes the user by setting deleted_at; Purge removes the
+// row once the retention period has passed
+func (r *UserRepository) Delete(ctx context.Context, id string, version int64) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE users SET deleted_at = ?, version = version + 1
+              WHERE id = ? AND deleted_at IS NULL` + versionCondition(version)
+    args := []interface{}{time.Now().UTC(), id}
+    result, err := r.db.ExecContext(ctx, query, append(args, versionArgs(version)...)...)
     if err != nil {
-        return false
+        return wrapError("delete user", err)
     }
     
-    rowsAffected, err := result.RowsAffected()
+    return r.requireWritten(ctx, "delete user", id, result)
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE users SET deleted_at = NULL, version = version + 1
+              WHERE id = ? AND deleted_at IS NOT NULL`
+    result, err := r.db.ExecContext(ctx, query, id)
+    return requireRowsAffected("restore user", result, err)
+}
+
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+    ctx, cancel := r.db.WithQueryTimeout(ctx)
+    defer cancel()
+
+    query := `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+    result, err := r.db.ExecContext(ctx, query, deletedBefore.UTC())
     if err != nil {
-        return false
+        return 0, wrapError("purge users", err)
     }
     
-    return rowsAffected > 0
+    purged, err := result.RowsAffected()
+    return purged, wrapError("purge users", err)
+}
+
+// versionCondition restricts a write to the expected version; version 0
+// means the write is unconditional
+func versionCondition(version int64) string {
+    if version == 0 {
+        return ""
+    }
+    return ` AND version = ?`
+}
+
+func versionArgs(version int64) []interface{} {
+    if version == 0 {
+        return nil
+    }
+    return []interface{}{version}
 }
 
-func (r *UserRepository) Delete(id string) bool {
-    query := `DELETE FROM users WHERE id = ?`
-    result, err := r.db.Exec(query, id)
+// requireWritten reports why a conditional write touched no row: the user
+// is gone (ErrNotFound) or has a different version (ErrStale)
+func (r *UserRepository) requireWritten(ctx context.Context, op, id string, result sql.Result) error {
+    err := requireRowsAffected(op, result, nil)
+    if !errors.Is(err, ErrNotFound) {
+        return err
+    }
     
+    var version int64
+    err = r.db.QueryRowContext(ctx, `SELECT version FROM users WHERE id = ? AND deleted_at IS NULL`, id).Scan(&version)
+    if err != nil {
+        return wrapError(op, err)
+    }
+    return &Error{Op: op, Kind: ErrStale}
+}
+
+// checkVersion reports ErrNotFound or ErrStale like a conditional write
+// that touched no row, without writing
+func (r *UserRepository) checkVersion(ctx context.Context, op, id string, version int64) error {
+    var current int64
+    err := r.db.QueryRowContext(ctx, `SELECT version FROM users WHERE id = ? AND deleted_at IS NULL`, id).Scan(&current)
     if err != nil {
-        return false
+        return wrapError(op, err)
+    }
+    if version != 0 && version != current {
+        return &Error{Op: op, Kind: ErrStale}
+    }
+    return nil
+}
+
+// wrapUserWriteError wraps err like wrapError, and names the field behind
+// a duplicate entry
+func wrapUserWriteError(op string, err error) error {
+    wrapped := wrapError(op, err)
+    var repoErr *Error
+    if errors.As(wrapped, &repoErr) && repoErr.Kind == ErrConflict {
+        repoErr.Field = userUniqueKeys[duplicateKeyName(err)]
+    }
+    return wrapped
+}
+
+// requireRowsAffected wraps err, and reports ErrNotFound when the
+// statement succeeded without touching any row
+func requireRowsAffected(op string, result sql.Result, err error) error {
+    if err != nil {
+        return wrapError(op, err)
     }
     
     rowsAffected, err := result.RowsAffected()
     if err != nil {
-        return false
+        return wrapError(op, err)
+    }
+    if rowsAffected == 0 {
+        return &Error{Op: op, Kind: ErrNotFound}
     }
     
-    return rowsAffected > 0
-}
\ No newline at end of file
+    return nil
+}
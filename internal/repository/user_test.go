@@ -1,7 +1,12 @@
 package repository
 
 import (
+    "context"
+    "errors"
+    "fmt"
     "testing"
+    "time"
+
     "go-crud-api/internal/model"
 )
 
@@ -23,9 +28,10 @@ func TestMockUserRepository_Save(t *testing.T) {
         Name:     "Test User",
         Email:    "test@example.com",
         Password: "password",
+        Version:  1,
     }
     
-    err := repo.Save(user)
+    err := repo.Save(context.Background(), user)
     if err != nil {
         t.Fatalf("Save returned error: %v", err)
     }
@@ -45,12 +51,12 @@ func TestMockUserRepository_GetAll(t *testing.T) {
     repo := NewMockUserRepository()
     
     // Add test users
-    user1 := model.User{ID: "1", Name: "User 1"}
-    user2 := model.User{ID: "2", Name: "User 2"}
-    repo.Save(user1)
-    repo.Save(user2)
+    user1 := model.User{ID: "1", Name: "User 1", Email: "user1@example.com"}
+    user2 := model.User{ID: "2", Name: "User 2", Email: "user2@example.com"}
+    repo.Save(context.Background(), user1)
+    repo.Save(context.Background(), user2)
     
-    users, err := repo.GetAll()
+    users, err := repo.GetAll(context.Background())
     if err != nil {
         t.Fatalf("GetAll returned error: %v", err)
     }
@@ -69,8 +75,9 @@ func TestMockUserRepository_FindById(t *testing.T) {
         Name:     "Find User",
         Email:    "find@example.com",
         Password: "password",
+        Version:  1,
     }
-    repo.Save(user)
+    repo.Save(context.Background(), user)
     
     tests := []struct {
         name      string
@@ -94,10 +101,14 @@ func TestMockUserRepository_FindById(t *testing.T) {
     
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
-            gotUser, found := repo.FindById(tt.id)
+            gotUser, err := repo.FindById(context.Background(), tt.id)
+            found := err == nil
             if found != tt.wantFound {
                 t.Errorf("FindById() found = %v, want %v", found, tt.wantFound)
             }
+            if !found && !errors.Is(err, ErrNotFound) {
+                t.Errorf("FindById() error = %v, want ErrNotFound", err)
+            }
             if found && gotUser != tt.wantUser {
                 t.Errorf("FindById() user = %+v, want %+v", gotUser, tt.wantUser)
             }
@@ -105,6 +116,49 @@ func TestMockUserRepository_FindById(t *testing.T) {
     }
 }
 
+func TestMockUserRepository_FindByEmail(t *testing.T) {
+    repo := NewMockUserRepository()
+    
+    user := model.User{ID: "email-123", Name: "Email User", Email: "email@example.com", Version: 1}
+    repo.Save(context.Background(), user)
+    
+    got, err := repo.FindByEmail(context.Background(), "email@example.com")
+    if err != nil || got != user {
+        t.Errorf("FindByEmail() = %+v, %v, want %+v, nil", got, err, user)
+    }
+    
+    if _, err := repo.FindByEmail(context.Background(), "missing@example.com"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("FindByEmail() of a non-existing user = %v, want ErrNotFound", err)
+    }
+}
+
+func TestMockUserRepository_UniqueEmail(t *testing.T) {
+    repo := NewMockUserRepository()
+    repo.Save(context.Background(), model.User{ID: "u-1", Email: "taken@example.com"})
+    repo.Save(context.Background(), model.User{ID: "u-2", Email: "free@example.com"})
+    
+    err := repo.Save(context.Background(), model.User{ID: "u-3", Email: "Taken@Example.com"})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "email" {
+        t.Errorf("Save() with a taken email = %v, want ErrConflict on email", err)
+    }
+    err = repo.Save(context.Background(), model.User{ID: "u-1", Email: "other@example.com"})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "id" {
+        t.Errorf("Save() with a taken id = %v, want ErrConflict on id", err)
+    }
+    
+    err = repo.Update(context.Background(), model.User{ID: "u-2", Email: "TAKEN@example.com"})
+    if !errors.Is(err, ErrConflict) || ConflictField(err) != "email" {
+        t.Errorf("Update() to a taken email = %v, want ErrConflict on email", err)
+    }
+    if err := repo.Update(context.Background(), model.User{ID: "u-1", Email: "taken@example.com", Name: "Same"}); err != nil {
+        t.Errorf("Update() keeping the own email = %v, want nil", err)
+    }
+    
+    if got, err := repo.FindByEmail(context.Background(), "TAKEN@example.com"); err != nil || got.ID != "u-1" {
+        t.Errorf("FindByEmail() is not case-insensitive: %+v, %v", got, err)
+    }
+}
+
 func TestMockUserRepository_Update(t *testing.T) {
     repo := NewMockUserRepository()
     
@@ -115,7 +169,7 @@ func TestMockUserRepository_Update(t *testing.T) {
         Email:    "original@example.com",
         Password: "password",
     }
-    repo.Save(originalUser)
+    repo.Save(context.Background(), originalUser)
     
     tests := []struct {
         name        string
@@ -146,29 +200,87 @@ func TestMockUserRepository_Update(t *testing.T) {
     
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
-            success := repo.Update(tt.updateUser)
+            err := repo.Update(context.Background(), tt.updateUser)
+            success := err == nil
             if success != tt.wantSuccess {
-                t.Errorf("Update() = %v, want %v", success, tt.wantSuccess)
+                t.Errorf("Update() error = %v, want success %v", err, tt.wantSuccess)
             }
             
             if success {
-                updatedUser, _ := repo.FindById(tt.updateUser.ID)
-                if updatedUser != tt.updateUser {
-                    t.Errorf("User not updated correctly: got %+v, want %+v", updatedUser, tt.updateUser)
+                updatedUser, _ := repo.FindById(context.Background(), tt.updateUser.ID)
+                want := tt.updateUser
+                want.Version = 2
+                if updatedUser != want {
+                    t.Errorf("User not updated correctly: got %+v, want %+v", updatedUser, want)
                 }
             }
         })
     }
 }
 
+func TestMockUserRepository_Patch(t *testing.T) {
+    repo := NewMockUserRepository()
+    original := model.User{ID: "patch-1", Name: "Original", Email: "patch1@example.com", Password: "hash", Role: model.RoleUser}
+    repo.Save(context.Background(), original)
+    repo.Save(context.Background(), model.User{ID: "patch-2", Email: "patch2@example.com"})
+    
+    name := "Patched"
+    if err := repo.Patch(context.Background(), "patch-1", UserChanges{Name: &name}); err != nil {
+        t.Fatalf("Patch() error: %v", err)
+    }
+    got, _ := repo.FindById(context.Background(), "patch-1")
+    want := original
+    want.Name = "Patched"
+    want.Version = 2
+    if got != want {
+        t.Errorf("Patch() stored %+v, want %+v", got, want)
+    }
+    
+    if err := repo.Patch(context.Background(), "patch-1", UserChanges{}); err != nil {
+        t.Errorf("Patch() without changes = %v, want nil", err)
+    }
+    if err := repo.Patch(context.Background(), "missing", UserChanges{}); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Patch() of a missing user = %v, want ErrNotFound", err)
+    }
+    taken := "PATCH2@example.com"
+    if err := repo.Patch(context.Background(), "patch-1", UserChanges{Email: &taken}); ConflictField(err) != "email" {
+        t.Errorf("Patch() to a taken email = %v, want ErrConflict on email", err)
+    }
+}
+
+func TestMockUserRepository_Versions(t *testing.T) {
+    repo := NewMockUserRepository()
+    repo.Save(context.Background(), model.User{ID: "v-1", Name: "V", Email: "v@example.com"})
+    
+    if err := repo.Update(context.Background(), model.User{ID: "v-1", Name: "V2", Email: "v@example.com", Version: 1}); err != nil {
+        t.Fatalf("Update() at the current version = %v, want nil", err)
+    }
+    if err := repo.Update(context.Background(), model.User{ID: "v-1", Name: "V3", Email: "v@example.com", Version: 1}); !errors.Is(err, ErrStale) {
+        t.Errorf("Update() at an old version = %v, want ErrStale", err)
+    }
+    name := "V3"
+    if err := repo.Patch(context.Background(), "v-1", UserChanges{Version: 1, Name: &name}); !errors.Is(err, ErrStale) {
+        t.Errorf("Patch() at an old version = %v, want ErrStale", err)
+    }
+    if err := repo.Patch(context.Background(), "v-1", UserChanges{Version: 2, Name: &name}); err != nil {
+        t.Errorf("Patch() at the current version = %v, want nil", err)
+    }
+    if err := repo.Delete(context.Background(), "v-1", 2); !errors.Is(err, ErrStale) {
+        t.Errorf("Delete() at an old version = %v, want ErrStale", err)
+    }
+    if err := repo.Delete(context.Background(), "v-1", 3); err != nil {
+        t.Errorf("Delete() at the current version = %v, want nil", err)
+    }
+}
+
 func TestMockUserRepository_Delete(t *testing.T) {
     repo := NewMockUserRepository()
     
     // Add test users
-    user1 := model.User{ID: "delete-1", Name: "User 1"}
-    user2 := model.User{ID: "delete-2", Name: "User 2"}
-    repo.Save(user1)
-    repo.Save(user2)
+    user1 := model.User{ID: "delete-1", Name: "User 1", Email: "delete1@example.com"}
+    user2 := model.User{ID: "delete-2", Name: "User 2", Email: "delete2@example.com"}
+    repo.Save(context.Background(), user1)
+    repo.Save(context.Background(), user2)
     
     tests := []struct {
         name        string
@@ -194,15 +306,15 @@ func TestMockUserRepository_Delete(t *testing.T) {
     
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
-            success := repo.Delete(tt.deleteID)
+            err := repo.Delete(context.Background(), tt.deleteID, 0)
+            success := err == nil
             if success != tt.wantSuccess {
-                t.Errorf("Delete() = %v, want %v", success, tt.wantSuccess)
+                t.Errorf("Delete() error = %v, want success %v", err, tt.wantSuccess)
             }
             
             // Verify user is actually deleted
             if success {
-                _, found := repo.FindById(tt.deleteID)
-                if found {
+                if _, err := repo.FindById(context.Background(), tt.deleteID); !errors.Is(err, ErrNotFound) {
                     t.Error("User still exists after deletion")
                 }
             }
@@ -210,8 +322,191 @@ func TestMockUserRepository_Delete(t *testing.T) {
     }
     
     // Verify other users are not affected
-    _, found := repo.FindById("delete-2")
-    if !found {
+    if _, err := repo.FindById(context.Background(), "delete-2"); err != nil {
         t.Error("Unrelated user was deleted")
     }
+}
+
+func TestMockUserRepository_SoftDelete(t *testing.T) {
+    ctx := context.Background()
+    repo := NewMockUserRepository()
+    repo.Save(ctx, model.User{ID: "soft-1", Name: "Soft", Email: "soft@example.com"})
+    
+    if err := repo.Delete(ctx, "soft-1", 1); err != nil {
+        t.Fatalf("Delete() = %v", err)
+    }
+    
+    deleted, err := repo.FindByIdIncludingDeleted(ctx, "soft-1")
+    if err != nil || !deleted.Deleted() || deleted.Version != 2 {
+        t.Fatalf("FindByIdIncludingDeleted() = %+v, %v, want a deleted user at version 2", deleted, err)
+    }
+    if _, err := repo.FindByEmail(ctx, "soft@example.com"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("FindByEmail() of a deleted user = %v, want ErrNotFound", err)
+    }
+    if users, _ := repo.GetAll(ctx); len(users) != 0 {
+        t.Errorf("GetAll() returned %d users, want none", len(users))
+    }
+    if page, _ := repo.List(ctx, ListOptions{IncludeDeleted: true}); len(page.Users) != 1 {
+        t.Errorf("List() including deleted returned %d users, want 1", len(page.Users))
+    }
+    name := "Renamed"
+    if err := repo.Patch(ctx, "soft-1", UserChanges{Name: &name}); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Patch() of a deleted user = %v, want ErrNotFound", err)
+    }
+    
+    // The email stays reserved until the user is purged
+    err = repo.Save(ctx, model.User{ID: "soft-2", Name: "Other", Email: "soft@example.com"})
+    if !errors.Is(err, ErrConflict) {
+        t.Errorf("Save() reusing a deleted user's email = %v, want ErrConflict", err)
+    }
+    
+    if err := repo.Restore(ctx, "soft-1"); err != nil {
+        t.Fatalf("Restore() = %v", err)
+    }
+    if err := repo.Restore(ctx, "soft-1"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Restore() of a live user = %v, want ErrNotFound", err)
+    }
+    restored, err := repo.FindById(ctx, "soft-1")
+    if err != nil || restored.Deleted() || restored.Version != 3 {
+        t.Errorf("FindById() after restore = %+v, %v", restored, err)
+    }
+}
+
+func TestMockUserRepository_Purge(t *testing.T) {
+    ctx := context.Background()
+    repo := NewMockUserRepository()
+    for _, id := range []string{"live", "recent", "old"} {
+        repo.Save(ctx, model.User{ID: id, Name: id, Email: id + "@example.com"})
+    }
+    repo.Delete(ctx, "old", 0)
+    cutoff := time.Now().Add(time.Second)
+    
+    purged, err := repo.Purge(ctx, cutoff)
+    if err != nil || purged != 1 {
+        t.Fatalf("Purge() = %d, %v, want 1", purged, err)
+    }
+    if _, err := repo.FindByIdIncludingDeleted(ctx, "old"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("purged user still exists: %v", err)
+    }
+    
+    // Users deleted after the cutoff are kept
+    repo.users["recent"] = withDeletedAt(repo.users["recent"], cutoff.Add(time.Hour))
+    if purged, _ := repo.Purge(ctx, cutoff); purged != 0 {
+        t.Errorf("Purge() removed %d users deleted after the cutoff", purged)
+    }
+    if _, err := repo.FindById(ctx, "live"); err != nil {
+        t.Errorf("live user was purged: %v", err)
+    }
+}
+
+func withDeletedAt(u model.User, at time.Time) model.User {
+    u.DeletedAt = &at
+    return u
+}
+
+func TestMockUserRepository_List(t *testing.T) {
+    repo := NewMockUserRepository()
+    base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+    names := []string{"alice", "Bob", "carol", "bob", "Dave"}
+    for i, name := range names {
+        repo.Save(context.Background(), model.User{
+            ID:        fmt.Sprintf("id-%d", i),
+            Name:      name,
+            Email:     fmt.Sprintf("%s%d@example.com", name, i),
+            CreatedAt: base.Add(time.Duration(i) * time.Hour),
+        })
+    }
+    
+    collect := func(opts ListOptions) []string {
+        var ids []string
+        for {
+            page, err := repo.List(context.Background(), opts)
+            if err != nil {
+                t.Fatalf("List returned error: %v", err)
+            }
+            for _, u := range page.Users {
+                ids = append(ids, u.ID)
+            }
+            if page.NextCursor == "" {
+                return ids
+            }
+            opts.Cursor = page.NextCursor
+        }
+    }
+    
+    tests := []struct {
+        name string
+        opts ListOptions
+        want []string
+    }{
+        {
+            name: "default order is creation time",
+            opts: ListOptions{Limit: 2},
+            want: []string{"id-0", "id-1", "id-2", "id-3", "id-4"},
+        },
+        {
+            name: "case-insensitive name with id tie-breaker",
+            opts: ListOptions{Limit: 2, Sort: []SortField{{Field: "name"}}},
+            want: []string{"id-0", "id-1", "id-3", "id-2", "id-4"},
+        },
+        {
+            name: "descending creation time",
+            opts: ListOptions{Limit: 3, Sort: []SortField{{Field: "created_at", Desc: true}}},
+            want: []string{"id-4", "id-3", "id-2", "id-1", "id-0"},
+        },
+        {
+            name: "mixed directions",
+            opts: ListOptions{Limit: 1, Sort: []SortField{{Field: "name", Desc: true}, {Field: "created_at"}}},
+            want: []string{"id-4", "id-2", "id-1", "id-3", "id-0"},
+        },
+        {
+            name: "name prefix filter",
+            opts: ListOptions{Limit: 1, Filter: UserFilter{NamePrefix: "BO"}},
+            want: []string{"id-1", "id-3"},
+        },
+        {
+            name: "email prefix filter",
+            opts: ListOptions{Filter: UserFilter{EmailPrefix: "carol2@"}},
+            want: []string{"id-2"},
+        },
+    }
+    
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := collect(tt.opts)
+            if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+                t.Errorf("Got %v, want %v", got, tt.want)
+            }
+        })
+    }
+    
+    page, _ := repo.List(context.Background(), ListOptions{Limit: 1, IncludeTotal: true, Filter: UserFilter{NamePrefix: "b"}})
+    if page.Total == nil || *page.Total != 2 {
+        t.Errorf("Expected total 2, got %v", page.Total)
+    }
+    
+    if _, err := repo.List(context.Background(), ListOptions{Cursor: "garbage"}); err == nil {
+        t.Error("Expected invalid cursor to be rejected")
+    }
+}
+
+func TestMockUserRepository_CancelledContext(t *testing.T) {
+    repo := NewMockUserRepository()
+    repo.Save(context.Background(), model.User{ID: "ctx-1", Name: "Ctx"})
+    
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    
+    if _, err := repo.GetAll(ctx); !errors.Is(err, ErrUnavailable) || !errors.Is(err, context.Canceled) {
+        t.Errorf("GetAll() error = %v, want ErrUnavailable wrapping context.Canceled", err)
+    }
+    if _, err := repo.List(ctx, ListOptions{}); !errors.Is(err, ErrUnavailable) || !errors.Is(err, context.Canceled) {
+        t.Errorf("List() error = %v, want ErrUnavailable wrapping context.Canceled", err)
+    }
+    if err := repo.Save(ctx, model.User{ID: "ctx-2"}); !errors.Is(err, ErrUnavailable) || !errors.Is(err, context.Canceled) {
+        t.Errorf("Save() error = %v, want ErrUnavailable wrapping context.Canceled", err)
+    }
+    if _, err := repo.FindById(context.Background(), "ctx-2"); err == nil {
+        t.Error("Save() with a cancelled context should not store the user")
+    }
 }
\ No newline at end of file
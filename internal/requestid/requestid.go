@@ -0,0 +1,47 @@
+// Package requestid carries the per-request correlation ID through the
+// request context
+package requestid
+
+import (
+    "context"
+
+    "github.com/google/uuid"
+)
+
+// Header is the request and response header carrying the ID
+const Header = "X-Request-ID"
+
+// maxLength bounds client supplied IDs so they cannot bloat logs
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a fresh random request ID
+func New() string {
+    return uuid.New().String()
+}
+
+// Valid reports whether a client supplied ID may be reused as is: non-empty,
+// bounded, and printable ASCII without spaces
+func Valid(id string) bool {
+    if id == "" || len(id) > maxLength {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        if id[i] <= ' ' || id[i] > '~' {
+            return false
+        }
+    }
+    return true
+}
+
+// WithRequestID returns a copy of ctx carrying id
+func WithRequestID(ctx context.Context, id string) context.Context {
+    return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+    id, _ := ctx.Value(contextKey{}).(string)
+    return id
+}
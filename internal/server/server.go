@@ -0,0 +1,134 @@
+// Package server runs the HTTP server with timeouts against slow clients
+// and a graceful shutdown that lets in-flight requests finish.
+package server
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "time"
+)
+
+// Defaults for Config. WriteTimeout bounds a whole response, so it must
+// cover the slowest handler including its database queries.
+const (
+    DefaultAddr              = ":8080"
+    DefaultReadHeaderTimeout = 5 * time.Second
+    DefaultReadTimeout       = 15 * time.Second
+    DefaultWriteTimeout      = 30 * time.Second
+    DefaultIdleTimeout       = 60 * time.Second
+    DefaultShutdownTimeout   = 20 * time.Second
+    DefaultShutdownDelay     = 0
+)
+
+// Config controls the HTTP server. Zero timeouts disable the limit,
+// except ShutdownTimeout: with 0, shutdown closes connections at once.
+// The yaml, toml and env tags name each setting in configuration files
+// and the environment.
+type Config struct {
+    Addr string `yaml:"addr" toml:"addr" env:"HTTP_ADDR"`
+    // ReadHeaderTimeout limits how long a client may take to send the
+    // request headers, so slow clients cannot hold connections open
+    ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+    // ReadTimeout limits reading the whole request, body included
+    ReadTimeout time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+    // WriteTimeout limits the time from the end of the request headers
+    // to the end of the response
+    WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+    // IdleTimeout limits how long a keep-alive connection waits for the
+    // next request
+    IdleTimeout time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+    // ShutdownTimeout is how long in-flight requests may take to finish
+    // once shutdown starts
+    ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+    // ShutdownDelay keeps serving requests for a while after shutdown is
+    // requested, so load balancers can notice the failing readiness check
+    // and stop sending new requests before the listener closes
+    ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+    return Config{
+        Addr:              DefaultAddr,
+        ReadHeaderTimeout: DefaultReadHeaderTimeout,
+        ReadTimeout:       DefaultReadTimeout,
+        WriteTimeout:      DefaultWriteTimeout,
+        IdleTimeout:       DefaultIdleTimeout,
+        ShutdownTimeout:   DefaultShutdownTimeout,
+        ShutdownDelay:     DefaultShutdownDelay,
+    }
+}
+
+// Validate reports an invalid listen address
+func (c Config) Validate() error {
+    if _, _, err := net.SplitHostPort(c.Addr); err != nil {
+        return fmt.Errorf("invalid addr %q: %v", c.Addr, err)
+    }
+    return nil
+}
+
+// New returns an http.Server for handler with the timeouts of cfg
+func New(cfg Config, handler http.Handler) *http.Server {
+    return &http.Server{
+        Addr:              cfg.Addr,
+        Handler:           handler,
+        ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+        ReadTimeout:       cfg.ReadTimeout,
+        WriteTimeout:      cfg.WriteTimeout,
+        IdleTimeout:       cfg.IdleTimeout,
+    }
+}
+
+// Run listens on cfg.Addr and serves handler until ctx is done, then
+// shuts down gracefully like Serve
+func Run(ctx context.Context, cfg Config, handler http.Handler) error {
+    ln, err := net.Listen("tcp", cfg.Addr)
+    if err != nil {
+        return err
+    }
+    return Serve(ctx, ln, cfg, handler)
+}
+
+// Serve serves handler on ln until ctx is done. It keeps serving for
+// cfg.ShutdownDelay, then stops accepting connections and waits up to cfg.ShutdownTimeout for in-flight requests
+// to finish; connections still open after that are closed and Serve
+// returns an error. Serve returns nil after a complete graceful shutdown.
+func Serve(ctx context.Context, ln net.Listener, cfg Config, handler http.Handler) error {
+    srv := New(cfg, handler)
+
+    errs := make(chan error, 1)
+    go func() {
+        errs <- srv.Serve(ln)
+    }()
+
+    select {
+    case err := <-errs:
+        // The server failed before shutdown was requested
+        return err
+    case <-ctx.Done():
+    }
+
+    if cfg.ShutdownDelay > 0 {
+        log.Printf("Shutdown requested, serving for another %s", cfg.ShutdownDelay)
+        select {
+        case err := <-errs:
+            return err
+        case <-time.After(cfg.ShutdownDelay):
+        }
+    }
+    log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.ShutdownTimeout)
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+    defer cancel()
+    if err := srv.Shutdown(shutdownCtx); err != nil {
+        srv.Close()
+        return fmt.Errorf("graceful shutdown incomplete: %w", err)
+    }
+    if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+    "context"
+    "io"
+    "net"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestNew(t *testing.T) {
+    cfg := DefaultConfig()
+    srv := New(cfg, http.NotFoundHandler())
+    if srv.Addr != DefaultAddr || srv.ReadHeaderTimeout != DefaultReadHeaderTimeout ||
+        srv.ReadTimeout != DefaultReadTimeout || srv.WriteTimeout != DefaultWriteTimeout ||
+        srv.IdleTimeout != DefaultIdleTimeout {
+        t.Errorf("New() = %+v, want the timeouts of %+v", srv, cfg)
+    }
+}
+
+// serve starts Serve on a free port and returns its address and result
+func serve(t *testing.T, ctx context.Context, cfg Config, handler http.Handler) (string, <-chan error) {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    done := make(chan error, 1)
+    go func() {
+        done <- Serve(ctx, ln, cfg, handler)
+    }()
+    return "http://" + ln.Addr().String(), done
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    started := make(chan struct{})
+    release := make(chan struct{})
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        <-release
+        io.WriteString(w, "done")
+    })
+    url, done := serve(t, ctx, DefaultConfig(), handler)
+
+    responses := make(chan string, 1)
+    go func() {
+        resp, err := http.Get(url)
+        if err != nil {
+            responses <- err.Error()
+            return
+        }
+        defer resp.Body.Close()
+        body, _ := io.ReadAll(resp.Body)
+        responses <- string(body)
+    }()
+
+    <-started
+    cancel()
+    // Shutdown waits for the request rather than returning
+    select {
+    case err := <-done:
+        t.Fatalf("Serve() returned %v with a request in flight", err)
+    case <-time.After(100 * time.Millisecond):
+    }
+    // New connections are refused once shutdown starts
+    if _, err := http.Get(url); err == nil {
+        t.Error("Expected new requests to be refused during shutdown")
+    }
+
+    close(release)
+    if body := <-responses; body != "done" {
+        t.Errorf("In-flight request got %q, want it to complete", body)
+    }
+    if err := <-done; err != nil {
+        t.Errorf("Serve() error = %v, want nil after a graceful shutdown", err)
+    }
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    started := make(chan struct{})
+    handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        close(started)
+        <-r.Context().Done()
+    })
+    cfg := DefaultConfig()
+    cfg.ShutdownTimeout = 50 * time.Millisecond
+    url, done := serve(t, ctx, cfg, handler)
+
+    go http.Get(url)
+    <-started
+    cancel()
+    select {
+    case err := <-done:
+        if err == nil {
+            t.Error("Serve() error = nil, want an error for the abandoned request")
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("Serve() did not return after the shutdown timeout")
+    }
+}
+
+func TestServeShutdownDelay(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cfg := DefaultConfig()
+    cfg.ShutdownDelay = 200 * time.Millisecond
+    url, done := serve(t, ctx, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, "ok")
+    }))
+
+    cancel()
+    // New requests are still served during the delay
+    resp, err := http.Get(url)
+    if err != nil {
+        t.Fatalf("Request during the shutdown delay failed: %v", err)
+    }
+    resp.Body.Close()
+
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Errorf("Serve() error = %v, want nil", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("Serve() did not return after the shutdown delay")
+    }
+}
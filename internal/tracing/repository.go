@@ -0,0 +1,180 @@
+package tracing
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+var (
+    _ repository.UserRepositoryInterface         = (*UserRepository)(nil)
+    _ repository.RefreshTokenRepositoryInterface = (*RefreshTokenRepository)(nil)
+)
+
+// endOperation ends the span of a repository call. Not found, conflict and
+// stale results are answers rather than failures, so they are recorded as
+// an attribute and leave the span's status unset.
+func endOperation(span trace.Span, err error) {
+    for _, expected := range []error{repository.ErrNotFound, repository.ErrConflict, repository.ErrStale} {
+        if errors.Is(err, expected) {
+            span.SetAttributes(attribute.String("repository.result", expected.Error()))
+            span.End()
+            return
+        }
+    }
+    End(span, err)
+}
+
+// UserRepository starts a span for every call to the user repository it
+// wraps; the SQL statements the call runs become its children
+type UserRepository struct {
+    next repository.UserRepositoryInterface
+}
+
+// NewUserRepository wraps next
+func NewUserRepository(next repository.UserRepositoryInterface) *UserRepository {
+    return &UserRepository{next: next}
+}
+
+func (r *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.GetAll")
+    users, err := r.next.GetAll(ctx)
+    endOperation(span, err)
+    return users, err
+}
+
+func (r *UserRepository) List(ctx context.Context, opts repository.ListOptions) (repository.UserPage, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.List")
+    page, err := r.next.List(ctx, opts)
+    endOperation(span, err)
+    return page, err
+}
+
+func (r *UserRepository) Search(ctx context.Context, opts repository.SearchOptions) ([]repository.SearchResult, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.Search")
+    results, err := r.next.Search(ctx, opts)
+    endOperation(span, err)
+    return results, err
+}
+
+func (r *UserRepository) Save(ctx context.Context, user model.User) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.Save")
+    err := r.next.Save(ctx, user)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) FindById(ctx context.Context, id string) (model.User, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.FindById")
+    user, err := r.next.FindById(ctx, id)
+    endOperation(span, err)
+    return user, err
+}
+
+func (r *UserRepository) FindByIdIncludingDeleted(ctx context.Context, id string) (model.User, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.FindByIdIncludingDeleted")
+    user, err := r.next.FindByIdIncludingDeleted(ctx, id)
+    endOperation(span, err)
+    return user, err
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (model.User, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.FindByEmail")
+    user, err := r.next.FindByEmail(ctx, email)
+    endOperation(span, err)
+    return user, err
+}
+
+func (r *UserRepository) Update(ctx context.Context, user model.User) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.Update")
+    err := r.next.Update(ctx, user)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) Patch(ctx context.Context, id string, changes repository.UserChanges) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.Patch")
+    err := r.next.Patch(ctx, id, changes)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) SetPassword(ctx context.Context, id string, hash string) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.SetPassword")
+    err := r.next.SetPassword(ctx, id, hash)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string, version int64) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.Delete")
+    err := r.next.Delete(ctx, id, version)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+    ctx, span := tracer.Start(ctx, "UserRepository.Restore")
+    err := r.next.Restore(ctx, id)
+    endOperation(span, err)
+    return err
+}
+
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+    ctx, span := tracer.Start(ctx, "UserRepository.Purge")
+    purged, err := r.next.Purge(ctx, deletedBefore)
+    endOperation(span, err)
+    return purged, err
+}
+
+// RefreshTokenRepository starts a span for every call to the refresh
+// token repository it wraps
+type RefreshTokenRepository struct {
+    next repository.RefreshTokenRepositoryInterface
+}
+
+// NewRefreshTokenRepository wraps next
+func NewRefreshTokenRepository(next repository.RefreshTokenRepositoryInterface) *RefreshTokenRepository {
+    return &RefreshTokenRepository{next: next}
+}
+
+func (r *RefreshTokenRepository) Save(ctx context.Context, token model.RefreshToken) error {
+    ctx, span := tracer.Start(ctx, "RefreshTokenRepository.Save")
+    err := r.next.Save(ctx, token)
+    endOperation(span, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+    ctx, span := tracer.Start(ctx, "RefreshTokenRepository.FindByHash")
+    token, err := r.next.FindByHash(ctx, hash)
+    endOperation(span, err)
+    return token, err
+}
+
+func (r *RefreshTokenRepository) Revoke(ctx context.Context, id string, replacedBy string) error {
+    ctx, span := tracer.Start(ctx, "RefreshTokenRepository.Revoke")
+    err := r.next.Revoke(ctx, id, replacedBy)
+    endOperation(span, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+    ctx, span := tracer.Start(ctx, "RefreshTokenRepository.RevokeFamily")
+    err := r.next.RevokeFamily(ctx, familyID)
+    endOperation(span, err)
+    return err
+}
+
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+    ctx, span := tracer.Start(ctx, "RefreshTokenRepository.DeleteExpired")
+    deleted, err := r.next.DeleteExpired(ctx, before)
+    endOperation(span, err)
+    return deleted, err
+}
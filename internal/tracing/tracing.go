@@ -0,0 +1,159 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter, W3C trace
+// context propagation, and server spans named after the router's route
+// templates. Code that creates spans uses the global tracer provider, so
+// without Setup every span is a no-op.
+package tracing
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+
+    "github.com/gorilla/mux"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+    "go.opentelemetry.io/otel/trace"
+
+    "go-crud-api/internal/instrument"
+)
+
+// Supported values of Config.Exporter
+const (
+    ExporterNone   = "none"
+    ExporterStdout = "stdout"
+    ExporterOTLP   = "otlp"
+)
+
+// DefaultServiceName identifies this service in traces
+const DefaultServiceName = "go-crud-api"
+
+// Config selects where spans are exported. The yaml, toml and env tags
+// name each setting in configuration files and the environment.
+type Config struct {
+    // Exporter is none, stdout (one JSON document per span, for offline
+    // use and tests) or otlp (OTLP over HTTP)
+    Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+    // OTLPEndpoint is the collector URL, e.g. http://localhost:4318;
+    // empty uses the exporter's default, localhost:4318 over HTTPS
+    OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+    ServiceName  string `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+    return Config{Exporter: ExporterNone, ServiceName: DefaultServiceName}
+}
+
+// Validate reports an unknown exporter or a missing service name
+func (c Config) Validate() error {
+    var errs []error
+    switch c.Exporter {
+    case ExporterNone, ExporterStdout, ExporterOTLP:
+    default:
+        errs = append(errs, fmt.Errorf("unsupported exporter %q (want %s, %s or %s)",
+            c.Exporter, ExporterNone, ExporterStdout, ExporterOTLP))
+    }
+    if c.ServiceName == "" {
+        errs = append(errs, errors.New("service_name is required"))
+    }
+    return errors.Join(errs...)
+}
+
+// Setup installs the global tracer provider for cfg and the W3C trace
+// context and baggage propagators. The returned shutdown flushes pending
+// spans; call it before exiting. Stdout spans are written to stdout.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+    return setup(ctx, cfg, os.Stdout)
+}
+
+// setup is Setup with the stdout exporter's output injected
+func setup(ctx context.Context, cfg Config, stdout io.Writer) (func(context.Context) error, error) {
+    // Incoming traceparent headers are honoured even when nothing is
+    // exported, so request logs and downstream services can correlate
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+        propagation.TraceContext{}, propagation.Baggage{}))
+
+    var exporter sdktrace.SpanExporter
+    var err error
+    switch cfg.Exporter {
+    case ExporterNone:
+        return func(context.Context) error { return nil }, nil
+    case ExporterStdout:
+        exporter, err = stdouttrace.New(stdouttrace.WithWriter(stdout))
+    case ExporterOTLP:
+        var opts []otlptracehttp.Option
+        if cfg.OTLPEndpoint != "" {
+            opts = append(opts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+        }
+        exporter, err = otlptracehttp.New(ctx, opts...)
+    default:
+        return nil, fmt.Errorf("unsupported exporter %q", cfg.Exporter)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to create %s exporter: %w", cfg.Exporter, err)
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+        // Follow the caller's sampling decision, sample everything else
+        sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+    )
+    otel.SetTracerProvider(provider)
+    return provider.Shutdown, nil
+}
+
+var tracer = otel.Tracer("go-crud-api/internal/tracing")
+
+// Middleware starts a server span for every request next serves,
+// continuing the trace of an incoming traceparent header. Spans are named
+// after the route template the request matches, e.g. GET /users/{id},
+// like the metrics. The trace ID is returned in the traceparent response
+// header so clients can look up their request.
+func Middleware(router *mux.Router, next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+        name := r.Method
+        attrs := []attribute.KeyValue{
+            semconv.HTTPRequestMethodKey.String(r.Method),
+            semconv.URLPath(r.URL.Path),
+        }
+        if template, ok := instrument.RouteTemplate(router, r); ok {
+            name += " " + template
+            attrs = append(attrs, semconv.HTTPRoute(template))
+        }
+
+        ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+        defer span.End()
+        otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+        recorder := instrument.NewStatusRecorder(w)
+        next.ServeHTTP(recorder, r.WithContext(ctx))
+
+        span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.Status()))
+        // Client errors are the client's; only server errors fail the span
+        if recorder.Status() >= http.StatusInternalServerError {
+            span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+        }
+    })
+}
+
+// End ends span, recording err as its status when it is not nil
+func End(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
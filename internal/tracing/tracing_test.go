@@ -0,0 +1,184 @@
+package tracing
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/gorilla/mux"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/propagation"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+    "go-crud-api/internal/model"
+    "go-crud-api/internal/repository"
+)
+
+// exporter receives every span ended in the tests. Tracers obtained
+// before the global provider is set stick to the first one installed, so
+// it is installed once, in TestMain.
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+    otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+    otel.SetTextMapPropagator(propagation.TraceContext{})
+    os.Exit(m.Run())
+}
+
+// record discards the spans of earlier tests
+func record(t *testing.T) *tracetest.InMemoryExporter {
+    t.Helper()
+    exporter.Reset()
+    return exporter
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+    for _, kv := range span.Attributes {
+        if kv.Key == key {
+            return kv.Value
+        }
+    }
+    return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+    recorder := record(t)
+    r := mux.NewRouter()
+    r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+        _, child := tracer.Start(r.Context(), "child")
+        child.End()
+        w.WriteHeader(http.StatusNotFound)
+    })
+    r.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusInternalServerError)
+    })
+    handler := Middleware(r, r)
+
+    const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+    req := httptest.NewRequest("GET", "/users/42", nil)
+    req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+    rec := httptest.NewRecorder()
+    handler.ServeHTTP(rec, req)
+    handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+    spans := recorder.GetSpans()
+    if len(spans) != 3 {
+        t.Fatalf("Got %d spans, want child, server and failing server span", len(spans))
+    }
+    child, server, failed := spans[0], spans[1], spans[2]
+
+    if server.Name != "GET /users/{id}" {
+        t.Errorf("Server span name = %q, want the route template", server.Name)
+    }
+    if got := server.SpanContext.TraceID().String(); got != traceID {
+        t.Errorf("Trace ID = %s, want the incoming traceparent's %s", got, traceID)
+    }
+    if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+        t.Errorf("Parent span = %s, want the incoming traceparent's", got)
+    }
+    if child.Parent.SpanID() != server.SpanContext.SpanID() {
+        t.Error("Handler span is not a child of the server span")
+    }
+    if got := attr(server, "http.response.status_code").AsInt64(); got != http.StatusNotFound {
+        t.Errorf("Status code attribute = %d, want 404", got)
+    }
+    if server.Status.Code == codes.Error {
+        t.Error("A 404 failed the server span")
+    }
+    if failed.Status.Code != codes.Error {
+        t.Error("A 500 did not fail the server span")
+    }
+    if got := rec.Header().Get("traceparent"); !strings.Contains(got, traceID) {
+        t.Errorf("Response traceparent = %q, want the trace ID", got)
+    }
+}
+
+func TestMiddlewareUnmatchedRoute(t *testing.T) {
+    recorder := record(t)
+    r := mux.NewRouter()
+    Middleware(r, r).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope/1", nil))
+
+    if spans := recorder.GetSpans(); len(spans) != 1 || spans[0].Name != "GET" {
+        t.Errorf("Unmatched request spans = %v, want one named after the method only", spans)
+    }
+}
+
+func TestUserRepository(t *testing.T) {
+    recorder := record(t)
+    repo := NewUserRepository(repository.NewMemoryUserRepository())
+    ctx := context.Background()
+
+    user := model.User{ID: "1", Name: "Ann", Email: "ann@example.com", Role: model.RoleUser}
+    if err := repo.Save(ctx, user); err != nil {
+        t.Fatal(err)
+    }
+    repo.FindById(ctx, "missing")
+
+    spans := recorder.GetSpans()
+    if len(spans) != 2 || spans[0].Name != "UserRepository.Save" || spans[1].Name != "UserRepository.FindById" {
+        t.Fatalf("Spans = %v, want one per call", spans)
+    }
+    notFound := spans[1]
+    if notFound.Status.Code == codes.Error {
+        t.Error("Not found failed the span")
+    }
+    if got := attr(notFound, "repository.result").AsString(); got != repository.ErrNotFound.Error() {
+        t.Errorf("repository.result = %q, want %q", got, repository.ErrNotFound)
+    }
+}
+
+func TestEnd(t *testing.T) {
+    recorder := record(t)
+    _, span := tracer.Start(context.Background(), "failing")
+    End(span, errors.New("boom"))
+
+    ended := recorder.GetSpans()[0]
+    if ended.Status.Code != codes.Error || ended.Status.Description != "boom" {
+        t.Errorf("Status = %+v, want the error", ended.Status)
+    }
+}
+
+func TestSetupStdout(t *testing.T) {
+    previous := otel.GetTracerProvider()
+    t.Cleanup(func() {
+        otel.SetTracerProvider(previous)
+    })
+
+    var out bytes.Buffer
+    cfg := DefaultConfig()
+    cfg.Exporter = ExporterStdout
+    shutdown, err := setup(context.Background(), cfg, &out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, span := otel.Tracer("test").Start(context.Background(), "exported span")
+    span.End()
+    if err := shutdown(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, want := range []string{`"Name":"exported span"`, DefaultServiceName} {
+        if !strings.Contains(out.String(), want) {
+            t.Errorf("Stdout export lacks %s:\n%s", want, out.String())
+        }
+    }
+}
+
+func TestValidate(t *testing.T) {
+    if err := DefaultConfig().Validate(); err != nil {
+        t.Errorf("DefaultConfig().Validate() = %v", err)
+    }
+    cfg := Config{Exporter: "jaeger"}
+    err := cfg.Validate()
+    if err == nil || !strings.Contains(err.Error(), "jaeger") || !strings.Contains(err.Error(), "service_name") {
+        t.Errorf("Validate() = %v, want the exporter and service name reported", err)
+    }
+}
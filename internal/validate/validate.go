@@ -0,0 +1,219 @@
+// Package validate checks request DTOs against declarative rules in their
+// `validate` struct tags, e.g.
+//
+//   Name  string `json:"name" validate:"required,max=100,name"`
+//   Email string `json:"email" validate:"required,email"`
+//
+// Rules are comma separated and apply to string fields; nested structs and
+// slices of structs are validated recursively. Every failing field is
+// reported, named by its JSON path.
+package validate
+
+import (
+    "fmt"
+    "net/mail"
+    "reflect"
+    "strconv"
+    "strings"
+    "sync"
+    "unicode"
+    "unicode/utf8"
+)
+
+// FieldError describes the first rule a field failed
+type FieldError struct {
+    Field   string
+    Message string
+}
+
+// Errors lists every invalid field of a validated value
+type Errors []FieldError
+
+func (e Errors) Error() string {
+    msgs := make([]string, len(e))
+    for i, fe := range e {
+        msgs[i] = fe.Field + " " + fe.Message
+    }
+    return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// check returns a message describing why value fails the rule, or ""
+type check func(value, param string) string
+
+var rules = map[string]check{
+    "required": func(value, _ string) string {
+        if strings.TrimSpace(value) == "" {
+            return "is required"
+        }
+        return ""
+    },
+    "min": func(value, param string) string {
+        if utf8.RuneCountInString(value) < mustAtoi(param) {
+            return "must be at least " + param + " characters"
+        }
+        return ""
+    },
+    "max": func(value, param string) string {
+        if utf8.RuneCountInString(value) > mustAtoi(param) {
+            return "must be at most " + param + " characters"
+        }
+        return ""
+    },
+    "email": func(value, _ string) string {
+        // ParseAddress also accepts "Name <addr>"; only a bare address is valid
+        addr, err := mail.ParseAddress(value)
+        if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+            return "must be a valid email address"
+        }
+        return ""
+    },
+    // Digits are allowed, as in "Admin User 2"
+    "name": func(value, _ string) string {
+        for _, r := range value {
+            if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" '-.", r) {
+                return "may only contain letters, digits, spaces, apostrophes, hyphens and periods"
+            }
+        }
+        return ""
+    },
+    "oneof": func(value, param string) string {
+        allowed := strings.Fields(param)
+        for _, a := range allowed {
+            if value == a {
+                return ""
+            }
+        }
+        return "must be one of " + strings.Join(allowed, ", ")
+    },
+}
+
+func mustAtoi(s string) int {
+    n, err := strconv.Atoi(s)
+    if err != nil {
+        panic(fmt.Sprintf("validate: invalid rule parameter %q", s))
+    }
+    return n
+}
+
+type rule struct {
+    param string
+    check check
+}
+
+// field is the parsed validation plan for one struct field
+type field struct {
+    index     int
+    name      string
+    omitEmpty bool
+    rules     []rule
+}
+
+var plans sync.Map // reflect.Type -> []field
+
+// Struct validates v, a struct or pointer to struct, and returns Errors
+// listing every invalid field, or nil. It panics on malformed tags, which
+// are programming errors.
+func Struct(v interface{}) error {
+    var errs Errors
+    validateValue(reflect.ValueOf(v), "", &errs)
+    if len(errs) == 0 {
+        return nil
+    }
+    return errs
+}
+
+func validateValue(v reflect.Value, prefix string, errs *Errors) {
+    for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+        if v.IsNil() {
+            return
+        }
+        v = v.Elem()
+    }
+
+    switch v.Kind() {
+    case reflect.Struct:
+        for _, f := range planFor(v.Type()) {
+            validateField(v.Field(f.index), f, join(prefix, f.name), errs)
+        }
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            validateValue(v.Index(i), prefix+"["+strconv.Itoa(i)+"]", errs)
+        }
+    }
+}
+
+func validateField(v reflect.Value, f field, path string, errs *Errors) {
+    if v.Kind() != reflect.String {
+        validateValue(v, path, errs)
+        return
+    }
+
+    value := v.String()
+    if f.omitEmpty && value == "" {
+        return
+    }
+    for _, r := range f.rules {
+        if msg := r.check(value, r.param); msg != "" {
+            *errs = append(*errs, FieldError{Field: path, Message: msg})
+            return
+        }
+    }
+}
+
+func join(prefix, name string) string {
+    if prefix == "" {
+        return name
+    }
+    return prefix + "." + name
+}
+
+func planFor(t reflect.Type) []field {
+    if plan, ok := plans.Load(t); ok {
+        return plan.([]field)
+    }
+
+    var plan []field
+    for i := 0; i < t.NumField(); i++ {
+        sf := t.Field(i)
+        if !sf.IsExported() {
+            continue
+        }
+
+        name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+        if name == "-" {
+            continue
+        }
+        if name == "" {
+            name = sf.Name
+        }
+
+        f := field{index: i, name: name}
+        tag := sf.Tag.Get("validate")
+        if tag != "" {
+            for _, spec := range strings.Split(tag, ",") {
+                ruleName, param, _ := strings.Cut(spec, "=")
+                if ruleName == "omitempty" {
+                    f.omitEmpty = true
+                    continue
+                }
+                check, ok := rules[ruleName]
+                if !ok {
+                    panic(fmt.Sprintf("validate: unknown rule %q on %s.%s", ruleName, t.Name(), sf.Name))
+                }
+                if ruleName == "min" || ruleName == "max" {
+                    mustAtoi(param)
+                }
+                f.rules = append(f.rules, rule{param: param, check: check})
+            }
+        }
+
+        kind := sf.Type.Kind()
+        if len(f.rules) > 0 || f.omitEmpty || kind == reflect.Struct || kind == reflect.Slice ||
+            kind == reflect.Array || kind == reflect.Ptr {
+            plan = append(plan, f)
+        }
+    }
+
+    plans.Store(t, plan)
+    return plan
+}
@@ -0,0 +1,98 @@
+package validate
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+type address struct {
+    City string `json:"city" validate:"required"`
+}
+
+type payload struct {
+    Name      string    `json:"name" validate:"required,max=5,name"`
+    Email     string    `json:"email,omitempty" validate:"required,email"`
+    Password  string    `json:"password" validate:"omitempty,min=8"`
+    Role      string    `json:"role" validate:"omitempty,oneof=admin user"`
+    Addresses []address `json:"addresses"`
+    Ignored   string    `json:"-" validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+    tests := []struct {
+        name string
+        in   payload
+        want Errors
+    }{
+        {
+            name: "valid",
+            in:   payload{Name: "Ann", Email: "ann@example.com"},
+        },
+        {
+            name: "every invalid field is reported",
+            in:   payload{Name: "  ", Email: "not-an-email", Password: "short", Role: "root"},
+            want: Errors{
+                {Field: "name", Message: "is required"},
+                {Field: "email", Message: "must be a valid email address"},
+                {Field: "password", Message: "must be at least 8 characters"},
+                {Field: "role", Message: "must be one of admin, user"},
+            },
+        },
+        {
+            name: "first failing rule per field",
+            in:   payload{Name: "Ann-Marie", Email: "ann@example.com"},
+            want: Errors{{Field: "name", Message: "must be at most 5 characters"}},
+        },
+        {
+            name: "allowed characters",
+            in:   payload{Name: "<b>", Email: "ann@example.com"},
+            want: Errors{{Field: "name", Message: "may only contain letters, digits, spaces, apostrophes, hyphens and periods"}},
+        },
+        {
+            name: "digits in names",
+            in:   payload{Name: "Ann 2", Email: "ann@example.com"},
+        },
+        {
+            name: "display name form is not a bare address",
+            in:   payload{Name: "Ann", Email: "Ann <ann@example.com>"},
+            want: Errors{{Field: "email", Message: "must be a valid email address"}},
+        },
+        {
+            name: "nested slices use JSON paths",
+            in:   payload{Name: "Ann", Email: "ann@example.com", Addresses: []address{{City: "Oslo"}, {}}},
+            want: Errors{{Field: "addresses[1].city", Message: "is required"}},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := Struct(&tt.in)
+            if tt.want == nil {
+                if err != nil {
+                    t.Fatalf("Struct() = %v, want nil", err)
+                }
+                return
+            }
+
+            var got Errors
+            if !errors.As(err, &got) {
+                t.Fatalf("Struct() = %v, want Errors", err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Struct() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestStructPanicsOnUnknownRule(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("Expected a panic for an unknown rule")
+        }
+    }()
+    Struct(struct {
+        Name string `validate:"requird"`
+    }{})
+}